@@ -24,6 +24,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -48,9 +49,19 @@ type API struct {
 	importWorkerPoolSize int
 	importWork           chan importJob
 
+	// readyChecks are run by Ready, in the order they were added.
+	readyMu     sync.RWMutex
+	readyChecks []readyCheck
+
 	Serializer Serializer
 }
 
+// readyCheck is a named check of whether part of a node is working.
+type readyCheck struct {
+	name  string
+	check func() error
+}
+
 // apiOption is a functional option type for pilosa.API
 type apiOption func(*API) error
 
@@ -463,6 +474,48 @@ func (api *API) DeleteField(ctx context.Context, indexName string, fieldName str
 	return nil
 }
 
+// DeleteColumn clears a column from every field and view of the named index
+// and removes its column attributes. Unless remote is true, the request is
+// also sent to every other node in the cluster.
+func (api *API) DeleteColumn(ctx context.Context, indexName string, columnID uint64, remote bool) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "API.DeleteColumn")
+	defer span.Finish()
+
+	if err := api.validate(apiDeleteColumn); err != nil {
+		return errors.Wrap(err, "validating api method")
+	}
+
+	// Find index.
+	index := api.holder.Index(indexName)
+	if index == nil {
+		return newNotFoundError(ErrIndexNotFound, indexName)
+	}
+
+	if err := index.clearColumn(columnID); err != nil {
+		return errors.Wrap(err, "clearing column")
+	}
+
+	// Do not forward the request if this is already being forwarded.
+	if remote {
+		return nil
+	}
+
+	// Column attributes are stored on every node, so every node is sent
+	// the request, not only the owners of the column's shard.
+	var eg errgroup.Group
+	for _, node := range Nodes(api.cluster.Nodes()).FilterID(api.server.nodeID) {
+		node := node
+		eg.Go(func() error {
+			return api.server.defaultClient.DeleteColumn(ctx, &node.URI, indexName, columnID, true)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return errors.Wrap(err, "deleting column on remote nodes")
+	}
+	api.holder.Stats.CountWithCustomTags("deleteColumn", 1, 1.0, []string{fmt.Sprintf("index:%s", indexName)})
+	return nil
+}
+
 // DeleteAvailableShard a shard ID from the available shard set cache.
 func (api *API) DeleteAvailableShard(_ context.Context, indexName, fieldName string, shardID uint64) error {
 	if err := api.validate(apiDeleteAvailableShard); err != nil {
@@ -660,6 +713,62 @@ func (api *API) FragmentData(ctx context.Context, indexName, fieldName, viewName
 	return f, nil
 }
 
+// ImportFragmentData replaces the contents of the specified fragment with
+// data previously produced by FragmentData. The fragment, and its view, are
+// created if they do not exist.
+func (api *API) ImportFragmentData(ctx context.Context, indexName, fieldName, viewName string, shard uint64, r io.Reader) error {
+	span, _ := tracing.StartSpanFromContext(ctx, "API.ImportFragmentData")
+	defer span.Finish()
+
+	if err := api.validate(apiImportFragmentData); err != nil {
+		return errors.Wrap(err, "validating api method")
+	}
+
+	// Validate that this handler owns the shard.
+	if !api.cluster.ownsShard(api.Node().ID, indexName, shard) {
+		api.server.logger.Printf("node %s does not own shard %d of index %s", api.Node().ID, shard, indexName)
+		return ErrClusterDoesNotOwnShard
+	}
+
+	field := api.holder.Field(indexName, fieldName)
+	if field == nil {
+		return newNotFoundError(ErrFieldNotFound, fieldName)
+	}
+
+	v, err := field.createViewIfNotExists(viewName)
+	if err != nil {
+		return errors.Wrap(err, "creating view")
+	}
+	frag, err := v.CreateFragmentIfNotExists(shard)
+	if err != nil {
+		return errors.Wrap(err, "creating fragment")
+	}
+	if _, err := frag.ReadFrom(r); err != nil {
+		return errors.Wrap(err, "reading fragment data")
+	}
+	return nil
+}
+
+// SyncFragment runs anti-entropy for a single fragment, merging the blocks
+// which differ between its replicas. The fragment, and its view, are created
+// locally if they do not exist.
+func (api *API) SyncFragment(ctx context.Context, indexName, fieldName, viewName string, shard uint64) error {
+	span, _ := tracing.StartSpanFromContext(ctx, "API.SyncFragment")
+	defer span.Finish()
+
+	if err := api.validate(apiSyncFragment); err != nil {
+		return errors.Wrap(err, "validating api method")
+	}
+
+	// Validate that this handler owns the shard.
+	if !api.cluster.ownsShard(api.Node().ID, indexName, shard) {
+		api.server.logger.Printf("node %s does not own shard %d of index %s", api.Node().ID, shard, indexName)
+		return ErrClusterDoesNotOwnShard
+	}
+
+	return errors.Wrap(api.server.syncer.syncFragment(indexName, fieldName, viewName, shard), "syncing fragment")
+}
+
 // Hosts returns a list of the hosts in the cluster including their ID,
 // URL, and which is the coordinator.
 func (api *API) Hosts(ctx context.Context) []*Node {
@@ -726,7 +835,15 @@ func (api *API) ClusterMessage(ctx context.Context, reqBody io.Reader) error {
 func (api *API) Schema(ctx context.Context) []*IndexInfo {
 	span, _ := tracing.StartSpanFromContext(ctx, "API.Schema")
 	defer span.Finish()
-	return api.holder.limitedSchema()
+	return api.holder.limitedSchema(false)
+}
+
+// SchemaWithViews returns information about each index in Pilosa, like
+// Schema, along with the views of each field.
+func (api *API) SchemaWithViews(ctx context.Context) []*IndexInfo {
+	span, _ := tracing.StartSpanFromContext(ctx, "API.SchemaWithViews")
+	defer span.Finish()
+	return api.holder.limitedSchema(true)
 }
 
 // ApplySchema takes the given schema and applies it across the
@@ -744,11 +861,15 @@ func (api *API) ApplySchema(ctx context.Context, s *Schema, remote bool) error {
 	}
 
 	if !remote {
+		// Creating a view tells the other nodes about it, so every node
+		// needs the fields before any views are created.
 		nodes := api.cluster.Nodes()
-		for i, node := range nodes {
-			err := api.server.defaultClient.PostSchema(ctx, &node.URI, s, true)
-			if err != nil {
-				return errors.Wrapf(err, "forwarding post schema to node %d of %d", i+1, len(nodes))
+		for _, schema := range []*Schema{schemaWithoutViews(s), s} {
+			for i, node := range nodes {
+				err := api.server.defaultClient.PostSchema(ctx, &node.URI, schema, true)
+				if err != nil {
+					return errors.Wrapf(err, "forwarding post schema to node %d of %d", i+1, len(nodes))
+				}
 			}
 		}
 	}
@@ -756,6 +877,20 @@ func (api *API) ApplySchema(ctx context.Context, s *Schema, remote bool) error {
 	return api.holder.applySchema(s)
 }
 
+// schemaWithoutViews returns a copy of s without the views of its fields.
+func schemaWithoutViews(s *Schema) *Schema {
+	other := &Schema{Indexes: make([]*IndexInfo, len(s.Indexes))}
+	for i, ii := range s.Indexes {
+		index := *ii
+		index.Fields = make([]*FieldInfo, len(ii.Fields))
+		for j, fi := range ii.Fields {
+			index.Fields[j] = &FieldInfo{Name: fi.Name, Options: fi.Options}
+		}
+		other.Indexes[i] = &index
+	}
+	return other
+}
+
 // Views returns the views in the given field.
 func (api *API) Views(ctx context.Context, indexName string, fieldName string) ([]*view, error) {
 	span, _ := tracing.StartSpanFromContext(ctx, "API.Views")
@@ -1143,6 +1278,34 @@ func (api *API) AvailableShardsByIndex(ctx context.Context) map[string]*roaring.
 	return api.holder.availableShardsByIndex()
 }
 
+// LocalStatus describes the data held by a single node.
+type LocalStatus struct {
+	// Shards lists, by index, the shards of which the node holds at least
+	// one fragment.
+	Shards map[string][]uint64 `json:"shards"`
+
+	// TranslateOffsets is the offset of each of the node's key translation
+	// stores. Replicas of a store lag behind its primary by the difference
+	// between their offsets.
+	TranslateOffsets TranslateOffsetMap `json:"translateOffsets"`
+}
+
+// LocalStatus returns the shards and key translation offsets of this node,
+// as opposed to those of the cluster.
+func (api *API) LocalStatus(ctx context.Context) (*LocalStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx, "API.LocalStatus")
+	defer span.Finish()
+
+	offsets, err := api.holder.TranslateOffsetMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting translate offsets")
+	}
+	return &LocalStatus{
+		Shards:           api.holder.localShards(),
+		TranslateOffsets: offsets,
+	}, nil
+}
+
 // StatsWithTags returns an instance of whatever implementation of StatsClient
 // pilosa is using with the given tags.
 func (api *API) StatsWithTags(tags []string) stats.StatsClient {
@@ -1224,7 +1387,26 @@ func (api *API) SetCoordinator(ctx context.Context, id string) (oldNode, newNode
 // RemoveNode puts the cluster into the "RESIZING" state and begins the job of
 // removing the given node.
 func (api *API) RemoveNode(id string) (*Node, error) {
-	if err := api.validate(apiRemoveNode); err != nil {
+	return api.removeNode(id, false)
+}
+
+// ForceRemoveNode removes the given node from the cluster without moving its
+// data to the remaining nodes. It is meant for nodes which are gone for good:
+// data held only by the removed node is lost. Once the node is removed, the
+// new owners of its shards sync them from the other replicas in the
+// background.
+func (api *API) ForceRemoveNode(id string) (*Node, error) {
+	return api.removeNode(id, true)
+}
+
+func (api *API) removeNode(id string, force bool) (*Node, error) {
+	method := apiRemoveNode
+	if force {
+		// A dead node can leave the cluster STARTING, so forced removal is
+		// allowed in that state too.
+		method = apiForceRemoveNode
+	}
+	if err := api.validate(method); err != nil {
 		return nil, errors.Wrap(err, "validating api method")
 	}
 
@@ -1239,7 +1421,7 @@ func (api *API) RemoveNode(id string) (*Node, error) {
 	}
 
 	// Start the resize process (similar to NodeJoin)
-	err := api.cluster.nodeLeave(id)
+	err := api.cluster.nodeLeave(id, force)
 	if err != nil {
 		return removeNode, errors.Wrap(err, "calling node leave")
 	}
@@ -1263,6 +1445,55 @@ func (api *API) State() string {
 	return api.cluster.State()
 }
 
+// Ready returns nil if the node can serve requests, and otherwise an error
+// describing why it cannot. A node is ready when the cluster state accepts
+// queries, the node is part of the cluster, the data directory is writable
+// and every check added with AddReadyCheck passes.
+//
+// Anti-entropy is not considered: it is a periodic pass over every
+// fragment rather than a queue, so there is no backlog to measure, and a
+// node keeps serving its replicas while they are repaired.
+func (api *API) Ready() error {
+	if err := api.validate(apiQuery); err != nil {
+		return errors.Wrap(err, "checking cluster state")
+	}
+
+	// Nodes are added to the cluster as they join it, and taken out when
+	// they are removed.
+	if api.cluster.nodeByID(api.server.nodeID) == nil {
+		return errors.New("node is not a member of the cluster")
+	}
+
+	f, err := ioutil.TempFile(api.holder.Path, ".ready")
+	if err != nil {
+		return errors.Wrap(err, "checking data directory")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "closing data directory check")
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		return errors.Wrap(err, "removing data directory check")
+	}
+
+	api.readyMu.RLock()
+	checks := api.readyChecks
+	api.readyMu.RUnlock()
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			return errors.Wrapf(err, "checking %s", c.name)
+		}
+	}
+	return nil
+}
+
+// AddReadyCheck adds a check to those run by Ready. The check returns an
+// error when the part of the node it covers cannot serve requests.
+func (api *API) AddReadyCheck(name string, check func() error) {
+	api.readyMu.Lock()
+	defer api.readyMu.Unlock()
+	api.readyChecks = append(api.readyChecks, readyCheck{name: name, check: check})
+}
+
 // Version returns the Pilosa version.
 func (api *API) Version() string {
 	return strings.TrimPrefix(Version, "v")
@@ -1346,6 +1577,7 @@ const (
 	apiCreateIndex
 	apiDeleteField
 	apiDeleteAvailableShard
+	apiDeleteColumn
 	apiDeleteIndex
 	apiDeleteView
 	apiExportCSV
@@ -1357,6 +1589,7 @@ const (
 	//apiHosts // not implemented
 	apiImport
 	apiImportValue
+	apiImportFragmentData
 	apiIndex
 	apiIndexAttrDiff
 	//apiLocalID // not implemented
@@ -1374,11 +1607,14 @@ const (
 	//apiVersion // not implemented
 	apiViews
 	apiApplySchema
+	apiForceRemoveNode
+	apiSyncFragment
 )
 
 var methodsCommon = map[apiMethod]struct{}{
-	apiClusterMessage: {},
-	apiSetCoordinator: {},
+	apiClusterMessage:  {},
+	apiSetCoordinator:  {},
+	apiForceRemoveNode: {},
 }
 
 var methodsResizing = map[apiMethod]struct{}{
@@ -1391,15 +1627,18 @@ var methodsNormal = map[apiMethod]struct{}{
 	apiCreateIndex:          {},
 	apiDeleteField:          {},
 	apiDeleteAvailableShard: {},
+	apiDeleteColumn:         {},
 	apiDeleteIndex:          {},
 	apiDeleteView:           {},
 	apiExportCSV:            {},
 	apiFragmentBlockData:    {},
 	apiFragmentBlocks:       {},
+	apiFragmentData:         {},
 	apiField:                {},
 	apiFieldAttrDiff:        {},
 	apiImport:               {},
 	apiImportValue:          {},
+	apiImportFragmentData:   {},
 	apiIndex:                {},
 	apiIndexAttrDiff:        {},
 	apiQuery:                {},
@@ -1408,4 +1647,5 @@ var methodsNormal = map[apiMethod]struct{}{
 	apiShardNodes:           {},
 	apiViews:                {},
 	apiApplySchema:          {},
+	apiSyncFragment:         {},
 }
@@ -266,3 +266,64 @@ type offsetModHasher struct{}
 func (*offsetModHasher) Hash(key uint64, n int) int {
 	return int(key+1) % n
 }
+
+func TestAPI_DeleteColumn(t *testing.T) {
+	c := test.MustRunCluster(t, 2,
+		[]server.CommandOption{
+			server.OptCommandServerOptions(
+				pilosa.OptServerNodeID("node0"),
+				pilosa.OptServerClusterHasher(&offsetModHasher{}),
+			)},
+		[]server.CommandOption{
+			server.OptCommandServerOptions(
+				pilosa.OptServerNodeID("node1"),
+				pilosa.OptServerClusterHasher(&offsetModHasher{}),
+			)},
+	)
+	defer c.Close()
+
+	ctx := context.Background()
+	index := "i"
+
+	if _, err := c[0].API.CreateIndex(ctx, index, pilosa.IndexOptions{TrackExistence: true}); err != nil {
+		t.Fatalf("creating index: %v", err)
+	}
+	if _, err := c[0].API.CreateField(ctx, index, "f"); err != nil {
+		t.Fatalf("creating field: %v", err)
+	}
+	if _, err := c[0].API.CreateField(ctx, index, "v", pilosa.OptFieldTypeInt(0, 100)); err != nil {
+		t.Fatalf("creating field: %v", err)
+	}
+	if _, err := c[0].API.Query(ctx, &pilosa.QueryRequest{
+		Index: index,
+		Query: `Set(1, f=1) Set(2, f=1) Set(1, f=2) Set(1, v=10) Set(2, v=20) SetColumnAttrs(1, name="a")`,
+	}); err != nil {
+		t.Fatalf("querying: %v", err)
+	}
+
+	// Column 1 lives in shard 0, which node1 owns; delete it through node0.
+	if err := c[0].API.DeleteColumn(ctx, index, 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range c {
+		resp, err := m.API.Query(ctx, &pilosa.QueryRequest{
+			Index: index,
+			Query: "Row(f=1) Row(f=2) Row(v>0) Not(Row(f=3))",
+		})
+		if err != nil {
+			t.Fatalf("node%d: querying: %v", i, err)
+		}
+		for j, exp := range [][]uint64{{2}, {}, {2}, {2}} {
+			if cols := resp.Results[j].(*pilosa.Row).Columns(); !reflect.DeepEqual(cols, exp) {
+				t.Fatalf("node%d: result %d: unexpected columns: %v", i, j, cols)
+			}
+		}
+
+		if attrs, err := m.Server.Holder().Index(index).ColumnAttrStore().Attrs(1); err != nil {
+			t.Fatal(err)
+		} else if len(attrs) != 0 {
+			t.Fatalf("node%d: unexpected attrs: %v", i, attrs)
+		}
+	}
+}
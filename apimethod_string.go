@@ -13,31 +13,35 @@ func _() {
 	_ = x[apiCreateIndex-2]
 	_ = x[apiDeleteField-3]
 	_ = x[apiDeleteAvailableShard-4]
-	_ = x[apiDeleteIndex-5]
-	_ = x[apiDeleteView-6]
-	_ = x[apiExportCSV-7]
-	_ = x[apiFragmentBlockData-8]
-	_ = x[apiFragmentBlocks-9]
-	_ = x[apiFragmentData-10]
-	_ = x[apiField-11]
-	_ = x[apiFieldAttrDiff-12]
-	_ = x[apiImport-13]
-	_ = x[apiImportValue-14]
-	_ = x[apiIndex-15]
-	_ = x[apiIndexAttrDiff-16]
-	_ = x[apiQuery-17]
-	_ = x[apiRecalculateCaches-18]
-	_ = x[apiRemoveNode-19]
-	_ = x[apiResizeAbort-20]
-	_ = x[apiSetCoordinator-21]
-	_ = x[apiShardNodes-22]
-	_ = x[apiViews-23]
-	_ = x[apiApplySchema-24]
+	_ = x[apiDeleteColumn-5]
+	_ = x[apiDeleteIndex-6]
+	_ = x[apiDeleteView-7]
+	_ = x[apiExportCSV-8]
+	_ = x[apiFragmentBlockData-9]
+	_ = x[apiFragmentBlocks-10]
+	_ = x[apiFragmentData-11]
+	_ = x[apiField-12]
+	_ = x[apiFieldAttrDiff-13]
+	_ = x[apiImport-14]
+	_ = x[apiImportValue-15]
+	_ = x[apiImportFragmentData-16]
+	_ = x[apiIndex-17]
+	_ = x[apiIndexAttrDiff-18]
+	_ = x[apiQuery-19]
+	_ = x[apiRecalculateCaches-20]
+	_ = x[apiRemoveNode-21]
+	_ = x[apiResizeAbort-22]
+	_ = x[apiSetCoordinator-23]
+	_ = x[apiShardNodes-24]
+	_ = x[apiViews-25]
+	_ = x[apiApplySchema-26]
+	_ = x[apiForceRemoveNode-27]
+	_ = x[apiSyncFragment-28]
 }
 
-const _apiMethod_name = "apiClusterMessageapiCreateFieldapiCreateIndexapiDeleteFieldapiDeleteAvailableShardapiDeleteIndexapiDeleteViewapiExportCSVapiFragmentBlockDataapiFragmentBlocksapiFragmentDataapiFieldapiFieldAttrDiffapiImportapiImportValueapiIndexapiIndexAttrDiffapiQueryapiRecalculateCachesapiRemoveNodeapiResizeAbortapiSetCoordinatorapiShardNodesapiViewsapiApplySchema"
+const _apiMethod_name = "apiClusterMessageapiCreateFieldapiCreateIndexapiDeleteFieldapiDeleteAvailableShardapiDeleteColumnapiDeleteIndexapiDeleteViewapiExportCSVapiFragmentBlockDataapiFragmentBlocksapiFragmentDataapiFieldapiFieldAttrDiffapiImportapiImportValueapiImportFragmentDataapiIndexapiIndexAttrDiffapiQueryapiRecalculateCachesapiRemoveNodeapiResizeAbortapiSetCoordinatorapiShardNodesapiViewsapiApplySchemaapiForceRemoveNodeapiSyncFragment"
 
-var _apiMethod_index = [...]uint16{0, 17, 31, 45, 59, 82, 96, 109, 121, 141, 158, 173, 181, 197, 206, 220, 228, 244, 252, 272, 285, 299, 316, 329, 337, 351}
+var _apiMethod_index = [...]uint16{0, 17, 31, 45, 59, 82, 97, 111, 124, 136, 156, 173, 188, 196, 212, 221, 235, 256, 264, 280, 288, 308, 321, 335, 352, 365, 373, 387, 405, 420}
 
 func (i apiMethod) String() string {
 	if i < 0 || i >= apiMethod(len(_apiMethod_index)-1) {
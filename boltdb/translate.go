@@ -14,6 +14,7 @@
 package boltdb
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -43,6 +44,7 @@ func OpenTranslateStore(path, index, field string) (pilosa.TranslateStore, error
 
 // Ensure type implements interface.
 var _ pilosa.TranslateStore = &TranslateStore{}
+var _ pilosa.TranslateKeyScanner = &TranslateStore{}
 
 // TranslateStore is an on-disk storage engine for translating string-to-uint64 values.
 type TranslateStore struct {
@@ -270,6 +272,23 @@ func (s *TranslateStore) TranslateIDs(ids []uint64) ([]string, error) {
 	return keys, nil
 }
 
+// KeysWithPrefix returns the key/ID pairs whose keys begin with prefix,
+// ordered by key.
+func (s *TranslateStore) KeysWithPrefix(prefix string) ([]pilosa.TranslateEntry, error) {
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var entries []pilosa.TranslateEntry
+	cur := tx.Bucket([]byte("keys")).Cursor()
+	for k, v := cur.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = cur.Next() {
+		entries = append(entries, pilosa.TranslateEntry{Index: s.index, Field: s.field, ID: btou64(v), Key: string(k)})
+	}
+	return entries, nil
+}
+
 // ForceSet writes the id/key pair to the store even if read only. Used by replication.
 func (s *TranslateStore) ForceSet(id uint64, key string) error {
 	if err := s.db.Update(func(tx *bolt.Tx) (err error) {
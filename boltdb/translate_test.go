@@ -130,6 +130,34 @@ func TestTranslateStore_TranslateIDs(t *testing.T) {
 	}
 }
 
+func TestTranslateStore_KeysWithPrefix(t *testing.T) {
+	s := MustOpenNewTranslateStore()
+	defer MustCloseTranslateStore(s)
+
+	// Setup initial keys.
+	if _, err := s.TranslateKeys([]string{"foo", "bar", "food", "fo", "fop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure only keys with the prefix are returned, ordered by key.
+	if entries, err := s.KeysWithPrefix("foo"); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	} else if got, want := entries[0], (pilosa.TranslateEntry{Index: "I", Field: "F", ID: 1, Key: "foo"}); got != want {
+		t.Fatalf("KeysWithPrefix()[0]=%+v, want %+v", got, want)
+	} else if got, want := entries[1], (pilosa.TranslateEntry{Index: "I", Field: "F", ID: 3, Key: "food"}); got != want {
+		t.Fatalf("KeysWithPrefix()[1]=%+v, want %+v", got, want)
+	}
+
+	// Ensure a prefix with no matching keys returns nothing.
+	if entries, err := s.KeysWithPrefix("baz"); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
 func TestTranslateStore_EntryReader(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
 		s := MustOpenNewTranslateStore()
@@ -69,6 +69,8 @@ type InternalClient interface {
 	SendMessage(ctx context.Context, uri *URI, msg []byte) error
 	RetrieveShardFromURI(ctx context.Context, index, field, view string, shard uint64, uri URI) (io.ReadCloser, error)
 	ImportRoaring(ctx context.Context, uri *URI, index, field string, shard uint64, remote bool, req *ImportRoaringRequest) error
+	DeleteColumn(ctx context.Context, uri *URI, index string, columnID uint64, remote bool) error
+	SyncFragment(ctx context.Context, uri *URI, index, field, view string, shard uint64) error
 }
 
 //===============
@@ -132,6 +134,12 @@ func (n nopInternalClient) ImportK(ctx context.Context, index, field string, bit
 func (n nopInternalClient) ImportRoaring(ctx context.Context, uri *URI, index, field string, shard uint64, remote bool, req *ImportRoaringRequest) error {
 	return nil
 }
+func (n nopInternalClient) DeleteColumn(ctx context.Context, uri *URI, index string, columnID uint64, remote bool) error {
+	return nil
+}
+func (n nopInternalClient) SyncFragment(ctx context.Context, uri *URI, index, field, view string, shard uint64) error {
+	return nil
+}
 func (n nopInternalClient) EnsureIndex(ctx context.Context, name string, options IndexOptions) error {
 	return nil
 }
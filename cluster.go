@@ -471,7 +471,7 @@ func (c *cluster) unprotectedSetState(state string) {
 		return
 	}
 
-	c.logger.Printf("change cluster state from %s to %s on %s", c.state, state, c.Node.ID)
+	c.logger.With("node", c.Node.ID).Printf("change cluster state from %s to %s", c.state, state)
 
 	var doCleanup bool
 
@@ -501,7 +501,7 @@ func (c *cluster) unprotectedSetState(state string) {
 
 		// Clean holder.
 		if err := cleaner.CleanHolder(); err != nil {
-			c.logger.Printf("holder clean error: err=%s", err)
+			c.logger.Errorf("holder clean error: err=%s", err)
 		}
 	}
 }
@@ -1012,7 +1012,7 @@ func (c *cluster) waitForStarted() error {
 			return fmt.Errorf("sending restart NodeJoin: %v", err)
 		}
 
-		c.logger.Printf("%v wait for joining to complete", c.Node.ID)
+		c.logger.With("node", c.Node.ID).Printf("wait for joining to complete")
 		<-c.joining
 		c.logger.Printf("joining has completed")
 	}
@@ -1065,9 +1065,9 @@ func (c *cluster) handleNodeAction(nodeAction nodeAction) error {
 	j, err := c.unprotectedGenerateResizeJob(nodeAction)
 	c.mu.Unlock()
 	if err != nil {
-		c.logger.Printf("generateResizeJob error: err=%s", err)
+		c.logger.Errorf("generateResizeJob error: err=%s", err)
 		if err := c.setStateAndBroadcast(ClusterStateNormal); err != nil {
-			c.logger.Printf("setStateAndBroadcast error: err=%s", err)
+			c.logger.Errorf("setStateAndBroadcast error: err=%s", err)
 		}
 		return errors.Wrap(err, "setting state")
 	}
@@ -1157,7 +1157,7 @@ func (c *cluster) listenForJoins() {
 			case nodeAction := <-c.joiningLeavingNodes:
 				err := c.handleNodeAction(nodeAction)
 				if err != nil {
-					c.logger.Printf("handleNodeAction error: err=%s", err)
+					c.logger.Errorf("handleNodeAction error: err=%s", err)
 					continue
 				}
 				setNormal = true
@@ -1169,7 +1169,7 @@ func (c *cluster) listenForJoins() {
 			if setNormal {
 				// Put the cluster back to state NORMAL and broadcast.
 				if err := c.setStateAndBroadcast(ClusterStateNormal); err != nil {
-					c.logger.Printf("setStateAndBroadcast error: err=%s", err)
+					c.logger.Errorf("setStateAndBroadcast error: err=%s", err)
 				}
 			}
 
@@ -1180,7 +1180,7 @@ func (c *cluster) listenForJoins() {
 			case nodeAction := <-c.joiningLeavingNodes:
 				err := c.handleNodeAction(nodeAction)
 				if err != nil {
-					c.logger.Printf("handleNodeAction error: err=%s", err)
+					c.logger.Errorf("handleNodeAction error: err=%s", err)
 					continue
 				}
 				setNormal = true
@@ -1295,7 +1295,7 @@ func (c *cluster) unprotectedCompleteCurrentJob(state string) error {
 
 // followResizeInstruction is run by any node that receives a ResizeInstruction.
 func (c *cluster) followResizeInstruction(instr *ResizeInstruction) error {
-	c.logger.Printf("follow resize instruction on %s", c.Node.ID)
+	c.logger.With("node", c.Node.ID).Printf("follow resize instruction")
 	// Make sure the cluster status on this node agrees with the Coordinator
 	// before attempting a resize.
 	if err := c.mergeClusterStatus(instr.ClusterStatus); err != nil {
@@ -1338,7 +1338,7 @@ func (c *cluster) followResizeInstruction(instr *ResizeInstruction) error {
 					// if we don't know about a field locally, log an error because
 					// fields should be created and synced prior to shard creation
 					if f == nil {
-						c.logger.Printf("local field not found: %s/%s", is.Name, fs.Name)
+						c.logger.With("index", is.Name).With("field", fs.Name).Errorf("local field not found")
 						continue
 					}
 					if err := f.AddRemoteAvailableShards(fs.AvailableShards); err != nil {
@@ -1404,7 +1404,7 @@ func (c *cluster) followResizeInstruction(instr *ResizeInstruction) error {
 		}
 
 		if err := c.sendTo(instr.Coordinator, complete); err != nil {
-			c.logger.Printf("sending resizeInstructionComplete error: err=%s", err)
+			c.logger.Errorf("sending resizeInstructionComplete error: err=%s", err)
 		}
 	}()
 	return nil
@@ -1676,18 +1676,28 @@ func (c *cluster) loadTopology() error {
 	return nil
 }
 
-// saveTopology writes the current topology to disk. unprotected.
+// saveTopology writes the current topology to disk. The file is written to a
+// temporary location and then renamed so that a crash mid-write cannot leave
+// a truncated topology behind. unprotected.
 func (c *cluster) saveTopology() error {
 
 	if err := os.MkdirAll(c.Path, 0777); err != nil {
 		return errors.Wrap(err, "creating directory")
 	}
 
+	path := filepath.Join(c.Path, ".topology")
+	tempPath := path + tempExt
+
 	if buf, err := proto.Marshal(encodeTopology(c.Topology)); err != nil {
 		return errors.Wrap(err, "marshalling")
-	} else if err := ioutil.WriteFile(filepath.Join(c.Path, ".topology"), buf, 0666); err != nil {
+	} else if err := ioutil.WriteFile(tempPath, buf, 0666); err != nil {
 		return errors.Wrap(err, "writing file")
 	}
+
+	// Move temp file to topology file location.
+	if err := os.Rename(tempPath, path); err != nil {
+		return errors.Wrap(err, "renaming file")
+	}
 	return nil
 }
 
@@ -1729,7 +1739,7 @@ func confirmNodeDown(uri URI, log logger.Logger) bool {
 	}
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		log.Printf("bad request:%s %s", u.String(), err)
+		log.Errorf("bad request:%s %s", u.String(), err)
 		return false
 	}
 	for i := 0; i < confirmDownRetries; i++ {
@@ -1744,7 +1754,7 @@ func confirmNodeDown(uri URI, log logger.Logger) bool {
 			}
 		}
 
-		log.Printf("NodeLeave confirm with %s %d. err: '%v' bod: '%s'", uri.HostPort(), i, err, bod)
+		log.With("uri", uri.HostPort()).Warnf("NodeLeave confirm %d. err: '%v' bod: '%s'", i, err, bod)
 		time.Sleep(confirmDownSleep * time.Second)
 	}
 	return true
@@ -1768,7 +1778,7 @@ func (c *cluster) ReceiveEvent(e *NodeEvent) (err error) {
 		c.mu.Lock()
 		defer c.mu.Unlock()
 		if c.unprotectedIsCoordinator() {
-			c.logger.Printf("received node leave: %v", e.Node)
+			c.logger.With("node", e.Node.ID).Warnf("received node leave: %v", e.Node)
 			// if removeNodeBasicSorted succeeds, that means that the node was
 			// not already removed by a removeNode request. We treat this as the
 			// host being temporarily unavailable, and expect it to come back
@@ -1781,11 +1791,11 @@ func (c *cluster) ReceiveEvent(e *NodeEvent) (err error) {
 					err = c.unprotectedSetStateAndBroadcast(c.determineClusterState())
 				}
 			} else {
-				c.logger.Printf("ignored received node leave: %v", e.Node)
+				c.logger.With("node", e.Node.ID).Printf("ignored received node leave: %v", e.Node)
 			}
 		}
 	case NodeUpdate:
-		c.logger.Printf("received node update event: id: %v, string: %v, uri: %v", e.Node.ID, e.Node.String(), e.Node.URI)
+		c.logger.With("node", e.Node.ID).With("uri", e.Node.URI).Printf("received node update event: %v", e.Node)
 		// NodeUpdate is intentionally not implemented.
 	}
 
@@ -1796,12 +1806,12 @@ func (c *cluster) ReceiveEvent(e *NodeEvent) (err error) {
 func (c *cluster) nodeJoin(node *Node) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.logger.Printf("node join event on coordinator, node: %s, id: %s", node.URI, node.ID)
+	c.logger.With("node", node.ID).With("uri", node.URI).Printf("node join event on coordinator")
 	if c.needTopologyAgreement() {
 		// A host that is not part of the topology can't be added to the STARTING cluster.
 		if !c.Topology.ContainsID(node.ID) {
 			err := fmt.Sprintf("host is not in topology: %s", node.ID)
-			c.logger.Printf("%v", err)
+			c.logger.Errorf("%v", err)
 			return errors.New(err)
 		}
 
@@ -1836,7 +1846,7 @@ func (c *cluster) nodeJoin(node *Node) error {
 	// the cluster.
 	if cnode := c.unprotectedNodeByID(node.ID); cnode != nil {
 		if cnode.URI != node.URI {
-			c.logger.Printf("node: %v changed URI from %s to %s", cnode.ID, cnode.URI, node.URI)
+			c.logger.With("node", cnode.ID).Printf("changed URI from %s to %s", cnode.URI, node.URI)
 			cnode.URI = node.URI
 		}
 		return c.unprotectedSetStateAndBroadcast(c.determineClusterState())
@@ -1862,8 +1872,10 @@ func (c *cluster) nodeJoin(node *Node) error {
 	return nil
 }
 
-// nodeLeave initiates the removal of a node from the cluster.
-func (c *cluster) nodeLeave(nodeID string) error {
+// nodeLeave initiates the removal of a node from the cluster. With force, the
+// node is removed straight away instead of having its data moved to the
+// remaining nodes first.
+func (c *cluster) nodeLeave(nodeID string, force bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	// Refuse the request if this is not the coordinator.
@@ -1872,7 +1884,7 @@ func (c *cluster) nodeLeave(nodeID string) error {
 			c.unprotectedCoordinatorNode().ID)
 	}
 
-	if c.state != ClusterStateNormal && c.state != ClusterStateDegraded {
+	if c.state != ClusterStateNormal && c.state != ClusterStateDegraded && !(force && c.state == ClusterStateStarting) {
 		return fmt.Errorf("cluster must be '%s' to remove a node but is '%s'",
 			ClusterStateNormal, c.state)
 	}
@@ -1887,21 +1899,42 @@ func (c *cluster) nodeLeave(nodeID string) error {
 		return fmt.Errorf("coordinator cannot be removed; first, make a different node the new coordinator")
 	}
 
-	// See if resize job can be generated
-	if _, err := c.unprotectedGenerateResizeJobByAction(
-		nodeAction{
-			node:   &Node{ID: nodeID},
-			action: resizeJobActionRemove},
-	); err != nil {
-		return errors.Wrap(err, "generating job")
+	// See if resize job can be generated, unless the data is not to be moved.
+	if !force {
+		if _, err := c.unprotectedGenerateResizeJobByAction(
+			nodeAction{
+				node:   &Node{ID: nodeID},
+				action: resizeJobActionRemove},
+		); err != nil {
+			return errors.Wrap(err, "generating job")
+		}
 	}
 
-	// If the holder does not yet contain data, go ahead and remove the node.
-	if ok, err := c.holder.HasData(); !ok && err == nil {
+	// If the holder does not yet contain data, or the data is not to be
+	// moved, go ahead and remove the node.
+	if ok, err := c.holder.HasData(); (!ok && err == nil) || force {
+		var gained map[string]fragsByHost
+		if ok {
+			gained = c.unprotectedGainedFrags(nodeID)
+		}
 		if err := c.removeNode(nodeID); err != nil {
 			return errors.Wrap(err, "removing node")
 		}
-		return c.unprotectedSetStateAndBroadcast(c.determineClusterState())
+		if err := c.unprotectedSetStateAndBroadcast(c.determineClusterState()); err != nil {
+			return err
+		}
+
+		// No resize job moves data to the new owners of the node's shards,
+		// and anti-entropy may be disabled, so sync them from the replicas
+		// which remain.
+		if len(gained) > 0 {
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				c.syncGainedFrags(gained)
+			}()
+		}
+		return nil
 	} else if err != nil {
 		return errors.Wrap(err, "checking if holder has data")
 	}
@@ -1916,6 +1949,62 @@ func (c *cluster) nodeLeave(nodeID string) error {
 	return nil
 }
 
+// unprotectedGainedFrags returns, by index and then by node ID, the
+// fragments which remaining nodes will own once the node with the given ID
+// is removed, but which they did not own while it was a member. A node
+// which is down has already been dropped from c.nodes, so it is added back
+// to find its fragments.
+func (c *cluster) unprotectedGainedFrags(nodeID string) map[string]fragsByHost {
+	from, to := newCluster(), newCluster()
+	for _, x := range []*cluster{from, to} {
+		x.nodes = Nodes(c.nodes).Clone()
+		x.Hasher = c.Hasher
+		x.partitionN = c.partitionN
+		x.ReplicaN = c.ReplicaN
+	}
+	from.addNodeBasicSorted(&Node{ID: nodeID})
+	to.removeNodeBasicSorted(nodeID)
+
+	gained := make(map[string]fragsByHost)
+	for _, idx := range c.holder.Indexes() {
+		fFrags := from.fragsByHost(idx)
+		for id, frags := range to.fragsByHost(idx) {
+			if diff := fragsDiff(frags, fFrags[id]); len(diff) > 0 {
+				if gained[idx.Name()] == nil {
+					gained[idx.Name()] = make(fragsByHost)
+				}
+				gained[idx.Name()][id] = diff
+			}
+		}
+	}
+	return gained
+}
+
+// syncGainedFrags asks each node to sync the fragments it has gained, as
+// returned by unprotectedGainedFrags, from their other replicas. Failures
+// are logged and left for anti-entropy.
+func (c *cluster) syncGainedFrags(gained map[string]fragsByHost) {
+	ctx := context.Background()
+	for index, byHost := range gained {
+		for id, frags := range byHost {
+			node := c.nodeByID(id)
+			if node == nil {
+				continue
+			}
+			for _, f := range frags {
+				select {
+				case <-c.closing:
+					return
+				default:
+				}
+				if err := c.InternalClient.SyncFragment(ctx, &node.URI, index, f.field, f.view, f.shard); err != nil {
+					c.logger.Printf("syncing fragment after removing node: index=%s, field=%s, view=%s, shard=%d, node=%s, err=%s", index, f.field, f.view, f.shard, id, err)
+				}
+			}
+		}
+	}
+}
+
 func (c *cluster) nodeStatus() *NodeStatus {
 	ns := &NodeStatus{
 		Node:   c.Node,
@@ -1943,7 +2032,7 @@ func (c *cluster) nodeStatus() *NodeStatus {
 func (c *cluster) mergeClusterStatus(cs *ClusterStatus) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.logger.Printf("merge cluster status: node=%s cluster=%v", c.Node.ID, cs)
+	c.logger.With("node", c.Node.ID).Printf("merge cluster status: cluster=%v", cs)
 	// Ignore status updates from self (coordinator).
 	if c.unprotectedIsCoordinator() {
 		return nil
@@ -1957,11 +2046,11 @@ func (c *cluster) mergeClusterStatus(cs *ClusterStatus) error {
 	// Add all nodes from the coordinator.
 	for _, node := range officialNodes {
 		if node.ID == c.Node.ID && node.State != c.Node.State {
-			c.logger.Printf("mismatched state in mergeClusterStatus got %v have %v", node.State, c.Node.State)
+			c.logger.With("node", node.ID).Warnf("mismatched state in mergeClusterStatus got %v have %v", node.State, c.Node.State)
 			go func(fromState, toState string) {
 				err := c.setNodeState(toState)
 				if err != nil {
-					c.logger.Printf("error setting node state from %v to %v: %v", fromState, toState, err)
+					c.logger.Errorf("error setting node state from %v to %v: %v", fromState, toState, err)
 				}
 			}(node.State, c.Node.State)
 		}
@@ -579,6 +579,25 @@ func TestCluster_Topology(t *testing.T) {
 			t.Errorf("ContainsHost error: %v", nodeinvalid.ID)
 		}
 	})
+
+	t.Run("SaveLoad", func(t *testing.T) {
+		if err := c1.saveTopology(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Ensure no temporary file is left behind.
+		if _, err := os.Stat(c1.Path + "/.topology" + tempExt); !os.IsNotExist(err) {
+			t.Fatalf("expected temporary topology file to be removed, got: %v", err)
+		}
+
+		c2 := newCluster()
+		c2.Path = c1.Path
+		if err := c2.loadTopology(); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(c2.Topology.nodeIDs, c1.Topology.nodeIDs) {
+			t.Errorf("expected topology: %v, but got: %v", c1.Topology.nodeIDs, c2.Topology.nodeIDs)
+		}
+	})
 }
 
 // Ensure that general cluster functionality works as expected.
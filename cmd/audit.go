@@ -0,0 +1,59 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var auditor *ctl.AuditCommand
+
+func newAuditCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	auditor = ctl.NewAuditCommand(stdin, stdout, stderr)
+	auditCmd := &cobra.Command{
+		Use:   "audit <path>",
+		Short: "Print recent entries from an audit log.",
+		Long: `
+Prints entries from the audit log file which a server writes when
+handler.audit-log-path is set. Each line shows the time, remote address,
+user, method, path and status of a request, followed by the query if
+there was one. Rotated files next to it, such as audit.log.1, are read
+as well. The files are read locally, so the command must be run on the
+node which wrote them.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("path required")
+			} else if len(args) > 1 {
+				return fmt.Errorf("only one path allowed")
+			}
+			auditor.Path = args[0]
+			return auditor.Run(context.Background())
+		},
+	}
+	flags := auditCmd.Flags()
+
+	flags.DurationVarP(&auditor.Since, "since", "", 0, "Only print entries newer than this, e.g. 1h - default all")
+	flags.IntVarP(&auditor.Limit, "limit", "n", 20, "Maximum number of entries to print, newest last - 0 prints all")
+	flags.StringVarP(&auditor.User, "user", "u", "", "Only print entries for this user")
+
+	return auditCmd
+}
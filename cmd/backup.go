@@ -0,0 +1,60 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Backuper *ctl.BackupCommand
+
+func newBackupCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Backuper = ctl.NewBackupCommand(stdin, stdout, stderr)
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up indexes to a tar archive.",
+		Long: `
+Backs up the schema and data of indexes to a tar archive, which can be
+loaded into an empty cluster with "pilosa restore". If the OUTFILE is not
+specified then the archive is written to STDOUT.
+
+Column and row attributes are included. Each fragment is read from the
+first of the nodes which own it that can be reached, at the moment it is
+read. Writes made while the backup runs may be partly included.
+
+The keys of indexes and fields which use keys cannot be backed up, so such
+indexes and fields are refused unless --force is given. With --force their
+IDs are backed up without keys, and no longer map to any key once restored.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Backuper.Run(context.Background())
+		},
+	}
+	flags := backupCmd.Flags()
+
+	flags.StringVarP(&Backuper.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.StringSliceVarP(&Backuper.Indexes, "index", "i", nil, "Pilosa indexes to back up - default all")
+	flags.StringVarP(&Backuper.Path, "output-file", "o", "", "File to write archive to - default stdout")
+	flags.BoolVarP(&Backuper.Force, "force", "", false, "Back up indexes and fields which use keys, without their keys")
+	ctl.SetTLSConfig(flags, &Backuper.TLS.CertificatePath, &Backuper.TLS.CertificateKeyPath, &Backuper.TLS.CACertPath, &Backuper.TLS.SkipVerify, &Backuper.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Backuper.APIKey)
+
+	return backupCmd
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Bencher *ctl.BenchCommand
+
+func newBenchCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Bencher = ctl.NewBenchCommand(stdin, stdout, stderr)
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark a running cluster.",
+		Long: `
+Sends a synthetic workload to a running cluster for a fixed duration, and
+reports the throughput and latency percentiles of the requests. Row and column
+IDs are drawn from a uniform or zipf distribution. The index and field are
+created if they don't exist.
+`,
+	}
+	flags := benchCmd.PersistentFlags()
+
+	flags.StringVarP(&Bencher.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.StringVarP(&Bencher.Index, "index", "i", "bench", "Pilosa index to use")
+	flags.StringVarP(&Bencher.Field, "field", "f", "bench", "Field to use")
+	flags.IntVarP(&Bencher.Concurrency, "concurrency", "", Bencher.Concurrency, "Number of requests to send at once")
+	flags.DurationVarP(&Bencher.Duration, "duration", "", Bencher.Duration, "How long to run for")
+	flags.StringVarP(&Bencher.Distribution, "distribution", "", Bencher.Distribution, "Distribution of row and column IDs: uniform or zipf")
+	flags.Float64VarP(&Bencher.ZipfS, "zipf-s", "", Bencher.ZipfS, "Exponent of the zipf distribution, greater than 1")
+	flags.Uint64VarP(&Bencher.MaxRowID, "max-row-id", "", Bencher.MaxRowID, "Row IDs are below this value")
+	flags.Uint64VarP(&Bencher.MaxColumnID, "max-column-id", "", Bencher.MaxColumnID, "Column IDs are below this value")
+	flags.Int64VarP(&Bencher.Seed, "seed", "", 0, "Seed for the random number generators")
+	ctl.SetTLSConfig(flags, &Bencher.TLS.CertificatePath, &Bencher.TLS.CertificateKeyPath, &Bencher.TLS.CACertPath, &Bencher.TLS.SkipVerify, &Bencher.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Bencher.APIKey)
+
+	benchCmd.AddCommand(newBenchOpCommand(ctl.BenchSetBit, "Set one bit per query."))
+	importCmd := newBenchOpCommand(ctl.BenchImport, "Import batches of bits.")
+	importCmd.Flags().IntVarP(&Bencher.BatchSize, "batch-size", "", Bencher.BatchSize, "Number of bits in each import")
+	benchCmd.AddCommand(importCmd)
+	benchCmd.AddCommand(newBenchOpCommand(ctl.BenchQuery, "Count the bits of a row per query."))
+
+	return benchCmd
+}
+
+func newBenchOpCommand(op, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   op,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			Bencher.Op = op
+			return Bencher.Run(context.Background())
+		},
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Certer *ctl.CertCommand
+
+func newCertCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Certer = ctl.NewCertCommand(stdin, stdout, stderr)
+	certCmd := &cobra.Command{
+		Use:   "cert [HOST...]",
+		Short: "Generate TLS certificates for a cluster.",
+		Long: `
+Generates a certificate and key for each host, signed by a CA, and prints
+the [tls] config section each node should use. The hosts are taken from the
+arguments or, if there are none, from cluster.hosts, so passing the cluster's
+config file with --config covers every node.
+
+The CA is created in the output directory if it has neither ca.crt nor
+ca.key, and reused otherwise, so certificates for new nodes can be added
+later. If only one of the two files is there, the command fails rather than
+replace the CA. Keep ca.key secret: anyone with it can join the cluster.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				Certer.Hosts = args
+			}
+			return Certer.Run(context.Background())
+		},
+	}
+	flags := certCmd.Flags()
+
+	flags.StringSliceVarP(&Certer.Hosts, "cluster.hosts", "", nil, "Hosts to generate certificates for, if none are given as arguments")
+	flags.StringVarP(&Certer.Dir, "dir", "d", Certer.Dir, "Directory to write certificates and keys to")
+	flags.DurationVarP(&Certer.ValidFor, "valid-for", "", Certer.ValidFor, "How long certificates are valid for")
+
+	return certCmd
+}
@@ -32,7 +32,13 @@ func newCheckCommand(stdin io.Reader, stdout io.Writer, stderr io.Writer) *cobra
 		Use:   "check <path> [path2]...",
 		Short: "Do a consistency check on a pilosa data file.",
 		Long: `
-Performs a consistency check on data files.
+Performs a consistency check on data files. If a path is a directory, such
+as the server's data directory, every fragment file beneath it is checked.
+
+The structure of each file and the checksums of its operation log are
+verified. Container data carries no checksum, so corruption which leaves
+a container well-formed is not detected; use "pilosa verify" to compare
+the replicas of a running cluster.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
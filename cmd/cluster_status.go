@@ -0,0 +1,52 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var ClusterStatuser *ctl.ClusterStatusCommand
+
+func newClusterStatusCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	ClusterStatuser = ctl.NewClusterStatusCommand(stdin, stdout, stderr)
+	clusterStatusCmd := &cobra.Command{
+		Use:   "cluster-status",
+		Short: "Show the state of the nodes of a cluster.",
+		Long: `
+Contacts a node and prints the state of the cluster, followed by each of its
+nodes: whether it is the coordinator, its state, whether it is ready to serve
+queries, how many shards it holds data of, and how many key translations it
+is missing compared to the node furthest ahead. A cluster which is being
+resized is in the RESIZING state.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ClusterStatuser.Run(context.Background())
+		},
+	}
+	flags := clusterStatusCmd.Flags()
+
+	flags.StringVarP(&ClusterStatuser.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.BoolVarP(&ClusterStatuser.JSON, "json", "", false, "Print the status as JSON.")
+	ctl.SetTLSConfig(flags, &ClusterStatuser.TLS.CertificatePath, &ClusterStatuser.TLS.CertificateKeyPath, &ClusterStatuser.TLS.CACertPath, &ClusterStatuser.TLS.SkipVerify, &ClusterStatuser.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &ClusterStatuser.APIKey)
+
+	return clusterStatusCmd
+}
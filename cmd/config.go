@@ -16,9 +16,14 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 
 	"github.com/pilosa/pilosa/v2/ctl"
 	"github.com/pilosa/pilosa/v2/server"
@@ -43,5 +48,112 @@ func newConfigCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command
 	// Attach flags to the command.
 	ctl.BuildServerFlags(confCmd, Server)
 
+	confCmd.AddCommand(newConfigShowCommand(stdin, stdout, stderr))
+	confCmd.AddCommand(newConfigValidateCommand(stdin, stdout, stderr))
+
 	return confCmd
 }
+
+func newConfigShowCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	show := ctl.NewConfigCommand(stdin, stdout, stderr)
+	Server := server.NewCommand(stdin, stdout, stderr)
+	var effective bool
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the default or effective configuration.",
+		Long: `
+Prints the default configuration. With --effective, prints the configuration a
+server would run with given the same config file, environment variables and
+flags.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			show.Config = server.NewConfig()
+			if effective {
+				show.Config = Server.Config
+			}
+			return show.Run(context.Background())
+		},
+	}
+
+	ctl.BuildServerFlags(showCmd, Server)
+	showCmd.Flags().BoolVar(&effective, "effective", false, "Merge the config file, environment variables and flags.")
+
+	return showCmd
+}
+
+func newConfigValidateCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Check a configuration file for errors.",
+		Long: `
+Parses a TOML configuration file and reports unknown options, values of the
+wrong type and values out of range. Environment variables and flags are not
+taken into account.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := validateConfigFile(args[0])
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				fmt.Fprintln(stdout, p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%s is not valid", args[0])
+			}
+			fmt.Fprintf(stdout, "%s is valid\n", args[0])
+			return nil
+		},
+	}
+	return validateCmd
+}
+
+// validateConfigFile reads the config file at path and returns a description
+// of each problem found in it. Unlike setAllConfig, it carries on past the
+// first bad option so that they can all be reported at once.
+func validateConfigFile(path string) ([]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("toml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading configuration file '%s': %v", path, err)
+	}
+
+	// Use the server's flags as the definition of the valid options.
+	srv := server.NewCommand(nil, nil, nil)
+	cmd := &cobra.Command{}
+	ctl.BuildServerFlags(cmd, srv)
+	flags := cmd.Flags()
+
+	var problems []string
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := flags.Lookup(key)
+		if f == nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid option", key))
+			continue
+		}
+		if err := setFlagFromViper(v, f); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	if err := srv.Config.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems, nil
+}
+
+// setFlagFromViper sets f to the value of the option with the same name in v.
+func setFlagFromViper(v *viper.Viper, f *pflag.Flag) error {
+	if f.Value.Type() == "stringSlice" {
+		// See setAllConfig.
+		return f.Value.Set(strings.Join(v.GetStringSlice(f.Name), ","))
+	}
+	return f.Value.Set(v.GetString(f.Name))
+}
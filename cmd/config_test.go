@@ -0,0 +1,76 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigShowCommand(t *testing.T) {
+	output, err := ExecNewRootCommand(t, "config", "show", "--bind", "localhost:10999")
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(output, `bind = ":10101"`) {
+		t.Fatalf("expected default bind, got:\n%s", output)
+	}
+
+	output, err = ExecNewRootCommand(t, "config", "show", "--effective", "--bind", "localhost:10999")
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(output, `bind = "localhost:10999"`) {
+		t.Fatalf("expected effective bind, got:\n%s", output)
+	}
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	file, err := ioutil.TempFile("", "test.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(`bind = "127.0.0.1:10101"
+max-writes-per-request = 100
+
+[cluster]
+  replicas = 2
+`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	output, err := ExecNewRootCommand(t, "config", "validate", file.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(output, "is valid") {
+		t.Fatalf("unexpected output: %s", output)
+	}
+
+	for _, config := range []string{
+		"[cluster]\n  partitions = 128\n",
+		"max-writes-per-request = \"many\"\n",
+		"[cluster]\n  replicas = 0\n",
+		"[metric]\n  service = \"graphite\"\n",
+	} {
+		if err := ioutil.WriteFile(file.Name(), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ExecNewRootCommand(t, "config", "validate", file.Name()); err == nil {
+			t.Fatalf("expected error for config:\n%s", config)
+		}
+	}
+}
@@ -17,7 +17,9 @@ package cmd
 import (
 	"context"
 	"io"
+	"strconv"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/pilosa/pilosa/v2/ctl"
@@ -27,6 +29,7 @@ var Exporter *ctl.ExportCommand
 
 func newExportCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
 	Exporter = ctl.NewExportCommand(stdin, stdout, stderr)
+	var shards []string
 	exportCmd := &cobra.Command{
 		Use:   "export",
 		Short: "Export data from pilosa.",
@@ -38,9 +41,24 @@ The format of the CSV file is:
 
 	ROWID,COLUMNID
 
-The file does not contain any headers.
+The file does not contain any headers. Only the standard view is exported,
+so the time views of a time field and the view of an int field are left out.
+
+With --format=roaring, every view of the field is exported instead, as one
+file per fragment named VIEW/SHARD under the OUTFILE directory. --views
+limits the export to the given views.
+
+--shards limits either format to the given shards.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			Exporter.Shards = Exporter.Shards[:0]
+			for _, s := range shards {
+				shard, err := strconv.ParseUint(s, 10, 64)
+				if err != nil {
+					return errors.Wrapf(err, "invalid shard %q", s)
+				}
+				Exporter.Shards = append(Exporter.Shards, shard)
+			}
 			return Exporter.Run(context.Background())
 		},
 	}
@@ -50,7 +68,11 @@ The file does not contain any headers.
 	flags.StringVarP(&Exporter.Index, "index", "i", "", "Pilosa index to export")
 	flags.StringVarP(&Exporter.Field, "field", "f", "", "Field to export")
 	flags.StringVarP(&Exporter.Path, "output-file", "o", "", "File to write export to - default stdout")
+	flags.StringVarP(&Exporter.Format, "format", "", "csv", "Format of the export: csv or roaring")
+	flags.StringSliceVarP(&shards, "shards", "", nil, "Shards to export - default all")
+	flags.StringSliceVarP(&Exporter.Views, "views", "", nil, "Views to export, for the roaring format - default all")
 	ctl.SetTLSConfig(flags, &Exporter.TLS.CertificatePath, &Exporter.TLS.CertificateKeyPath, &Exporter.TLS.CACertPath, &Exporter.TLS.SkipVerify, &Exporter.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Exporter.APIKey)
 
 	return exportCmd
 }
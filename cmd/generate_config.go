@@ -17,6 +17,7 @@ package cmd
 import (
 	"context"
 	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -31,11 +32,20 @@ func newGenerateConfigCommand(stdin io.Reader, stdout io.Writer, stderr io.Write
 		Use:   "generate-config",
 		Short: "Print the default configuration.",
 		Long: `generate-config prints the default configuration to stdout
+
+A preset adjusts the defaults for a kind of deployment:
+
+  single-node    one server, without gossip or replication
+  small-cluster  a few nodes, with 2 replicas
+  large-cluster  many nodes, with 3 replicas and raised limits
+  dev            one server on localhost, with verbose logging
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return generateConf.Run(context.Background())
 		},
 	}
+	flags := confCmd.Flags()
+	flags.StringVarP(&generateConf.Preset, "preset", "", "", "Preset to apply to the defaults, one of ["+strings.Join(ctl.ConfigPresets(), ", ")+"].")
 
 	return confCmd
 }
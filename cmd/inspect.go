@@ -34,6 +34,10 @@ func newInspectCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command
 		Short: "Get stats on a pilosa data file.",
 		Long: `
 Inspects a data file and provides stats.
+
+Prints the file header and storage version, whether the op log checksums
+are valid, a histogram of container types, the distribution of bits across
+rows, and the list of containers.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
@@ -0,0 +1,94 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var NodeWaiter *ctl.NodeWaitCommand
+var NodeRemover *ctl.NodeRemoveCommand
+
+func newNodeCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	nodeCmd := &cobra.Command{
+		Use:   "node",
+		Short: "Wait for or remove cluster nodes.",
+	}
+	nodeCmd.AddCommand(newNodeWaitCommand(stdin, stdout, stderr))
+	nodeCmd.AddCommand(newNodeRemoveCommand(stdin, stdout, stderr))
+	return nodeCmd
+}
+
+func newNodeWaitCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	NodeWaiter = ctl.NewNodeWaitCommand(stdin, stdout, stderr)
+	waitCmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for a node to join the cluster.",
+		Long: `
+Waits until a node has joined the cluster and received its share of the data.
+This command does not add the node: a node joins a cluster when it is started
+with one of the cluster's nodes in gossip.seeds, and the coordinator then moves
+data to it. The command only polls the cluster's status until then.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return NodeWaiter.Run(context.Background())
+		},
+	}
+	flags := waitCmd.Flags()
+
+	flags.StringVarP(&NodeWaiter.Host, "host", "", "localhost:10101", "host:port of a Pilosa node in the cluster.")
+	flags.StringVarP(&NodeWaiter.Node, "node", "n", "", "ID or host:port of the joining node")
+	flags.DurationVarP(&NodeWaiter.Timeout, "timeout", "", 0, "How long to wait. Zero means no limit")
+	ctl.SetTLSConfig(flags, &NodeWaiter.TLS.CertificatePath, &NodeWaiter.TLS.CertificateKeyPath, &NodeWaiter.TLS.CACertPath, &NodeWaiter.TLS.SkipVerify, &NodeWaiter.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &NodeWaiter.APIKey)
+
+	return waitCmd
+}
+
+func newNodeRemoveCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	NodeRemover = ctl.NewNodeRemoveCommand(stdin, stdout, stderr)
+	removeCmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a node from the cluster.",
+		Long: `
+Asks the coordinator to remove a node from the cluster, and waits until its
+data has been moved to the remaining nodes. This needs a replica of each shard
+of the node on another node, so a node which is down can only be removed from
+a cluster with more than one replica.
+
+With --force, the node is removed without moving its data. Use it for nodes
+which are gone for good: data held only by the node is lost, and the new
+owners of its shards sync the rest from the other replicas in the background.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return NodeRemover.Run(context.Background())
+		},
+	}
+	flags := removeCmd.Flags()
+
+	flags.StringVarP(&NodeRemover.Host, "host", "", "localhost:10101", "host:port of a Pilosa node in the cluster.")
+	flags.StringVarP(&NodeRemover.Node, "node", "n", "", "ID or host:port of the node to remove")
+	flags.BoolVarP(&NodeRemover.Force, "force", "", false, "Remove the node without moving its data")
+	flags.DurationVarP(&NodeRemover.Timeout, "timeout", "", 0, "How long to wait. Zero means no limit")
+	ctl.SetTLSConfig(flags, &NodeRemover.TLS.CertificatePath, &NodeRemover.TLS.CertificateKeyPath, &NodeRemover.TLS.CACertPath, &NodeRemover.TLS.SkipVerify, &NodeRemover.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &NodeRemover.APIKey)
+
+	return removeCmd
+}
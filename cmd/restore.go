@@ -0,0 +1,55 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Restorer *ctl.RestoreCommand
+
+func newRestoreCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Restorer = ctl.NewRestoreCommand(stdin, stdout, stderr)
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore indexes from a tar archive.",
+		Long: `
+Restores the schema and data of indexes from a tar archive written by
+"pilosa backup". If the INFILE is not specified then the archive is read
+from STDIN.
+
+Indexes, fields and views are created if they do not exist. Each fragment
+in the archive replaces the data of that shard, and is copied to every node
+which owns it in the target cluster. That cluster need not have the same
+number of nodes as the cluster that was backed up.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Restorer.Run(context.Background())
+		},
+	}
+	flags := restoreCmd.Flags()
+
+	flags.StringVarP(&Restorer.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.StringVarP(&Restorer.Path, "input-file", "f", "", "File to read archive from - default stdin")
+	ctl.SetTLSConfig(flags, &Restorer.TLS.CertificatePath, &Restorer.TLS.CertificateKeyPath, &Restorer.TLS.CACertPath, &Restorer.TLS.SkipVerify, &Restorer.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Restorer.APIKey)
+
+	return restoreCmd
+}
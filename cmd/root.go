@@ -68,13 +68,23 @@ Build Time: ` + pilosa.BuildTime + "\n",
 	_ = rc.PersistentFlags().MarkHidden("dry-run")
 	rc.PersistentFlags().StringP("config", "c", "", "Configuration file to read from.")
 
+	rc.AddCommand(newAuditCommand(stdin, stdout, stderr))
+	rc.AddCommand(newBackupCommand(stdin, stdout, stderr))
+	rc.AddCommand(newBenchCommand(stdin, stdout, stderr))
+	rc.AddCommand(newCertCommand(stdin, stdout, stderr))
 	rc.AddCommand(newCheckCommand(stdin, stdout, stderr))
+	rc.AddCommand(newClusterStatusCommand(stdin, stdout, stderr))
 	rc.AddCommand(newConfigCommand(stdin, stdout, stderr))
 	rc.AddCommand(newExportCommand(stdin, stdout, stderr))
 	rc.AddCommand(newGenerateConfigCommand(stdin, stdout, stderr))
 	rc.AddCommand(newImportCommand(stdin, stdout, stderr))
 	rc.AddCommand(newInspectCommand(stdin, stdout, stderr))
+	rc.AddCommand(newNodeCommand(stdin, stdout, stderr))
+	rc.AddCommand(newRestoreCommand(stdin, stdout, stderr))
+	rc.AddCommand(newSchemaCommand(stdin, stdout, stderr))
 	rc.AddCommand(newServeCmd(stdin, stdout, stderr))
+	rc.AddCommand(newShellCommand(stdin, stdout, stderr))
+	rc.AddCommand(newVerifyCommand(stdin, stdout, stderr))
 	rc.AddCommand(newHolderCmd(stdin, stdout, stderr))
 
 	rc.SetOutput(stderr)
@@ -0,0 +1,85 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var SchemaApplier *ctl.SchemaApplyCommand
+
+func newSchemaCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Manage the schema of a cluster.",
+	}
+	schemaCmd.AddCommand(newSchemaApplyCommand(stdin, stdout, stderr))
+	return schemaCmd
+}
+
+func newSchemaApplyCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	SchemaApplier = ctl.NewSchemaApplyCommand(stdin, stdout, stderr)
+	applyCmd := &cobra.Command{
+		Use:   "apply SCHEMA_FILE",
+		Short: "Change the schema of a cluster to match a file.",
+		Long: `
+Compares the indexes and fields described by a schema file, in YAML or JSON,
+with those of the cluster, prints the changes needed for them to match, and
+makes them. For example:
+
+  indexes:
+    - name: repository
+      options:
+        trackExistence: true
+      fields:
+        - name: stargazer
+        - name: language
+          options:
+            type: mutex
+        - name: stars
+          options:
+            type: int
+            min: 0
+            max: 1000000
+
+Options are named as in the HTTP API. Options which are left out get their
+defaults when an index or field is created, and are ignored otherwise.
+
+Options of existing indexes and fields can't be changed, so indexes and fields
+whose options differ are dropped and created again. That, and dropping the
+indexes and fields which are not in the file, loses data: these changes are
+only made with --drop. Use --plan to print the changes without making them.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			SchemaApplier.Path = args[0]
+			return SchemaApplier.Run(context.Background())
+		},
+	}
+	flags := applyCmd.Flags()
+
+	flags.StringVarP(&SchemaApplier.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.BoolVarP(&SchemaApplier.Plan, "plan", "", false, "Print the changes without making them")
+	flags.BoolVarP(&SchemaApplier.Drop, "drop", "", false, "Drop and recreate indexes and fields, losing their data")
+	ctl.SetTLSConfig(flags, &SchemaApplier.TLS.CertificatePath, &SchemaApplier.TLS.CertificateKeyPath, &SchemaApplier.TLS.CACertPath, &SchemaApplier.TLS.SkipVerify, &SchemaApplier.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &SchemaApplier.APIKey)
+
+	return applyCmd
+}
@@ -93,10 +93,13 @@ func TestServerConfig(t *testing.T) {
 				"PILOSA_TRANSLATION_MAP_SIZE":   "100000",
 				"PILOSA_PROFILE_BLOCK_RATE":     "9123",
 				"PILOSA_PROFILE_MUTEX_FRACTION": "444",
+				"PILOSA_WORKER_POOL_SIZE":       "3",
 			},
 			cfgFileContent: `
 	bind = "localhost:0"
 	data-dir = "` + actualDataDir + `"
+	worker-pool-size = 5
+	import-worker-pool-size = 4
 	[cluster]
 		disabled = true
 		hosts = [
@@ -113,6 +116,8 @@ func TestServerConfig(t *testing.T) {
 				v.Check(cmd.Server.Config.Translation.MapSize, 100000)
 				v.Check(cmd.Server.Config.Profile.BlockRate, 4832)
 				v.Check(cmd.Server.Config.Profile.MutexFraction, 8290)
+				v.Check(cmd.Server.Config.WorkerPoolSize, 3)
+				v.Check(cmd.Server.Config.ImportWorkerPoolSize, 4)
 				return v.Error()
 			},
 		},
@@ -0,0 +1,64 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Sheller *ctl.ShellCommand
+
+func newShellCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Sheller = ctl.NewShellCommand(stdin, stdout, stderr)
+	shellCmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run queries interactively.",
+		Long: `
+Starts an interactive shell for running PQL queries. A query may span several
+lines, and runs once its parentheses are balanced. On a terminal, the up and
+down keys recall previous queries, and tab completes PQL calls, field names
+and, after \c and \d, index names.
+
+Enter \h in the shell for a list of commands. When the input isn't a
+terminal, queries are read from it one after the other, so a script can be
+piped to the shell.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Sheller.Run(context.Background())
+		},
+	}
+	flags := shellCmd.Flags()
+
+	var history string
+	if home := os.Getenv("HOME"); home != "" {
+		history = filepath.Join(home, ".pilosa_history")
+	}
+
+	flags.StringVarP(&Sheller.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.StringVarP(&Sheller.Index, "index", "i", "", "Pilosa index to query")
+	flags.StringVarP(&Sheller.Format, "format", "f", ctl.ShellFormatTable, "Output format of results, table or json")
+	flags.StringVarP(&Sheller.HistoryPath, "history-file", "", history, "File to save queries to. Empty to save nothing")
+	ctl.SetTLSConfig(flags, &Sheller.TLS.CertificatePath, &Sheller.TLS.CertificateKeyPath, &Sheller.TLS.CACertPath, &Sheller.TLS.SkipVerify, &Sheller.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Sheller.APIKey)
+
+	return shellCmd
+}
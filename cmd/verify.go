@@ -0,0 +1,55 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pilosa/pilosa/v2/ctl"
+)
+
+var Verifier *ctl.VerifyCommand
+
+func newVerifyCommand(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	Verifier = ctl.NewVerifyCommand(stdin, stdout, stderr)
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the replicas of each fragment match.",
+		Long: `
+Compares the block checksums of every fragment on each node which owns it,
+and prints the fragments which are missing from a node or whose blocks
+differ between nodes. With --repair, anti-entropy is run for each of those
+fragments, merging the blocks which differ, and they are checked again.
+
+Exits with an error if any fragment's replicas still differ. Shards with a
+single replica are skipped.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Verifier.Run(context.Background())
+		},
+	}
+	flags := verifyCmd.Flags()
+
+	flags.StringVarP(&Verifier.Host, "host", "", "localhost:10101", "host:port of Pilosa.")
+	flags.StringSliceVarP(&Verifier.Indexes, "index", "i", nil, "Pilosa indexes to verify - default all")
+	flags.BoolVarP(&Verifier.Repair, "repair", "", false, "Run anti-entropy for fragments whose replicas differ")
+	ctl.SetTLSConfig(flags, &Verifier.TLS.CertificatePath, &Verifier.TLS.CertificateKeyPath, &Verifier.TLS.CACertPath, &Verifier.TLS.SkipVerify, &Verifier.TLS.EnableClientVerification)
+	ctl.SetAPIKey(flags, &Verifier.APIKey)
+
+	return verifyCmd
+}
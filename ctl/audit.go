@@ -0,0 +1,131 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pkg/errors"
+)
+
+// AuditCommand represents a command for printing recent entries from a
+// server's audit log.
+type AuditCommand struct {
+	// Path of the audit log file.
+	Path string
+
+	// Only entries newer than Since are printed. Zero prints all entries.
+	Since time.Duration
+
+	// Maximum number of entries to print, counting back from the newest.
+	// Zero prints all entries.
+	Limit int
+
+	// Only entries recorded for User are printed, if set.
+	User string
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	now func() time.Time
+}
+
+// NewAuditCommand returns a new instance of AuditCommand.
+func NewAuditCommand(stdin io.Reader, stdout, stderr io.Writer) *AuditCommand {
+	return &AuditCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+		now:   time.Now,
+	}
+}
+
+// Run executes the audit command.
+func (cmd *AuditCommand) Run(_ context.Context) error {
+	if _, err := os.Stat(cmd.Path); err != nil {
+		return errors.Wrap(err, "opening audit log")
+	}
+
+	var since time.Time
+	if cmd.Since > 0 {
+		since = cmd.now().Add(-cmd.Since)
+	}
+
+	// Read the rotated logs, oldest first, and then the current one.
+	paths := []string{cmd.Path}
+	for i := 1; ; i++ {
+		path := fmt.Sprintf("%s.%d", cmd.Path, i)
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		paths = append([]string{path}, paths...)
+	}
+
+	var entries []http.AuditEntry
+	for _, path := range paths {
+		var err error
+		if entries, err = cmd.readEntries(path, since, entries); err != nil {
+			return errors.Wrapf(err, "reading %s", path)
+		}
+	}
+
+	for _, entry := range entries {
+		user := entry.User
+		if user == "" {
+			user = "-"
+		}
+		fmt.Fprintf(cmd.Stdout, "%s %s %s %s %s %d", entry.Time.Format(time.RFC3339), entry.RemoteAddr, user, entry.Method, entry.Path, entry.Status)
+		if entry.Query != "" {
+			fmt.Fprintf(cmd.Stdout, " %q", entry.Query)
+		}
+		fmt.Fprintln(cmd.Stdout)
+	}
+	return nil
+}
+
+// readEntries appends the matching entries of the log at path to entries.
+// Only the newest Limit entries are kept. The log is in time order, so older
+// entries are dropped from the front as newer ones are read.
+func (cmd *AuditCommand) readEntries(path string, since time.Time, entries []http.AuditEntry) ([]http.AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening audit log")
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		var entry http.AuditEntry
+		if err := dec.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "decoding audit entry")
+		}
+
+		if entry.Time.Before(since) || (cmd.User != "" && entry.User != cmd.User) {
+			continue
+		}
+		entries = append(entries, entry)
+		if cmd.Limit > 0 && len(entries) > cmd.Limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, nil
+}
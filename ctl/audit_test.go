@@ -0,0 +1,100 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAuditCommand_Run(t *testing.T) {
+	f, err := ioutil.TempFile("", "pilosa-audit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	// The oldest entry has been rotated into a backup.
+	defer os.Remove(f.Name() + ".1")
+	if err := ioutil.WriteFile(f.Name()+".1", []byte(`{"time":"2019-01-01T10:00:00Z","remoteAddr":"10.0.0.1:5000","user":"alice","userAgent":"curl","method":"POST","path":"/index/i","status":200}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"time":"2019-01-01T11:00:00Z","remoteAddr":"10.0.0.2:5000","userAgent":"curl","method":"DELETE","path":"/index/j","status":404}
+{"time":"2019-01-01T11:30:00Z","remoteAddr":"10.0.0.1:5000","user":"alice","userAgent":"curl","method":"POST","path":"/index/i/query","query":"Set(1, f=1)","status":200}
+`); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		since time.Duration
+		limit int
+		user  string
+		exp   string
+	}{
+		{
+			name: "All",
+			exp: "2019-01-01T10:00:00Z 10.0.0.1:5000 alice POST /index/i 200\n" +
+				"2019-01-01T11:00:00Z 10.0.0.2:5000 - DELETE /index/j 404\n" +
+				"2019-01-01T11:30:00Z 10.0.0.1:5000 alice POST /index/i/query 200 \"Set(1, f=1)\"\n",
+		},
+		{
+			name:  "Limit",
+			limit: 1,
+			exp:   "2019-01-01T11:30:00Z 10.0.0.1:5000 alice POST /index/i/query 200 \"Set(1, f=1)\"\n",
+		},
+		{
+			name:  "Since",
+			since: 90 * time.Minute,
+			exp: "2019-01-01T11:00:00Z 10.0.0.2:5000 - DELETE /index/j 404\n" +
+				"2019-01-01T11:30:00Z 10.0.0.1:5000 alice POST /index/i/query 200 \"Set(1, f=1)\"\n",
+		},
+		{
+			name:  "User",
+			limit: 5,
+			user:  "alice",
+			exp: "2019-01-01T10:00:00Z 10.0.0.1:5000 alice POST /index/i 200\n" +
+				"2019-01-01T11:30:00Z 10.0.0.1:5000 alice POST /index/i/query 200 \"Set(1, f=1)\"\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cm := NewAuditCommand(nil, &buf, ioutil.Discard)
+			cm.now = func() time.Time { return time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC) }
+			cm.Path = f.Name()
+			cm.Since, cm.Limit, cm.User = tt.since, tt.limit, tt.user
+			if err := cm.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			} else if buf.String() != tt.exp {
+				t.Fatalf("unexpected output:\n%s", buf.String())
+			}
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		cm := NewAuditCommand(nil, ioutil.Discard, ioutil.Discard)
+		cm.Path = f.Name() + ".missing"
+		if err := cm.Run(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,301 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// backupSchemaName is the name of the archive entry holding the schema. It
+// is always the first entry. It is followed by the column attributes of each
+// index, named "columnattrs/INDEX", the row attributes of each field, named
+// "rowattrs/INDEX/FIELD", and one entry per fragment, named
+// "fragments/INDEX/FIELD/VIEW/SHARD".
+const backupSchemaName = "schema.json"
+
+// BackupCommand represents a command for backing up indexes to a tar archive.
+type BackupCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Names of the indexes to back up. All indexes if empty.
+	Indexes []string
+
+	// Filename to write the archive to.
+	Path string
+
+	// Back up indexes and fields which use keys. Their keys are not backed
+	// up, so the IDs which are restored no longer map to any key.
+	Force bool
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewBackupCommand returns a new instance of BackupCommand.
+func NewBackupCommand(stdin io.Reader, stdout, stderr io.Writer) *BackupCommand {
+	return &BackupCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// Run executes the backup.
+func (cmd *BackupCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	schema, err := client.SchemaWithViews(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting schema")
+	}
+	indexes, err := selectIndexes(schema, cmd.Indexes)
+	if err != nil {
+		return err
+	}
+
+	// Key translation data cannot be read back from a cluster, so keyed
+	// indexes and fields would be restored as bare IDs.
+	for _, ii := range indexes {
+		if cmd.Force {
+			break
+		}
+		if ii.Options.Keys {
+			return errors.Errorf("index %s uses keys, which cannot be backed up; use --force to back up its IDs without keys", ii.Name)
+		}
+		for _, fi := range ii.Fields {
+			if fi.Options.Keys {
+				return errors.Errorf("field %s of index %s uses keys, which cannot be backed up; use --force to back up its IDs without keys", fi.Name, ii.Name)
+			}
+		}
+	}
+
+	// Every node holds all attributes, so they are read from the first
+	// node which answers.
+	nodes, err := client.Nodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting nodes")
+	}
+
+	maxShards, err := client.MaxShardByIndex(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting shard count")
+	}
+
+	// Use output file, if specified.
+	// Otherwise use STDOUT.
+	var w io.Writer = cmd.Stdout
+	if cmd.Path != "" {
+		f, err := os.Create(cmd.Path)
+		if err != nil {
+			return errors.Wrap(err, "creating file")
+		}
+		defer f.Close()
+
+		w = f
+	}
+	tw := tar.NewWriter(w)
+
+	buf, err := json.Marshal(indexes)
+	if err != nil {
+		return errors.Wrap(err, "marshaling schema")
+	}
+	if err := writeTarEntry(tw, backupSchemaName, buf); err != nil {
+		return err
+	}
+
+	for _, ii := range indexes {
+		if ii.Options.Keys {
+			logger.Printf("index %s uses keys, which are not backed up, nor are its column attributes", ii.Name)
+		} else if err := cmd.backupAttrs(ctx, tw, path.Join("columnattrs", ii.Name), nodes, func(uri *pilosa.URI) (map[uint64]map[string]interface{}, error) {
+			return client.ColumnAttrDiff(ctx, uri, ii.Name, nil)
+		}); err != nil {
+			return errors.Wrapf(err, "backing up column attributes of index %s", ii.Name)
+		}
+		for _, fi := range ii.Fields {
+			if fi.Options.Keys {
+				logger.Printf("field %s of index %s uses keys, which are not backed up, nor are its row attributes", fi.Name, ii.Name)
+				continue
+			}
+			fieldName := fi.Name
+			if err := cmd.backupAttrs(ctx, tw, path.Join("rowattrs", ii.Name, fi.Name), nodes, func(uri *pilosa.URI) (map[uint64]map[string]interface{}, error) {
+				return client.RowAttrDiff(ctx, uri, ii.Name, fieldName, nil)
+			}); err != nil {
+				return errors.Wrapf(err, "backing up row attributes of field %s/%s", ii.Name, fi.Name)
+			}
+		}
+
+		for shard := uint64(0); shard <= maxShards[ii.Name]; shard++ {
+			nodes, err := client.FragmentNodes(ctx, ii.Name, shard)
+			if err != nil {
+				return errors.Wrap(err, "getting fragment nodes")
+			} else if len(nodes) == 0 {
+				return errors.Errorf("no nodes own shard %d of index %s", shard, ii.Name)
+			}
+
+			logger.Printf("backing up index %s, shard %d", ii.Name, shard)
+			for _, fi := range ii.Fields {
+				for _, vi := range fi.Views {
+					data, err := retrieveReplica(ctx, client, nodes, ii.Name, fi.Name, vi.Name, shard)
+					if err == pilosa.ErrFragmentNotFound {
+						continue
+					} else if err != nil {
+						return errors.Wrapf(err, "retrieving fragment %s/%s/%s/%d", ii.Name, fi.Name, vi.Name, shard)
+					}
+
+					name := path.Join("fragments", ii.Name, fi.Name, vi.Name, fmt.Sprint(shard))
+					if err := writeTarEntry(tw, name, data); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "closing archive")
+	}
+
+	// Close writer, if applicable.
+	if w, ok := w.(io.Closer); ok {
+		if err := w.Close(); err != nil {
+			return errors.Wrap(err, "closing")
+		}
+	}
+
+	return nil
+}
+
+// backupAttrs writes the attributes returned by the first of nodes to answer
+// fetch to tw, as a JSON object named name. Nothing is written if there are
+// no attributes.
+func (cmd *BackupCommand) backupAttrs(ctx context.Context, tw *tar.Writer, name string, nodes []*pilosa.Node, fetch func(uri *pilosa.URI) (map[uint64]map[string]interface{}, error)) error {
+	var attrs map[uint64]map[string]interface{}
+	err := errors.New("no nodes")
+	for _, node := range nodes {
+		uri := node.URI
+		if attrs, err = fetch(&uri); err == nil {
+			break
+		}
+		cmd.Logger().Printf("reading attributes from %s: %s", node.URI, err)
+	}
+	if err != nil {
+		return err
+	} else if len(attrs) == 0 {
+		return nil
+	}
+
+	buf, err := json.Marshal(attrs)
+	if err != nil {
+		return errors.Wrap(err, "marshaling attributes")
+	}
+	return writeTarEntry(tw, name, buf)
+}
+
+// retrieveReplica returns the data of a fragment from the first of its
+// replicas, nodes, which can be reached.
+func retrieveReplica(ctx context.Context, client *http.InternalClient, nodes []*pilosa.Node, index, field, view string, shard uint64) ([]byte, error) {
+	var err error
+	for _, node := range nodes {
+		var data []byte
+		data, err = retrieveFragment(ctx, client, node.URI, index, field, view, shard)
+		if err == nil || err == pilosa.ErrFragmentNotFound {
+			return data, err
+		}
+	}
+	return nil, err
+}
+
+// retrieveFragment returns the data of a fragment from the node at uri.
+func retrieveFragment(ctx context.Context, client *http.InternalClient, uri pilosa.URI, index, field, view string, shard uint64) ([]byte, error) {
+	rc, err := client.RetrieveShardFromURI(ctx, index, field, view, shard, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// selectIndexes returns the indexes in schema with the given names, or all of
+// them if names is empty.
+func selectIndexes(schema []*pilosa.IndexInfo, names []string) ([]*pilosa.IndexInfo, error) {
+	if len(names) == 0 {
+		return schema, nil
+	}
+
+	byName := make(map[string]*pilosa.IndexInfo, len(schema))
+	for _, ii := range schema {
+		byName[ii.Name] = ii
+	}
+	indexes := make([]*pilosa.IndexInfo, 0, len(names))
+	for _, name := range names {
+		ii, ok := byName[name]
+		if !ok {
+			return nil, errors.Wrap(pilosa.ErrIndexNotFound, name)
+		}
+		indexes = append(indexes, ii)
+	}
+	return indexes, nil
+}
+
+// writeTarEntry writes a file named name containing data to tw.
+func writeTarEntry(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return errors.Wrapf(err, "writing header of %s", name)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return errors.Wrapf(err, "writing %s", name)
+	}
+	return nil
+}
+
+func (cmd *BackupCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *BackupCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *BackupCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
@@ -0,0 +1,190 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestBackupRestoreCommand_Run(t *testing.T) {
+	src := test.MustRunCluster(t, 1)
+	defer src.Close()
+
+	// The destination is larger and replicated, so each shard is restored
+	// to several nodes which did not hold it before.
+	dst := test.MustNewCluster(t, 3)
+	for _, c := range dst {
+		c.Config.Cluster.ReplicaN = 2
+	}
+	if err := dst.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer dst.Close()
+
+	src.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	src.CreateField(t, "i", pilosa.IndexOptions{}, "v", pilosa.OptFieldTypeInt(0, 1000))
+	src.Query(t, "i", `Set(1, f=1) Set(2, f=1) Set(3000000, f=2) Set(1, v=500)
+		SetRowAttrs(f, 1, name="one", weight=1.5) SetColumnAttrs(3000000, active=true, age=40)`)
+
+	dir, err := ioutil.TempDir("", "pilosa-backup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.tar")
+
+	buf := bytes.Buffer{}
+	stdin, stdout, stderr := GetIO(buf)
+	backup := NewBackupCommand(stdin, stdout, stderr)
+	backup.Host = src[0].API.Node().URI.HostPort()
+	backup.Path = path
+	if err := backup.Run(context.Background()); err != nil {
+		t.Fatalf("backing up: %s", err)
+	}
+
+	restore := NewRestoreCommand(stdin, stdout, stderr)
+	restore.Host = dst[0].API.Node().URI.HostPort()
+	restore.Path = path
+	if err := restore.Run(context.Background()); err != nil {
+		t.Fatalf("restoring: %s", err)
+	}
+
+	// Every replica of each shard has the data: a remote query only reads
+	// the node it is sent to.
+	for shard, row := range map[uint64]int{0: 1, 3000000 / pilosa.ShardWidth: 2} {
+		var owners int
+		for _, c := range dst {
+			if !ownsShard(t, c, "i", shard) {
+				continue
+			}
+			owners++
+			resp, err := c.API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: fmt.Sprintf("Count(Row(f=%d))", row), Shards: []uint64{shard}, Remote: true})
+			if err != nil {
+				t.Fatal(err)
+			} else if n := resp.Results[0].(uint64); n == 0 {
+				t.Fatalf("shard %d missing from node %s", shard, c.API.Node().ID)
+			}
+		}
+		if owners != 2 {
+			t.Fatalf("unexpected number of owners of shard %d: %d", shard, owners)
+		}
+	}
+
+	resp := dst.Query(t, "i", "Count(Row(f=1)) Count(Row(f=2)) Sum(field=v) Row(f=1) Row(f=2)")
+	if n := resp.Results[0].(uint64); n != 2 {
+		t.Fatalf("unexpected count of row 1: %d", n)
+	} else if n := resp.Results[1].(uint64); n != 1 {
+		t.Fatalf("unexpected count of row 2: %d", n)
+	} else if vc := resp.Results[2].(pilosa.ValCount); vc.Val != 500 || vc.Count != 1 {
+		t.Fatalf("unexpected sum: %+v", vc)
+	} else if attrs := resp.Results[3].(*pilosa.Row).Attrs; !reflect.DeepEqual(attrs, map[string]interface{}{"name": "one", "weight": 1.5}) {
+		t.Fatalf("unexpected row attributes: %v", attrs)
+	}
+
+	colResp, err := dst[1].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: "Row(f=2)", ColumnAttrs: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if cols := colResp.ColumnAttrSets; len(cols) != 1 || cols[0].ID != 3000000 || !reflect.DeepEqual(cols[0].Attrs, map[string]interface{}{"active": true, "age": int64(40)}) {
+		t.Fatalf("unexpected column attributes: %+v", cols)
+	}
+}
+
+// ownsShard returns true if the node of c owns shard of index.
+func ownsShard(t *testing.T, c *test.Command, index string, shard uint64) bool {
+	nodes, err := c.API.ShardNodes(context.Background(), index, shard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range nodes {
+		if node.ID == c.API.Node().ID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBackupCommand_Keys(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+	cluster.CreateField(t, "k", pilosa.IndexOptions{Keys: true}, "f")
+	cluster.Query(t, "k", `Set("a", f=1)`)
+
+	buf := bytes.Buffer{}
+	stdin, stdout, stderr := GetIO(buf)
+	cm := NewBackupCommand(stdin, stdout, stderr)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	cm.Path = os.DevNull
+	if err := cm.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected keyed index to be refused, got %v", err)
+	}
+
+	cm.Force = true
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatalf("backing up with --force: %s", err)
+	}
+}
+
+// Ensure a fragment is read from another replica when the first cannot be
+// reached.
+func TestRetrieveReplica(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1)")
+
+	client, err := commandClient(&BackupCommand{Host: cluster[0].API.Node().URI.HostPort(), CmdIO: pilosa.NewCmdIO(nil, ioutil.Discard, ioutil.Discard)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	down, err := pilosa.NewURIFromAddress("localhost:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := []*pilosa.Node{{ID: "down", URI: *down}, cluster[0].API.Node()}
+
+	if data, err := retrieveReplica(context.Background(), client, nodes, "i", "f", "standard", 0); err != nil {
+		t.Fatal(err)
+	} else if len(data) == 0 {
+		t.Fatal("expected fragment data")
+	}
+	if _, err := retrieveReplica(context.Background(), client, nodes[:1], "i", "f", "standard", 0); err == nil {
+		t.Fatal("expected error when no replica can be reached")
+	}
+}
+
+func TestBackupCommand_UnknownIndex(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	buf := bytes.Buffer{}
+	stdin, stdout, stderr := GetIO(buf)
+	cm := NewBackupCommand(stdin, stdout, stderr)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	cm.Indexes = []string{"missing"}
+	if err := cm.Run(context.Background()); err == nil {
+		t.Fatal("expected error for unknown index")
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// Benchmark operations.
+const (
+	BenchSetBit = "setbit"
+	BenchImport = "import"
+	BenchQuery  = "query"
+)
+
+// Key distributions for benchmarks.
+const (
+	DistributionUniform = "uniform"
+	DistributionZipf    = "zipf"
+)
+
+// BenchCommand represents a command for running a synthetic workload against
+// a cluster.
+type BenchCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Op is the operation to run: setbit, import or query.
+	Op string
+
+	// Name of the index & field to use. They are created if they don't
+	// exist.
+	Index string
+	Field string
+
+	// Number of workers sending requests at once.
+	Concurrency int
+
+	// How long to run for.
+	Duration time.Duration
+
+	// Distribution of row and column IDs: uniform or zipf.
+	Distribution string
+
+	// ZipfS is the exponent of the zipf distribution. Must be greater than 1.
+	ZipfS float64
+
+	// Row and column IDs are below these values.
+	MaxRowID    uint64
+	MaxColumnID uint64
+
+	// Number of bits sent by each import request.
+	BatchSize int
+
+	// Seed for the random number generators.
+	Seed int64
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewBenchCommand returns a new instance of BenchCommand.
+func NewBenchCommand(stdin io.Reader, stdout, stderr io.Writer) *BenchCommand {
+	return &BenchCommand{
+		CmdIO:        pilosa.NewCmdIO(stdin, stdout, stderr),
+		Concurrency:  1,
+		Duration:     10 * time.Second,
+		Distribution: DistributionUniform,
+		ZipfS:        1.1,
+		MaxRowID:     1000,
+		MaxColumnID:  pilosa.ShardWidth,
+		BatchSize:    1000,
+	}
+}
+
+// Run executes the benchmark and prints a summary of its results.
+func (cmd *BenchCommand) Run(ctx context.Context) error {
+	// Validate arguments.
+	if cmd.Index == "" {
+		return pilosa.ErrIndexRequired
+	} else if cmd.Field == "" {
+		return pilosa.ErrFieldRequired
+	} else if cmd.Duration <= 0 {
+		return errors.New("duration must be positive")
+	} else if cmd.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	} else if cmd.MaxRowID == 0 || cmd.MaxColumnID == 0 {
+		return errors.New("max row and column IDs must be positive")
+	} else if cmd.Op == BenchImport && cmd.BatchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+	switch cmd.Op {
+	case BenchSetBit, BenchImport, BenchQuery:
+	default:
+		return errors.Errorf("unknown benchmark: %q", cmd.Op)
+	}
+	switch cmd.Distribution {
+	case DistributionUniform:
+	case DistributionZipf:
+		if cmd.ZipfS <= 1 {
+			return errors.New("zipf exponent must be greater than 1")
+		}
+	default:
+		return errors.Errorf("unknown distribution: %q", cmd.Distribution)
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+	if err := client.EnsureIndex(ctx, cmd.Index, pilosa.IndexOptions{}); err != nil {
+		return errors.Wrap(err, "creating index")
+	} else if err := client.EnsureField(ctx, cmd.Index, cmd.Field); err != nil {
+		return errors.Wrap(err, "creating field")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cmd.Duration)
+	defer cancel()
+
+	results := make([]*benchResult, cmd.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		results[i] = &benchResult{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cmd.runWorker(ctx, client, cmd.Seed+int64(i), results[i])
+		}(i)
+	}
+	wg.Wait()
+
+	result := mergeBenchResults(results)
+	result.elapsed = time.Since(start)
+	result.print(cmd.Stdout, cmd.Op)
+	if result.errors > 0 && len(result.latencies) == 0 {
+		return errors.Wrap(result.err, "every request failed")
+	}
+	return nil
+}
+
+// runWorker sends requests one at a time until ctx is done, recording them in
+// result.
+func (cmd *BenchCommand) runWorker(ctx context.Context, client *http.InternalClient, seed int64, result *benchResult) {
+	rnd := rand.New(rand.NewSource(seed))
+	rowID := cmd.newGenerator(rnd, cmd.MaxRowID)
+	columnID := cmd.newGenerator(rnd, cmd.MaxColumnID)
+
+	for ctx.Err() == nil {
+		var err error
+		var bits int
+		start := time.Now()
+		switch cmd.Op {
+		case BenchSetBit:
+			q := fmt.Sprintf("Set(%d, %s=%d)", columnID(), cmd.Field, rowID())
+			_, err = client.Query(ctx, cmd.Index, &pilosa.QueryRequest{Query: q})
+			bits = 1
+		case BenchImport:
+			err = cmd.importBatch(ctx, client, rowID, columnID)
+			bits = cmd.BatchSize
+		case BenchQuery:
+			q := fmt.Sprintf("Count(Row(%s=%d))", cmd.Field, rowID())
+			_, err = client.Query(ctx, cmd.Index, &pilosa.QueryRequest{Query: q})
+		}
+
+		// Requests cut short by the end of the benchmark don't count.
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			result.errors++
+			if result.err == nil {
+				result.err = err
+			}
+			continue
+		}
+		result.latencies = append(result.latencies, time.Since(start))
+		result.bits += bits
+	}
+}
+
+// importBatch imports a batch of random bits, with one request for each
+// shard they fall in.
+func (cmd *BenchCommand) importBatch(ctx context.Context, client *http.InternalClient, rowID, columnID func() uint64) error {
+	byShard := make(map[uint64][]pilosa.Bit)
+	for i := 0; i < cmd.BatchSize; i++ {
+		bit := pilosa.Bit{RowID: rowID(), ColumnID: columnID()}
+		shard := bit.ColumnID / pilosa.ShardWidth
+		byShard[shard] = append(byShard[shard], bit)
+	}
+	for shard, bits := range byShard {
+		if err := client.Import(ctx, cmd.Index, cmd.Field, shard, bits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGenerator returns a function which generates IDs below max in the
+// distribution of cmd.
+func (cmd *BenchCommand) newGenerator(rnd *rand.Rand, max uint64) func() uint64 {
+	if cmd.Distribution == DistributionZipf {
+		return rand.NewZipf(rnd, cmd.ZipfS, 1, max-1).Uint64
+	}
+	return func() uint64 { return uint64(rnd.Int63n(int64(max))) }
+}
+
+// benchResult holds the outcome of the requests sent by a benchmark.
+type benchResult struct {
+	latencies []time.Duration
+	bits      int
+	errors    int
+	err       error // first error
+	elapsed   time.Duration
+}
+
+// mergeBenchResults combines the results of several workers, sorting their
+// latencies.
+func mergeBenchResults(results []*benchResult) *benchResult {
+	merged := &benchResult{}
+	for _, r := range results {
+		merged.latencies = append(merged.latencies, r.latencies...)
+		merged.bits += r.bits
+		merged.errors += r.errors
+		if merged.err == nil {
+			merged.err = r.err
+		}
+	}
+	sort.Slice(merged.latencies, func(i, j int) bool { return merged.latencies[i] < merged.latencies[j] })
+	return merged
+}
+
+// percentile returns the latency below which p percent of requests completed.
+// The latencies must be sorted.
+func (r *benchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(r.latencies) {
+		i = len(r.latencies) - 1
+	}
+	return r.latencies[i]
+}
+
+// print writes a summary of r to w.
+func (r *benchResult) print(w io.Writer, op string) {
+	secs := r.elapsed.Seconds()
+	fmt.Fprintf(w, "requests:    %d in %s (%d errors)\n", len(r.latencies), r.elapsed.Round(time.Millisecond), r.errors)
+	fmt.Fprintf(w, "throughput:  %.1f requests/s\n", float64(len(r.latencies))/secs)
+	if op != BenchQuery {
+		fmt.Fprintf(w, "             %.1f bits/s\n", float64(r.bits)/secs)
+	}
+	fmt.Fprintf(w, "latency:     p50=%s p90=%s p99=%s max=%s\n", r.percentile(50), r.percentile(90), r.percentile(99), r.percentile(100))
+	if r.err != nil {
+		fmt.Fprintf(w, "first error: %s\n", r.err)
+	}
+}
+
+func (cmd *BenchCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *BenchCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *BenchCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
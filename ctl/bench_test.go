@@ -0,0 +1,94 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestBenchCommand_Run(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	for _, op := range []string{BenchSetBit, BenchImport, BenchQuery} {
+		for _, dist := range []string{DistributionUniform, DistributionZipf} {
+			t.Run(op+"-"+dist, func(t *testing.T) {
+				var buf bytes.Buffer
+				cm := NewBenchCommand(strings.NewReader(""), &buf, ioutil.Discard)
+				cm.Host = cluster[0].API.Node().URI.HostPort()
+				cm.Op = op
+				cm.Index, cm.Field = "i", "f"
+				cm.Concurrency = 2
+				cm.Duration = 200 * time.Millisecond
+				cm.Distribution = dist
+				cm.BatchSize = 100
+				if err := cm.Run(context.Background()); err != nil {
+					t.Fatal(err)
+				} else if !strings.Contains(buf.String(), "requests/s") || strings.Contains(buf.String(), "first error") {
+					t.Fatalf("unexpected output:\n%s", buf.String())
+				}
+			})
+		}
+	}
+
+	// The writes landed in the field.
+	resp := cluster.Query(t, "i", "Rows(f)")
+	if rows := resp.Results[0].(pilosa.RowIdentifiers).Rows; len(rows) == 0 {
+		t.Fatal("expected bits to be set")
+	}
+}
+
+func TestBenchCommand_InvalidArgs(t *testing.T) {
+	for _, fn := range []func(*BenchCommand){
+		func(cm *BenchCommand) { cm.Op = "delete" },
+		func(cm *BenchCommand) { cm.Distribution = "normal" },
+		func(cm *BenchCommand) { cm.Distribution, cm.ZipfS = DistributionZipf, 1 },
+		func(cm *BenchCommand) { cm.Concurrency = 0 },
+		func(cm *BenchCommand) { cm.Index = "" },
+	} {
+		cm := NewBenchCommand(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+		cm.Op, cm.Index, cm.Field = BenchQuery, "i", "f"
+		fn(cm)
+		if err := cm.Run(context.Background()); err == nil {
+			t.Fatalf("expected error for %+v", cm)
+		}
+	}
+}
+
+func TestBenchResult_Percentile(t *testing.T) {
+	r := &benchResult{}
+	if p := r.percentile(50); p != 0 {
+		t.Fatalf("expected 0 without requests, got %s", p)
+	}
+	for i := 1; i <= 100; i++ {
+		r.latencies = append(r.latencies, time.Duration(i)*time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p   float64
+		exp time.Duration
+	}{{50, 50 * time.Millisecond}, {99, 99 * time.Millisecond}, {100, 100 * time.Millisecond}, {0, time.Millisecond}} {
+		if p := r.percentile(tt.p); p != tt.exp {
+			t.Fatalf("p%v: expected %s, got %s", tt.p, tt.exp, p)
+		}
+	}
+}
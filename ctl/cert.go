@@ -0,0 +1,251 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pkg/errors"
+)
+
+// Names of the CA files written by CertCommand.
+const (
+	certCAName    = "ca.crt"
+	certCAKeyName = "ca.key"
+)
+
+// CertCommand represents a command for generating a CA and a certificate for
+// each node of a cluster.
+type CertCommand struct {
+	// Hosts are the addresses of the nodes. Each node's certificate is
+	// valid for its host name or IP address.
+	Hosts []string
+
+	// Dir is the directory the certificates and keys are written to.
+	Dir string
+
+	// ValidFor is how long the certificates are valid for.
+	ValidFor time.Duration
+
+	// Standard input/output
+	*pilosa.CmdIO
+}
+
+// NewCertCommand returns a new instance of CertCommand.
+func NewCertCommand(stdin io.Reader, stdout, stderr io.Writer) *CertCommand {
+	return &CertCommand{
+		CmdIO:    pilosa.NewCmdIO(stdin, stdout, stderr),
+		Dir:      ".",
+		ValidFor: 365 * 24 * time.Hour,
+	}
+}
+
+// Run writes a certificate and key for each host, signed by the CA in Dir,
+// and prints the [tls] config section each node should use. The CA is created
+// if Dir has neither its certificate nor its key, and reused otherwise, so
+// that nodes can be added to a cluster later.
+func (cmd *CertCommand) Run(_ context.Context) error {
+	logger := cmd.Logger()
+	if len(cmd.Hosts) == 0 {
+		return errors.New("at least one host required")
+	} else if cmd.ValidFor <= 0 {
+		return errors.New("validity must be positive")
+	}
+
+	dir, err := filepath.Abs(cmd.Dir)
+	if err != nil {
+		return errors.Wrap(err, "resolving directory")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "creating directory")
+	}
+
+	exists, err := caExists(dir)
+	if err != nil {
+		return err
+	}
+	var ca *x509.Certificate
+	var caKey *ecdsa.PrivateKey
+	if exists {
+		ca, caKey, err = cmd.loadCA(dir)
+	} else {
+		logger.Printf("creating CA in %s", dir)
+		ca, caKey, err = cmd.createCA(dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, host := range cmd.Hosts {
+		uri, err := pilosa.NewURIFromAddress(host)
+		if err != nil {
+			return errors.Wrapf(err, "parsing host %s", host)
+		}
+		certPath := filepath.Join(dir, uri.Host+".crt")
+		keyPath := filepath.Join(dir, uri.Host+".key")
+
+		logger.Printf("creating certificate for %s", uri.Host)
+		tmpl, err := cmd.newTemplate(uri.Host)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(uri.Host); ip != nil {
+			tmpl.IPAddresses = []net.IP{ip}
+		} else {
+			tmpl.DNSNames = []string{uri.Host}
+		}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		// Nodes are clients of each other as well as servers.
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		if err := writeCertificate(certPath, keyPath, tmpl, ca, caKey); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.Stdout, "# %s\n", uri.HostPort())
+		fmt.Fprintf(cmd.Stdout, "[tls]\n")
+		fmt.Fprintf(cmd.Stdout, "  certificate = %q\n", certPath)
+		fmt.Fprintf(cmd.Stdout, "  key = %q\n", keyPath)
+		fmt.Fprintf(cmd.Stdout, "  ca-certificate = %q\n", filepath.Join(dir, certCAName))
+		fmt.Fprintf(cmd.Stdout, "  enable-client-verification = true\n\n")
+	}
+	return nil
+}
+
+// caExists returns true if dir has a CA certificate and key, and false if it
+// has neither. Having only one of them is an error, so that a CA whose key
+// is missing is never replaced by a new one.
+func caExists(dir string) (bool, error) {
+	var found, missing []string
+	for _, name := range []string{certCAName, certCAKeyName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			missing = append(missing, name)
+		} else if err != nil {
+			return false, errors.Wrapf(err, "checking %s", name)
+		} else {
+			found = append(found, name)
+		}
+	}
+	if len(found) == 1 {
+		return false, errors.Errorf("found %s but not %s in %s", found[0], missing[0], dir)
+	}
+	return len(found) == 2, nil
+}
+
+// loadCA reads the CA certificate and key from dir.
+func (cmd *CertCommand) loadCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := ioutil.ReadFile(filepath.Join(dir, certCAName))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading CA certificate")
+	}
+	keyPEM, err := ioutil.ReadFile(filepath.Join(dir, certCAKeyName))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading CA key")
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, nil, errors.New("decoding CA certificate")
+	}
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing CA certificate")
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, errors.New("decoding CA key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing CA key")
+	}
+	return ca, key, nil
+}
+
+// createCA writes a new self-signed CA certificate and key to dir.
+func (cmd *CertCommand) createCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	tmpl, err := cmd.newTemplate("Pilosa CA")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	certPath, keyPath := filepath.Join(dir, certCAName), filepath.Join(dir, certCAKeyName)
+	if err := writeCertificate(certPath, keyPath, tmpl, nil, nil); err != nil {
+		return nil, nil, err
+	}
+	return cmd.loadCA(dir)
+}
+
+// newTemplate returns a certificate template named name, valid from now for
+// cmd.ValidFor.
+func (cmd *CertCommand) newTemplate(name string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "generating serial number")
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"Pilosa"}, CommonName: name},
+		NotBefore:    now.Add(-time.Hour), // allow for clock skew
+		NotAfter:     now.Add(cmd.ValidFor),
+	}, nil
+}
+
+// writeCertificate generates a key and writes it, with a certificate made from
+// tmpl and signed by parent, to PEM files. The certificate is self-signed if
+// parent is nil.
+func writeCertificate(certPath, keyPath string, tmpl, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "generating key")
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return errors.Wrap(err, "creating certificate")
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return errors.Wrap(err, "marshaling key")
+	}
+
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return errors.Wrap(err, "writing key")
+	}
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return errors.Wrap(err, "writing certificate")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCertCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pilosa-cert-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := NewCertCommand(nil, &stdout, ioutil.Discard)
+	cmd.Dir = dir
+	cmd.Hosts = []string{"localhost:10101", "https://10.0.0.2:10101"}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(stdout.String(), "[tls]"); got != 2 {
+		t.Fatalf("expected 2 tls sections, got %d: %s", got, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), filepath.Join(dir, "localhost.crt")) {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	caPEM, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		t.Fatal("parsing CA certificate")
+	}
+
+	// Each node's certificate must be signed by the CA, for its host.
+	for _, host := range []string{"localhost", "10.0.0.2"} {
+		pair, err := tls.LoadX509KeyPair(filepath.Join(dir, host+".crt"), filepath.Join(dir, host+".key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{
+			DNSName:   host,
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}); err != nil {
+			t.Fatalf("verifying %s: %v", host, err)
+		}
+	}
+
+	// Adding a node reuses the CA.
+	cmd = NewCertCommand(nil, ioutil.Discard, ioutil.Discard)
+	cmd.Dir = dir
+	cmd.Hosts = []string{"node3:10101"}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt")); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, caPEM) {
+		t.Fatal("expected CA to be reused")
+	}
+
+	// A CA certificate without its key is an error, and is left in place.
+	if err := os.Remove(filepath.Join(dir, "ca.key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "found ca.crt but not ca.key") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt")); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, caPEM) {
+		t.Fatal("expected CA certificate to be kept")
+	} else if _, err := os.Stat(filepath.Join(dir, "ca.key")); !os.IsNotExist(err) {
+		t.Fatalf("expected no CA key, got %v", err)
+	}
+}
@@ -46,6 +46,14 @@ func NewCheckCommand(stdin io.Reader, stdout, stderr io.Writer) *CheckCommand {
 // Run executes the check command.
 func (cmd *CheckCommand) Run(_ context.Context) error {
 	for _, path := range cmd.Paths {
+		fi, err := os.Stat(path)
+		if err == nil && fi.IsDir() {
+			if err := cmd.checkDir(path); err != nil {
+				return errors.Wrap(err, "checking directory")
+			}
+			continue
+		}
+
 		switch filepath.Ext(path) {
 		case "":
 			if err := cmd.checkBitmapFile(path); err != nil {
@@ -67,6 +75,26 @@ func (cmd *CheckCommand) Run(_ context.Context) error {
 	return nil
 }
 
+// checkDir performs a consistency check on every fragment file under the
+// data directory path. A fragment which cannot be read is reported and the
+// scan continues with the next one.
+func (cmd *CheckCommand) checkDir(path string) error {
+	return filepath.Walk(path, func(fpath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Fragment files are stored without an extension in a "fragments"
+		// directory, next to their .cache files.
+		if !fi.Mode().IsRegular() || filepath.Ext(fpath) != "" || filepath.Base(filepath.Dir(fpath)) != "fragments" {
+			return nil
+		}
+		if err := cmd.checkBitmapFile(fpath); err != nil {
+			fmt.Fprintf(cmd.Stdout, "%s: %s\n", fpath, err)
+		}
+		return nil
+	})
+}
+
 // checkBitmapFile performs a consistency check on path for a roaring bitmap file.
 func (cmd *CheckCommand) checkBitmapFile(path string) (err error) {
 	// Open file handle.
@@ -114,6 +142,7 @@ func (cmd *CheckCommand) checkBitmapFile(path string) (err error) {
 		default:
 			fmt.Fprintf(cmd.Stdout, "%s: %s\n", path, err.Error())
 		}
+		return nil
 	}
 
 	// Print success message if no errors were found.
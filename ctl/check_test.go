@@ -26,6 +26,8 @@ import (
 	"testing"
 
 	"context"
+
+	"github.com/pilosa/pilosa/v2/roaring"
 )
 
 func TestCheckCommand_RunCacheFile(t *testing.T) {
@@ -100,6 +102,49 @@ func TestCheckCommand_Run(t *testing.T) {
 	//	Todo: need correct roaring file for happy path
 }
 
+func TestCheckCommand_RunDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fragDir := filepath.Join(dir, "i", "f", "views", "standard", "fragments")
+	if err := os.MkdirAll(fragDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	var data bytes.Buffer
+	if _, err := roaring.NewBitmap(1, 2, 3).WriteTo(&data); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{
+		filepath.Join(fragDir, "0"):       data.Bytes(),
+		filepath.Join(fragDir, "1"):       []byte("1234,1223"),
+		filepath.Join(fragDir, "0.cache"): []byte("1234,1223"),
+		filepath.Join(dir, "i", ".meta"):  []byte("1234,1223"),
+	} {
+		if err := ioutil.WriteFile(name, content, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	cm := NewCheckCommand(bytes.NewReader(nil), &buf, &buf)
+	cm.Paths = []string{dir}
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, filepath.Join(fragDir, "0")+": ok") {
+		t.Fatalf("expected valid fragment to be ok: %s", out)
+	} else if !strings.Contains(out, filepath.Join(fragDir, "1")+": unmarshalling") {
+		t.Fatalf("expected corrupt fragment to be reported: %s", out)
+	} else if strings.Contains(out, ".cache") || strings.Contains(out, ".meta") {
+		t.Fatalf("expected only fragment files to be checked: %s", out)
+	}
+}
+
 // TempFileName generates a temporary filename with extension
 func TempFileName(prefix, suffix string) string {
 	randBytes := make([]byte, 16)
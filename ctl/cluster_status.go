@@ -0,0 +1,178 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// ClusterStatusCommand represents a command for inspecting the nodes of a
+// cluster.
+type ClusterStatusCommand struct {
+	// Remote host and port.
+	Host string
+
+	// JSON prints the status as JSON instead of a table.
+	JSON bool
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// ClusterStatus is the status of a cluster, as printed by
+// ClusterStatusCommand.
+type ClusterStatus struct {
+	State string               `json:"state"`
+	Nodes []*ClusterNodeStatus `json:"nodes"`
+}
+
+// ClusterNodeStatus is the status of a single node of a cluster.
+type ClusterNodeStatus struct {
+	*pilosa.Node
+
+	// Ready is true if the node is ready to serve queries. Otherwise, Error
+	// says why not.
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+
+	// Shards is the number of shards of each index of which the node holds
+	// data. It is nil, and StatusError says why, if the node could not be
+	// asked.
+	Shards      map[string]int `json:"shards"`
+	StatusError string         `json:"statusError,omitempty"`
+
+	// TranslateLag is the number of key translations the node is missing,
+	// summed over its translation stores, compared to the node furthest
+	// ahead for each store. Replicas copy translations from the primary in
+	// the background, so a growing lag means replication is falling behind.
+	TranslateLag uint64 `json:"translateLag"`
+}
+
+// NewClusterStatusCommand returns a new instance of ClusterStatusCommand.
+func NewClusterStatusCommand(stdin io.Reader, stdout, stderr io.Writer) *ClusterStatusCommand {
+	return &ClusterStatusCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// Run executes the command.
+func (cmd *ClusterStatusCommand) Run(ctx context.Context) error {
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	state, nodes, err := client.Status(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "getting status")
+	}
+	// Ask each node which shards and translations it holds.
+	status := &ClusterStatus{State: state}
+	offsets := make(map[*ClusterNodeStatus]pilosa.TranslateOffsetMap, len(nodes))
+	maxOffsets := make(pilosa.TranslateOffsetMap)
+	for _, node := range nodes {
+		ns := &ClusterNodeStatus{Node: node}
+		status.Nodes = append(status.Nodes, ns)
+		if err := client.Ready(ctx, &node.URI); err != nil {
+			ns.Error = err.Error()
+		} else {
+			ns.Ready = true
+		}
+
+		local, err := client.LocalStatus(ctx, &node.URI)
+		if err != nil {
+			ns.StatusError = err.Error()
+			continue
+		}
+		ns.Shards = make(map[string]int, len(local.Shards))
+		for index, shards := range local.Shards {
+			ns.Shards[index] = len(shards)
+		}
+		offsets[ns] = local.TranslateOffsets
+		for index, m := range local.TranslateOffsets {
+			for field, offset := range m {
+				if offset > maxOffsets.FieldOffset(index, field) {
+					maxOffsets.SetFieldOffset(index, field, offset)
+				}
+			}
+		}
+	}
+
+	// Compare each node's translation stores with the furthest ahead.
+	for ns, m := range offsets {
+		for index, max := range maxOffsets {
+			for field, offset := range max {
+				ns.TranslateLag += offset - m.FieldOffset(index, field)
+			}
+		}
+	}
+
+	if cmd.JSON {
+		enc := json.NewEncoder(cmd.Stdout)
+		enc.SetIndent("", "\t")
+		return errors.Wrap(enc.Encode(status), "encoding status")
+	}
+	return cmd.printTable(status)
+}
+
+// printTable prints status as a table, with one row per node.
+func (cmd *ClusterStatusCommand) printTable(status *ClusterStatus) error {
+	fmt.Fprintf(cmd.Stdout, "State: %s\n\n", status.State)
+
+	tw := tabwriter.NewWriter(cmd.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tURI\tCOORDINATOR\tSTATE\tREADY\tSHARDS\tTRANSLATE LAG")
+	for _, ns := range status.Nodes {
+		ready := "yes"
+		if !ns.Ready {
+			ready = "no: " + ns.Error
+		}
+		shards, lag := "?", "?"
+		if ns.Shards != nil {
+			var n int
+			for _, x := range ns.Shards {
+				n += x
+			}
+			shards, lag = fmt.Sprint(n), fmt.Sprint(ns.TranslateLag)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\t%s\t%s\t%s\n", ns.ID, ns.URI, ns.IsCoordinator, ns.State, ready, shards, lag)
+	}
+	return errors.Wrap(tw.Flush(), "flushing")
+}
+
+func (cmd *ClusterStatusCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *ClusterStatusCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *ClusterStatusCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestClusterStatusCommand_Run(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1) Set(3000000, f=1)")
+
+	var buf bytes.Buffer
+	cm := NewClusterStatusCommand(strings.NewReader(""), &buf, ioutil.Discard)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	cm.JSON = true
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var status ClusterStatus
+	if err := json.Unmarshal(buf.Bytes(), &status); err != nil {
+		t.Fatalf("decoding status: %s", err)
+	} else if status.State != pilosa.ClusterStateNormal {
+		t.Fatalf("unexpected state: %s", status.State)
+	} else if len(status.Nodes) != 1 {
+		t.Fatalf("unexpected nodes: %+v", status.Nodes)
+	}
+	// Columns 1 and 3000000 are in shards 0 and 2, so the node holds data of
+	// two shards.
+	if ns := status.Nodes[0]; ns.ID != cluster[0].API.Node().ID || !ns.Ready || ns.Shards["i"] != 2 || ns.TranslateLag != 0 {
+		t.Fatalf("unexpected node status: %+v", ns)
+	}
+
+	// The table has a row for the node.
+	buf.Reset()
+	cm.JSON = false
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), cluster[0].API.Node().ID) {
+		t.Fatalf("expected node in table:\n%s", buf.String())
+	}
+}
+
+// Ensure the command authenticates with its API key.
+func TestClusterStatusCommand_APIKey(t *testing.T) {
+	cluster := test.MustNewCluster(t, 1)
+	cluster[0].Config.Auth.Enable = true
+	cluster[0].Config.Auth.NodeKey = "node-secret"
+	cluster[0].Config.Auth.APIKeys = []string{"secret=admin"}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	cm := NewClusterStatusCommand(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	if err := cm.Run(context.Background()); err == nil {
+		t.Fatal("expected error without API key")
+	}
+	cm.APIKey = "secret"
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 type CommandWithTLSSupport interface {
 	TLSHost() string
 	TLSConfiguration() server.TLSConfig
+	AuthAPIKey() string
 	Logger() *log.Logger
 }
 
@@ -39,6 +40,12 @@ func SetTLSConfig(flags *pflag.FlagSet, certificatePath *string, certificateKeyP
 	flags.BoolVarP(enableClientVerification, "tls.enable-client-verification", "", false, "Enable TLS certificate client verification for incoming connections")
 }
 
+// SetAPIKey creates the common flag for the API key a command authenticates
+// with.
+func SetAPIKey(flags *pflag.FlagSet, apiKey *string) {
+	flags.StringVarP(apiKey, "api-key", "", "", "API key sent to servers which require authentication")
+}
+
 // commandClient returns a pilosa.InternalHTTPClient for the command
 func commandClient(cmd CommandWithTLSSupport) (*http.InternalClient, error) {
 	tls := cmd.TLSConfiguration()
@@ -46,7 +53,11 @@ func commandClient(cmd CommandWithTLSSupport) (*http.InternalClient, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "getting tls config")
 	}
-	client, err := http.NewInternalClient(cmd.TLSHost(), http.GetHTTPClient(tlsConfig))
+	httpClient := http.GetHTTPClient(tlsConfig)
+	if key := cmd.AuthAPIKey(); key != "" {
+		httpClient = http.WithAPIKey(httpClient, key)
+	}
+	client, err := http.NewInternalClient(cmd.TLSHost(), httpClient)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting internal client")
 	}
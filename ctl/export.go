@@ -15,9 +15,12 @@
 package ctl
 
 import (
+	"archive/tar"
 	"context"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 
 	"github.com/pilosa/pilosa/v2"
 	"github.com/pilosa/pilosa/v2/server"
@@ -33,13 +36,27 @@ type ExportCommand struct {
 	Index string
 	Field string
 
-	// Filename to export to.
+	// Filename to export to. For the roaring format, a directory.
 	Path string
 
+	// Format of the export, "csv" or "roaring". Defaults to "csv".
+	Format string
+
+	// Shards limits the export to the given shards. Every shard is exported
+	// if it is empty.
+	Shards []uint64
+
+	// Views limits a roaring export to the given views. Every view is
+	// exported if it is empty. CSV exports only read the standard view.
+	Views []string
+
 	// Standard input/output
 	*pilosa.CmdIO
 
 	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
 }
 
 // NewExportCommand returns a new instance of ExportCommand.
@@ -60,6 +77,17 @@ func (cmd *ExportCommand) Run(ctx context.Context) error {
 		return pilosa.ErrFieldRequired
 	}
 
+	switch cmd.Format {
+	case "", "csv":
+		if len(cmd.Views) > 0 {
+			return errors.New("views can only be chosen for roaring exports")
+		}
+	case "roaring":
+		return cmd.runRoaring(ctx)
+	default:
+		return errors.Errorf("unknown export format: %q", cmd.Format)
+	}
+
 	// Use output file, if specified.
 	// Otherwise use STDOUT.
 	var w io.Writer = cmd.Stdout
@@ -86,7 +114,7 @@ func (cmd *ExportCommand) Run(ctx context.Context) error {
 	}
 
 	// Export each shard.
-	for shard := uint64(0); shard <= maxShards[cmd.Index]; shard++ {
+	for _, shard := range cmd.exportShards(maxShards[cmd.Index]) {
 		logger.Printf("exporting shard: %d", shard)
 		if err := client.ExportCSV(ctx, cmd.Index, cmd.Field, shard, w); err != nil {
 			return errors.Wrap(err, "exporting")
@@ -103,6 +131,145 @@ func (cmd *ExportCommand) Run(ctx context.Context) error {
 	return nil
 }
 
+// runRoaring exports each fragment of the field, in every view or those
+// given with Views, to a file named VIEW/SHARD under the output directory. Fragments are copied as they
+// are read from the nodes, in Pilosa's roaring format.
+func (cmd *ExportCommand) runRoaring(ctx context.Context) error {
+	logger := cmd.Logger()
+
+	if cmd.Path == "" {
+		return errors.New("output directory required for roaring export")
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	// Find the views of the field.
+	schema, err := client.SchemaWithViews(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting schema")
+	}
+	var index *pilosa.IndexInfo
+	for _, ii := range schema {
+		if ii.Name == cmd.Index {
+			index = ii
+		}
+	}
+	if index == nil {
+		return errors.Wrap(pilosa.ErrIndexNotFound, cmd.Index)
+	}
+	var field *pilosa.FieldInfo
+	for _, fi := range index.Fields {
+		if fi.Name == cmd.Field {
+			field = fi
+		}
+	}
+	if field == nil {
+		return errors.Wrap(pilosa.ErrFieldNotFound, cmd.Field)
+	}
+	views := field.Views
+	if len(cmd.Views) > 0 {
+		byName := make(map[string]*pilosa.ViewInfo, len(views))
+		for _, vi := range views {
+			byName[vi.Name] = vi
+		}
+		views = views[:0:0]
+		for _, name := range cmd.Views {
+			vi := byName[name]
+			if vi == nil {
+				return errors.Errorf("field %s has no view %q", cmd.Field, name)
+			}
+			views = append(views, vi)
+		}
+	}
+	for _, vi := range views {
+		if err := os.MkdirAll(filepath.Join(cmd.Path, vi.Name), 0750); err != nil {
+			return errors.Wrap(err, "creating directory")
+		}
+	}
+
+	// Determine shard count.
+	maxShards, err := client.MaxShardByIndex(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting shard count")
+	}
+
+	// Export each shard.
+	for _, shard := range cmd.exportShards(maxShards[cmd.Index]) {
+		nodes, err := client.FragmentNodes(ctx, cmd.Index, shard)
+		if err != nil {
+			return errors.Wrap(err, "getting fragment nodes")
+		} else if len(nodes) == 0 {
+			return errors.Errorf("no nodes own shard %d", shard)
+		}
+
+		logger.Printf("exporting shard: %d", shard)
+		for _, vi := range views {
+			rc, err := client.RetrieveShardFromURI(ctx, cmd.Index, cmd.Field, vi.Name, shard, nodes[0].URI)
+			if err == pilosa.ErrFragmentNotFound {
+				continue
+			} else if err != nil {
+				return errors.Wrap(err, "retrieving fragment")
+			}
+			err = writeFragmentData(filepath.Join(cmd.Path, vi.Name, strconv.FormatUint(shard, 10)), rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportShards returns the shards to export, up to and including max:
+// those given with Shards, or all of them.
+func (cmd *ExportCommand) exportShards(max uint64) []uint64 {
+	var shards []uint64
+	if len(cmd.Shards) == 0 {
+		for shard := uint64(0); shard <= max; shard++ {
+			shards = append(shards, shard)
+		}
+		return shards
+	}
+	for _, shard := range cmd.Shards {
+		if shard <= max {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// writeFragmentData copies the roaring data out of a fragment archive, as
+// sent by a node, to a new file at path.
+func writeFragmentData(path string, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return errors.New("fragment archive has no data")
+		} else if err != nil {
+			return errors.Wrap(err, "reading fragment archive")
+		} else if hdr.Name == "data" {
+			break
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "creating file")
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, tr); err != nil {
+		return errors.Wrap(err, "copying")
+	}
+	return errors.Wrap(f.Close(), "closing")
+}
+
 func (cmd *ExportCommand) TLSHost() string {
 	return cmd.Host
 }
@@ -110,3 +277,7 @@ func (cmd *ExportCommand) TLSHost() string {
 func (cmd *ExportCommand) TLSConfiguration() server.TLSConfig {
 	return cmd.TLS
 }
+
+func (cmd *ExportCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
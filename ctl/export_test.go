@@ -17,12 +17,18 @@ package ctl
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/roaring"
 	"github.com/pilosa/pilosa/v2/test"
+	"github.com/pkg/errors"
 )
 
 func TestExportCommand_Validation(t *testing.T) {
@@ -71,3 +77,84 @@ func TestExportCommand_Run(t *testing.T) {
 		t.Fatalf("Export Run doesn't work: %s", err)
 	}
 }
+
+func TestExportCommand_RunRoaring(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1) Set(2, f=1) Set(3000000, f=2)")
+
+	dir, err := ioutil.TempDir("", "pilosa-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buf := bytes.Buffer{}
+	stdin, stdout, stderr := GetIO(buf)
+	cm := NewExportCommand(stdin, stdout, stderr)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	cm.Index = "i"
+	cm.Field = "f"
+	cm.Format = "roaring"
+	cm.Path = dir
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatalf("Export Run doesn't work: %s", err)
+	}
+
+	// Column 3000000 is in shard 2, and shard 1 is empty.
+	for shard, exp := range map[int]uint64{0: 2, 2: 1} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "standard", strconv.Itoa(shard)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bm := roaring.NewBitmap()
+		if err := bm.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		} else if n := bm.Count(); n != exp {
+			t.Fatalf("shard %d: expected %d bits, got %d", shard, exp, n)
+		}
+	}
+
+	// Only the chosen shards and views are exported.
+	dir2, err := ioutil.TempDir("", "pilosa-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+	cm.Path = dir2
+	cm.Shards = []uint64{2, 5}
+	cm.Views = []string{"standard"}
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatalf("Export Run doesn't work: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir2, "standard", "2")); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(filepath.Join(dir2, "standard", "0")); !os.IsNotExist(err) {
+		t.Fatalf("expected shard 0 to be left out, got %v", err)
+	}
+	cm.Views = []string{"nope"}
+	if err := cm.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "no view") {
+		t.Fatalf("expected unknown view error, got %v", err)
+	}
+	cm.Format = "csv"
+	if err := cm.Run(context.Background()); err == nil {
+		t.Fatal("expected error choosing views for a CSV export")
+	}
+	cm.Format, cm.Shards, cm.Views = "roaring", nil, nil
+
+	cm.Field = "g"
+	if err := cm.Run(context.Background()); errors.Cause(err) != pilosa.ErrFieldNotFound {
+		t.Fatalf("expected field not found error, got %v", err)
+	}
+	cm.Index = "j"
+	if err := cm.Run(context.Background()); errors.Cause(err) != pilosa.ErrIndexNotFound {
+		t.Fatalf("expected index not found error, got %v", err)
+	}
+
+	cm.Format = "parquet"
+	if err := cm.Run(context.Background()); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
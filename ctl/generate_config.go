@@ -18,15 +18,89 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml"
 	"github.com/pilosa/pilosa/v2"
 	"github.com/pilosa/pilosa/v2/server"
+	ptoml "github.com/pilosa/pilosa/v2/toml"
 	"github.com/pkg/errors"
 )
 
+// Config presets, for GenerateConfigCommand.
+const (
+	PresetSingleNode   = "single-node"
+	PresetSmallCluster = "small-cluster"
+	PresetLargeCluster = "large-cluster"
+	PresetDev          = "dev"
+)
+
+// configPresets adjust the default config for common kinds of deployment.
+var configPresets = map[string]func(c *server.Config){
+	// A single server, without gossip or replication. The mmap limit stays
+	// below the usual vm.max_map_count of 65530.
+	PresetSingleNode: func(c *server.Config) {
+		c.Cluster.Disabled = true
+		c.Cluster.ReplicaN = 1
+		c.AntiEntropy.Interval = 0
+		c.MaxMapCount = 60000
+		c.MaxFileCount = 60000
+	},
+
+	// A few nodes, with every shard on two of them.
+	PresetSmallCluster: func(c *server.Config) {
+		c.Cluster.ReplicaN = 2
+		c.AntiEntropy.Interval = ptoml.Duration(10 * time.Minute)
+		c.MaxMapCount = 60000
+		c.MaxFileCount = 60000
+	},
+
+	// Many nodes with raised system limits, and every shard on three of
+	// them. Anti-entropy runs less often since each pass moves more data, and
+	// gossip is more tolerant of slow nodes.
+	PresetLargeCluster: func(c *server.Config) {
+		c.Cluster.ReplicaN = 3
+		c.AntiEntropy.Interval = ptoml.Duration(30 * time.Minute)
+		c.MaxMapCount = 250000
+		c.MaxFileCount = 250000
+		c.Gossip.PushPullInterval = ptoml.Duration(time.Minute)
+		c.Gossip.ToTheDeadTime = ptoml.Duration(time.Minute)
+		c.Gossip.Nodes = 5
+	},
+
+	// A single server on localhost, with small limits and verbose logging.
+	PresetDev: func(c *server.Config) {
+		c.Bind = "localhost:10101"
+		c.Verbose = true
+		c.Cluster.Disabled = true
+		c.Cluster.ReplicaN = 1
+		c.Cluster.LongQueryTime = ptoml.Duration(10 * time.Second)
+		c.AntiEntropy.Interval = 0
+		c.MaxMapCount = 10000
+		c.MaxFileCount = 10000
+		c.Metric.Diagnostics = false
+	},
+}
+
+// ConfigPresets returns the names of the presets accepted by
+// GenerateConfigCommand, in order.
+func ConfigPresets() []string {
+	names := make([]string, 0, len(configPresets))
+	for name := range configPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GenerateConfigCommand represents a command for printing a default config.
 type GenerateConfigCommand struct {
+	// Preset is the name of a preset to apply to the default config. The
+	// plain defaults are printed if it is empty.
+	Preset string
+
 	*pilosa.CmdIO
 }
 
@@ -37,9 +111,16 @@ func NewGenerateConfigCommand(stdin io.Reader, stdout, stderr io.Writer) *Genera
 	}
 }
 
-// Run prints out the default config.
+// Run prints out the default config, adjusted by the preset if there is one.
 func (cmd *GenerateConfigCommand) Run(_ context.Context) error {
 	conf := server.NewConfig()
+	if cmd.Preset != "" {
+		preset, ok := configPresets[cmd.Preset]
+		if !ok {
+			return errors.Errorf("unknown preset: %q, choose from [%s]", cmd.Preset, strings.Join(ConfigPresets(), ", "))
+		}
+		preset(conf)
+	}
 	ret, err := toml.Marshal(*conf)
 	if err != nil {
 		return errors.Wrap(err, "unmarshalling default config")
@@ -18,9 +18,12 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/pilosa/pilosa/v2/server"
 )
 
 func TestGenerateConfigCommand_Run(t *testing.T) {
@@ -40,5 +43,37 @@ func TestGenerateConfigCommand_Run(t *testing.T) {
 	}
 	if !strings.Contains(buf.String(), ":10101") {
 		t.Fatalf("Unexpected config: %s", buf.String())
+	} else if strings.Contains(buf.String(), "worker-pool-size") {
+		t.Fatalf("expected worker pool sizes to be left out: %s", buf.String())
+	}
+}
+
+func TestGenerateConfigCommand_Run_Preset(t *testing.T) {
+	var buf bytes.Buffer
+	cm := NewGenerateConfigCommand(nil, &buf, os.Stderr)
+	cm.Preset = PresetLargeCluster
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"replicas = 3", `interval = "30m0s"`, "max-map-count = 250000"} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Fatalf("expected %q in config: %s", exp, buf.String())
+		}
+	}
+
+	cm = NewGenerateConfigCommand(nil, ioutil.Discard, os.Stderr)
+	cm.Preset = "huge"
+	if err := cm.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown preset") {
+		t.Fatalf("expected unknown preset error, got %v", err)
+	}
+}
+
+func TestConfigPresets_Valid(t *testing.T) {
+	for _, name := range ConfigPresets() {
+		conf := server.NewConfig()
+		configPresets[name](conf)
+		if err := conf.Validate(); err != nil {
+			t.Fatalf("preset %s: %v", name, err)
+		}
 	}
 }
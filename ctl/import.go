@@ -68,6 +68,9 @@ type ImportCommand struct { // nolint: maligned
 	*pilosa.CmdIO
 
 	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
 }
 
 // NewImportCommand returns a new instance of ImportCommand.
@@ -397,3 +400,7 @@ func (cmd *ImportCommand) TLSHost() string {
 func (cmd *ImportCommand) TLSConfiguration() server.TLSConfig {
 	return cmd.TLS
 }
+
+func (cmd *ImportCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
@@ -16,8 +16,10 @@ package ctl
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math/bits"
 	"os"
 	"syscall"
 	"text/tabwriter"
@@ -70,11 +72,26 @@ func (cmd *InspectCommand) Run(_ context.Context) error {
 			fmt.Fprintf(cmd.Stderr, "inspect command: munmap failed: %v", err)
 		}
 	}()
-	// Attach the mmap file to the bitmap.
+	// Print the file header, which Pilosa roaring files start with.
+	fmt.Fprintf(cmd.Stdout, "== Header ==\n")
+	if len(data) >= 4 && uint32(binary.LittleEndian.Uint16(data[0:2])) == roaring.MagicNumber {
+		fmt.Fprintf(cmd.Stdout, "Format: Pilosa roaring\n")
+		fmt.Fprintf(cmd.Stdout, "Storage version: %d\n", data[2])
+		fmt.Fprintf(cmd.Stdout, "Flags: 0x%02x\n", data[3])
+	} else {
+		fmt.Fprintf(cmd.Stdout, "Format: standard roaring or unknown\n")
+	}
+	fmt.Fprintf(cmd.Stdout, "Size: %d bytes\n", len(data))
+	fmt.Fprintln(cmd.Stdout, "")
+
+	// Attach the mmap file to the bitmap. A corrupt op log still leaves the
+	// containers, and the ops before the bad one, to inspect.
 	t := time.Now()
 	fmt.Fprintf(cmd.Stderr, "unmarshalling bitmap...")
 	bm := roaring.NewBitmap()
-	if err := bm.UnmarshalBinary(data); err != nil {
+	err = bm.UnmarshalBinary(data)
+	opLogErr, _ := errors.Cause(err).(*roaring.OpLogError)
+	if err != nil && opLogErr == nil {
 		return errors.Wrap(err, "unmarshalling")
 	}
 	fmt.Fprintf(cmd.Stderr, " (%s)\n", time.Since(t))
@@ -89,8 +106,45 @@ func (cmd *InspectCommand) Run(_ context.Context) error {
 	fmt.Fprintf(cmd.Stdout, "== Bitmap Info ==\n")
 	fmt.Fprintf(cmd.Stdout, "Containers: %d\n", len(info.Containers))
 	fmt.Fprintf(cmd.Stdout, "Operations: %d\n", info.OpN)
+	if opLogErr != nil {
+		fmt.Fprintf(cmd.Stdout, "Op log: CORRUPT: %s\n", opLogErr)
+	} else {
+		fmt.Fprintf(cmd.Stdout, "Op log: %d ops, checksums ok\n", info.Ops)
+	}
+	if err := bm.Check(); err != nil {
+		fmt.Fprintf(cmd.Stdout, "Consistency: %s\n", err)
+	} else {
+		fmt.Fprintf(cmd.Stdout, "Consistency: ok\n")
+	}
 	fmt.Fprintln(cmd.Stdout, "")
 
+	// Summarize containers by type, and bits by row. Each row of a fragment
+	// spans ShardWidth bits, or containersPerRow containers.
+	const containersPerRow = pilosa.ShardWidth >> 16
+	types := []string{"array", "bitmap", "run"}
+	stats := make(map[string]*containerTypeStats)
+	for _, typ := range types {
+		stats[typ] = &containerTypeStats{}
+	}
+	rows := make(map[uint64]int64)
+	for _, ci := range info.Containers {
+		st, ok := stats[ci.Type]
+		if !ok {
+			st = &containerTypeStats{}
+			stats[ci.Type] = st
+			types = append(types, ci.Type)
+		}
+		st.count++
+		st.n += int64(ci.N)
+		st.alloc += ci.Alloc
+
+		if ci.N > 0 {
+			rows[ci.Key/containersPerRow] += int64(ci.N)
+		}
+	}
+	cmd.printContainerTypes(types, stats)
+	cmd.printRows(rows)
+
 	// Print info for each container.
 	fmt.Fprintln(cmd.Stdout, "== Containers ==")
 	tw := tabwriter.NewWriter(cmd.Stdout, 0, 8, 0, '\t', 0)
@@ -108,3 +162,63 @@ func (cmd *InspectCommand) Run(_ context.Context) error {
 
 	return nil
 }
+
+// containerTypeStats holds totals for the containers of one type.
+type containerTypeStats struct {
+	count int
+	n     int64
+	alloc int
+}
+
+// printContainerTypes prints the number of containers of each type, with the
+// bits they hold and the memory they use.
+func (cmd *InspectCommand) printContainerTypes(types []string, stats map[string]*containerTypeStats) {
+	fmt.Fprintln(cmd.Stdout, "== Container Types ==")
+	tw := tabwriter.NewWriter(cmd.Stdout, 0, 8, 0, '\t', 0)
+	fmt.Fprintf(tw, "%s\t% 8s \t% 10s \t% 10s\n", "TYPE", "COUNT", "N", "ALLOC")
+	for _, typ := range types {
+		st := stats[typ]
+		fmt.Fprintf(tw, "%s\t% 8d \t% 10d \t% 10d\n", typ, st.count, st.n, st.alloc)
+	}
+	tw.Flush()
+	fmt.Fprintln(cmd.Stdout, "")
+}
+
+// printRows prints how many bits the rows of the fragment hold, as a histogram
+// with power of two buckets. rows maps row IDs to their bit counts.
+func (cmd *InspectCommand) printRows(rows map[uint64]int64) {
+	fmt.Fprintln(cmd.Stdout, "== Rows ==")
+	if len(rows) == 0 {
+		fmt.Fprintf(cmd.Stdout, "Rows: 0\n\n")
+		return
+	}
+	var total, min, max int64
+	var buckets []int
+	for _, n := range rows {
+		total += n
+		if min == 0 || n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+		b := bits.Len64(uint64(n)) - 1
+		for len(buckets) <= b {
+			buckets = append(buckets, 0)
+		}
+		buckets[b]++
+	}
+	fmt.Fprintf(cmd.Stdout, "Rows: %d\n", len(rows))
+	fmt.Fprintf(cmd.Stdout, "Bits per row: min=%d max=%d mean=%.1f\n", min, max, float64(total)/float64(len(rows)))
+
+	tw := tabwriter.NewWriter(cmd.Stdout, 0, 8, 0, '\t', 0)
+	fmt.Fprintf(tw, "%s\t% 8s\n", "BITS", "ROWS")
+	for b, count := range buckets {
+		if count == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%d-%d\t% 8d\n", uint64(1)<<uint(b), uint64(1)<<uint(b+1)-1, count)
+	}
+	tw.Flush()
+	fmt.Fprintln(cmd.Stdout, "")
+}
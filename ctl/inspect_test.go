@@ -22,6 +22,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/roaring"
 )
 
 func TestInspectCommand_Run(t *testing.T) {
@@ -55,6 +58,72 @@ func TestInspectCommand_Run(t *testing.T) {
 	if !strings.Contains(buf.String(), "unmarshalling bitmap...") {
 		t.Fatalf("Inspect doesn't work: %s", err)
 	}
+}
+
+func TestInspectCommand_Run_Stats(t *testing.T) {
+	// Row 0 ends up with 4 bits and row 1 with 1, the last 2 from the op log.
+	bm := roaring.NewBitmap(1, 2, 3)
+	var buf bytes.Buffer
+	if _, err := bm.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bm.OpWriter = &buf
+	if _, err := bm.Add(pilosa.ShardWidth + 1); err != nil {
+		t.Fatal(err)
+	} else if _, err := bm.Add(4); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		out, err := runInspect(t, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, exp := range []string{
+			"== Header ==",
+			"Format: Pilosa roaring",
+			"Op log: 2 ops, checksums ok",
+			"Consistency: ok",
+			"== Container Types ==",
+			"Rows: 2",
+			"Bits per row: min=1 max=4 mean=2.5",
+		} {
+			if !strings.Contains(out, exp) {
+				t.Fatalf("expected %q in output:\n%s", exp, out)
+			}
+		}
+	})
+
+	t.Run("CorruptOpLog", func(t *testing.T) {
+		data := append([]byte(nil), buf.Bytes()...)
+		data[len(data)-1] ^= 0xff
+		out, err := runInspect(t, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "Op log: CORRUPT: op log at offset") {
+			t.Fatalf("expected corrupt op log in output:\n%s", out)
+		}
+	})
+}
+
+// runInspect runs an InspectCommand on a file containing data, and returns
+// what it printed to stdout.
+func runInspect(t *testing.T, data []byte) (string, error) {
+	t.Helper()
+	file, err := ioutil.TempFile("", "inspectTest")
+	if err != nil {
+		t.Fatalf("creating tempfile: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		t.Fatalf("writing to tempfile: %v", err)
+	}
+	file.Close()
 
-	//	Todo: need correct roaring file for happy path
+	var stdout bytes.Buffer
+	cm := NewInspectCommand(nil, &stdout, ioutil.Discard)
+	cm.Path = file.Name()
+	err = cm.Run(context.Background())
+	return stdout.String(), err
 }
@@ -0,0 +1,238 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// nodePollInterval is how often the node commands check on the cluster while
+// waiting for a resize to finish.
+var nodePollInterval = time.Second
+
+// NodeWaitCommand represents a command for waiting until a new node has
+// joined a cluster and received its data.
+type NodeWaitCommand struct {
+	// Remote host and port of a node already in the cluster.
+	Host string
+
+	// Node is the ID or host:port of the joining node.
+	Node string
+
+	// How long to wait for. Zero means no limit.
+	Timeout time.Duration
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewNodeWaitCommand returns a new instance of NodeWaitCommand.
+func NewNodeWaitCommand(stdin io.Reader, stdout, stderr io.Writer) *NodeWaitCommand {
+	return &NodeWaitCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// Run waits until the node is a member of the cluster and the cluster is no
+// longer resizing. Nodes join a cluster through gossip, once they are started
+// with one of its members as a seed.
+func (cmd *NodeWaitCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+	if cmd.Node == "" {
+		return errors.New("node required")
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	logger.Printf("waiting for node %s to join", cmd.Node)
+	return waitForCluster(ctx, client, nil, func(state string, nodes []*pilosa.Node) (bool, error) {
+		node := findNode(nodes, cmd.Node)
+		if node == nil || state == pilosa.ClusterStateResizing {
+			return false, nil
+		}
+		logger.Printf("node %s (%s) has joined, cluster is %s", node.ID, node.URI, state)
+		return true, nil
+	})
+}
+
+func (cmd *NodeWaitCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *NodeWaitCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *NodeWaitCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
+
+// NodeRemoveCommand represents a command for removing a node from a cluster.
+type NodeRemoveCommand struct {
+	// Remote host and port of a node in the cluster.
+	Host string
+
+	// Node is the ID or host:port of the node to remove.
+	Node string
+
+	// Force removes the node without moving its data to the remaining nodes,
+	// for nodes which are gone for good.
+	Force bool
+
+	// How long to wait for. Zero means no limit.
+	Timeout time.Duration
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewNodeRemoveCommand returns a new instance of NodeRemoveCommand.
+func NewNodeRemoveCommand(stdin io.Reader, stdout, stderr io.Writer) *NodeRemoveCommand {
+	return &NodeRemoveCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// Run asks the coordinator to remove the node, and waits until its data has
+// been moved and it has left the cluster.
+func (cmd *NodeRemoveCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+	if cmd.Node == "" {
+		return errors.New("node required")
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	_, nodes, err := client.Status(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "getting status")
+	}
+	node := findNode(nodes, cmd.Node)
+	if node == nil {
+		return errors.Wrap(pilosa.ErrNodeIDNotExists, cmd.Node)
+	}
+	coordinator := getCoordinatorNode(nodes)
+	if coordinator == nil {
+		return errors.New("cluster has no coordinator")
+	}
+
+	// Removal is driven by the coordinator.
+	logger.Printf("removing node %s (%s)", node.ID, node.URI)
+	if _, err := client.RemoveNode(ctx, &coordinator.URI, node.ID, cmd.Force); err != nil {
+		return errors.Wrap(err, "removing node")
+	}
+
+	// Follow the removal from the coordinator, which stays in the cluster.
+	return waitForCluster(ctx, client, &coordinator.URI, func(state string, nodes []*pilosa.Node) (bool, error) {
+		if state == pilosa.ClusterStateResizing {
+			return false, nil
+		} else if findNode(nodes, node.ID) != nil {
+			return false, errors.Errorf("resize ended without removing node %s, cluster is %s", node.ID, state)
+		}
+		logger.Printf("node %s has been removed, cluster is %s", node.ID, state)
+		return true, nil
+	})
+}
+
+func (cmd *NodeRemoveCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *NodeRemoveCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *NodeRemoveCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
+
+// waitForCluster polls the status of the cluster, as seen by the node at uri,
+// until done returns true or an error.
+func waitForCluster(ctx context.Context, client *http.InternalClient, uri *pilosa.URI, done func(state string, nodes []*pilosa.Node) (bool, error)) error {
+	ticker := time.NewTicker(nodePollInterval)
+	defer ticker.Stop()
+	for {
+		state, nodes, err := client.Status(ctx, uri)
+		if err != nil {
+			return errors.Wrap(err, "getting status")
+		}
+		if ok, err := done(state, nodes); err != nil || ok {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for cluster")
+		case <-ticker.C:
+		}
+	}
+}
+
+// findNode returns the node in nodes whose ID or host:port is s.
+func findNode(nodes []*pilosa.Node, s string) *pilosa.Node {
+	for _, node := range nodes {
+		if node.ID == s || node.URI.HostPort() == s {
+			return node
+		}
+	}
+	return nil
+}
+
+// getCoordinatorNode returns the coordinator among nodes, if there is one.
+func getCoordinatorNode(nodes []*pilosa.Node) *pilosa.Node {
+	for _, node := range nodes {
+		if node.IsCoordinator {
+			return node
+		}
+	}
+	return nil
+}
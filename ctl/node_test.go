@@ -0,0 +1,79 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestNodeCommands_Run(t *testing.T) {
+	defer func(d time.Duration) { nodePollInterval = d }(nodePollInterval)
+	nodePollInterval = 10 * time.Millisecond
+
+	cluster := test.MustNewCluster(t, 3)
+	for _, c := range cluster {
+		c.Config.Cluster.ReplicaN = 2
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer cluster.Close()
+	for i := 0; cluster[0].API.State() != pilosa.ClusterStateNormal; i++ {
+		if i > 1000 {
+			t.Fatalf("cluster did not become NORMAL: %s", cluster[0].API.State())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1) Set(1048577, f=1) Set(2097153, f=1) Set(3145729, f=1)")
+
+	// Waiting for a node which is already a member returns straight away.
+	wait := NewNodeWaitCommand(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	wait.Host = cluster[0].API.Node().URI.HostPort()
+	wait.Node = cluster[1].API.Node().URI.HostPort()
+	wait.Timeout = 5 * time.Second
+	if err := wait.Run(context.Background()); err != nil {
+		t.Fatalf("waiting for node: %v", err)
+	}
+
+	remove := NewNodeRemoveCommand(strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	remove.Host = cluster[1].API.Node().URI.HostPort()
+	remove.Node = cluster[2].API.Node().ID
+	remove.Timeout = 30 * time.Second
+	if err := remove.Run(context.Background()); err != nil {
+		t.Fatalf("removing node: %v", err)
+	}
+	if hosts := cluster[0].API.Hosts(context.Background()); len(hosts) != 2 {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+	resp := cluster.Query(t, "i", "Count(Row(f=1))")
+	if n := resp.Results[0].(uint64); n != 4 {
+		t.Fatalf("unexpected count after removal: %d", n)
+	}
+
+	// Unknown nodes can't be removed.
+	remove.Node = "unknown"
+	if err := remove.Run(context.Background()); err == nil {
+		t.Fatal("expected error removing unknown node")
+	}
+}
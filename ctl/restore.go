@@ -0,0 +1,266 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// RestoreCommand represents a command for restoring indexes from a tar
+// archive written by BackupCommand.
+type RestoreCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Filename to read the archive from.
+	Path string
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewRestoreCommand returns a new instance of RestoreCommand.
+func NewRestoreCommand(stdin io.Reader, stdout, stderr io.Writer) *RestoreCommand {
+	return &RestoreCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// Run executes the restore.
+func (cmd *RestoreCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+
+	// Use input file, if specified.
+	// Otherwise use STDIN.
+	var r io.Reader = cmd.Stdin
+	if cmd.Path != "" {
+		f, err := os.Open(cmd.Path)
+		if err != nil {
+			return errors.Wrap(err, "opening file")
+		}
+		defer f.Close()
+
+		r = f
+	}
+	tr := tar.NewReader(r)
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+	uri, err := pilosa.NewURIFromAddress(cmd.Host)
+	if err != nil {
+		return errors.Wrap(err, "parsing host")
+	}
+
+	// The schema comes first, so that fragments have somewhere to go.
+	hdr, err := tr.Next()
+	if err == io.EOF {
+		return errors.New("archive is empty")
+	} else if err != nil {
+		return errors.Wrap(err, "reading archive")
+	} else if hdr.Name != backupSchemaName {
+		return errors.Errorf("expected %s at start of archive, got %s", backupSchemaName, hdr.Name)
+	}
+	schema := &pilosa.Schema{}
+	if err := json.NewDecoder(tr).Decode(&schema.Indexes); err != nil {
+		return errors.Wrap(err, "decoding schema")
+	}
+	if err := client.PostSchema(ctx, uri, schema, false); err != nil {
+		return errors.Wrap(err, "creating schema")
+	}
+
+	// Cache the owners of each shard, since every field and view of a
+	// shard shares them.
+	owners := make(map[string]map[uint64][]*pilosa.Node)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "reading archive")
+		}
+
+		if parts := strings.Split(hdr.Name, "/"); parts[0] == "columnattrs" || parts[0] == "rowattrs" {
+			if err := cmd.restoreAttrs(ctx, client, parts, tr); err != nil {
+				return errors.Wrapf(err, "restoring %s", hdr.Name)
+			}
+			continue
+		}
+
+		index, field, view, shard, err := parseFragmentEntryName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", hdr.Name)
+		}
+
+		if owners[index] == nil {
+			owners[index] = make(map[uint64][]*pilosa.Node)
+		}
+		nodes, ok := owners[index][shard]
+		if !ok {
+			if nodes, err = client.FragmentNodes(ctx, index, shard); err != nil {
+				return errors.Wrap(err, "getting fragment nodes")
+			}
+			owners[index][shard] = nodes
+			logger.Printf("restoring index %s, shard %d", index, shard)
+		}
+
+		// Every replica gets a copy.
+		for _, node := range nodes {
+			if err := client.ImportFragmentData(ctx, &node.URI, index, field, view, shard, bytes.NewReader(data)); err != nil {
+				return errors.Wrapf(err, "restoring %s to %s", hdr.Name, node.URI)
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreAttrsBatchSize is the number of attribute calls sent in each query.
+const restoreAttrsBatchSize = 1000
+
+// restoreAttrs sets the attributes in the archive entry r, whose name is
+// "columnattrs/INDEX" or "rowattrs/INDEX/FIELD", split into parts.
+func (cmd *RestoreCommand) restoreAttrs(ctx context.Context, client *http.InternalClient, parts []string, r io.Reader) error {
+	var index, call string
+	switch {
+	case parts[0] == "columnattrs" && len(parts) == 2:
+		index, call = parts[1], "SetColumnAttrs("
+	case parts[0] == "rowattrs" && len(parts) == 3:
+		index, call = parts[1], "SetRowAttrs("+parts[2]+", "
+	default:
+		return errors.New("unexpected archive entry")
+	}
+
+	// Numbers are kept as they were written, so that integers stay integers.
+	var attrs map[uint64]map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&attrs); err != nil {
+		return errors.Wrap(err, "decoding attributes")
+	}
+	ids := make([]uint64, 0, len(attrs))
+	for id := range attrs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var buf bytes.Buffer
+	for i, id := range ids {
+		args, err := formatAttrArgs(attrs[id])
+		if err != nil {
+			return errors.Wrapf(err, "formatting attributes of %d", id)
+		} else if args != "" {
+			fmt.Fprintf(&buf, "%s%d, %s)\n", call, id, args)
+		}
+
+		if ((i+1)%restoreAttrsBatchSize == 0 || i == len(ids)-1) && buf.Len() > 0 {
+			if _, err := client.Query(ctx, index, &pilosa.QueryRequest{Index: index, Query: buf.String()}); err != nil {
+				return errors.Wrap(err, "setting attributes")
+			}
+			buf.Reset()
+		}
+	}
+	return nil
+}
+
+// formatAttrArgs formats attrs as the arguments of a PQL call, in key order.
+func formatAttrArgs(attrs map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var v string
+		switch a := attrs[k].(type) {
+		case nil:
+			continue
+		case string:
+			v = strconv.Quote(a)
+		case bool:
+			v = strconv.FormatBool(a)
+		case json.Number:
+			// PQL has no exponent notation.
+			if strings.ContainsAny(a.String(), "eE") {
+				f, err := a.Float64()
+				if err != nil {
+					return "", err
+				}
+				v = strconv.FormatFloat(f, 'f', -1, 64)
+			} else {
+				v = a.String()
+			}
+		default:
+			return "", errors.Errorf("unexpected value of attribute %s: %v", k, a)
+		}
+		args = append(args, k+"="+v)
+	}
+	return strings.Join(args, ", "), nil
+}
+
+// parseFragmentEntryName parses an archive entry name of the form
+// "fragments/INDEX/FIELD/VIEW/SHARD".
+func parseFragmentEntryName(name string) (index, field, view string, shard uint64, err error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 5 || parts[0] != "fragments" {
+		return "", "", "", 0, errors.Errorf("unexpected archive entry: %s", name)
+	}
+	shard, err = strconv.ParseUint(parts[4], 10, 64)
+	if err != nil {
+		return "", "", "", 0, errors.Wrapf(err, "parsing shard of archive entry %s", name)
+	}
+	return parts[1], parts[2], parts[3], shard, nil
+}
+
+func (cmd *RestoreCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *RestoreCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *RestoreCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
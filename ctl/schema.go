@@ -0,0 +1,394 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaApplyCommand represents a command for changing the schema of a
+// cluster to match a schema file.
+type SchemaApplyCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Path of the schema file, in YAML or JSON.
+	Path string
+
+	// Plan prints the changes without making them.
+	Plan bool
+
+	// Drop allows changes which lose data: dropping indexes and fields which
+	// are not in the file, and recreating those whose options differ.
+	Drop bool
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewSchemaApplyCommand returns a new instance of SchemaApplyCommand.
+func NewSchemaApplyCommand(stdin io.Reader, stdout, stderr io.Writer) *SchemaApplyCommand {
+	return &SchemaApplyCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// schemaFile is the schema read by SchemaApplyCommand. Options are named as
+// in the HTTP API, and only those which are given are compared with the
+// cluster.
+type schemaFile struct {
+	Indexes []*schemaFileIndex `yaml:"indexes"`
+}
+
+type schemaFileIndex struct {
+	Name    string             `yaml:"name"`
+	Options schemaIndexOptions `yaml:"options"`
+	Fields  []*schemaFileField `yaml:"fields"`
+}
+
+type schemaIndexOptions struct {
+	Keys           *bool `yaml:"keys" json:"keys,omitempty"`
+	TrackExistence *bool `yaml:"trackExistence" json:"trackExistence,omitempty"`
+}
+
+type schemaFileField struct {
+	Name    string             `yaml:"name"`
+	Options schemaFieldOptions `yaml:"options"`
+}
+
+type schemaFieldOptions struct {
+	Type           *string `yaml:"type" json:"type,omitempty"`
+	CacheType      *string `yaml:"cacheType" json:"cacheType,omitempty"`
+	CacheSize      *uint32 `yaml:"cacheSize" json:"cacheSize,omitempty"`
+	Min            *int64  `yaml:"min" json:"min,omitempty"`
+	Max            *int64  `yaml:"max" json:"max,omitempty"`
+	TimeQuantum    *string `yaml:"timeQuantum" json:"timeQuantum,omitempty"`
+	Keys           *bool   `yaml:"keys" json:"keys,omitempty"`
+	NoStandardView *bool   `yaml:"noStandardView" json:"noStandardView,omitempty"`
+}
+
+// Kinds of schema change.
+const (
+	schemaCreate   = "+"
+	schemaDrop     = "-"
+	schemaRecreate = "-/+"
+)
+
+// schemaChange is a change to a single index or field.
+type schemaChange struct {
+	op    string
+	index *schemaFileIndex // for drops, only the name is set
+	field *schemaFileField // nil for changes to an index
+
+	// reason lists the options which differ, for recreations.
+	reason string
+}
+
+// destructive returns true if the change loses data.
+func (c *schemaChange) destructive() bool {
+	return c.op != schemaCreate
+}
+
+func (c *schemaChange) String() string {
+	s := fmt.Sprintf("%-3s index %s", c.op, c.index.Name)
+	if c.field != nil {
+		s = fmt.Sprintf("%-3s field %s/%s", c.op, c.index.Name, c.field.Name)
+	}
+	if c.reason != "" {
+		s += " (" + c.reason + ")"
+	}
+	return s
+}
+
+// Run reads the schema file, prints the changes needed for the cluster to
+// match it, and makes them unless Plan is set.
+func (cmd *SchemaApplyCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+
+	buf, err := ioutil.ReadFile(cmd.Path)
+	if err != nil {
+		return errors.Wrap(err, "reading schema file")
+	}
+	file, err := parseSchemaFile(buf)
+	if err != nil {
+		return errors.Wrap(err, "parsing schema file")
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+	current, err := client.Schema(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting schema")
+	}
+
+	changes, err := diffSchema(file, current)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(cmd.Stdout, "No changes.")
+		return nil
+	}
+	var skipped int
+	for _, c := range changes {
+		if c.destructive() && !cmd.Drop {
+			fmt.Fprintf(cmd.Stdout, "%s: skipped, needs --drop\n", c)
+			skipped++
+		} else {
+			fmt.Fprintln(cmd.Stdout, c)
+		}
+	}
+	if cmd.Plan {
+		return nil
+	}
+
+	for _, c := range changes {
+		if c.destructive() && !cmd.Drop {
+			continue
+		}
+		logger.Printf("applying: %s", c)
+		if err := applySchemaChange(ctx, client, c); err != nil {
+			return errors.Wrapf(err, "applying %s", c)
+		}
+	}
+	if skipped > 0 {
+		logger.Printf("skipped %d changes which lose data", skipped)
+	}
+	return nil
+}
+
+// applySchemaChange makes a single change to the cluster. Recreating an index
+// recreates its fields too.
+func applySchemaChange(ctx context.Context, client *http.InternalClient, c *schemaChange) error {
+	if c.field != nil {
+		if c.op != schemaCreate {
+			if err := client.DeleteField(ctx, c.index.Name, c.field.Name); err != nil {
+				return errors.Wrap(err, "deleting field")
+			}
+		}
+		if c.op != schemaDrop {
+			return createSchemaField(ctx, client, c.index.Name, c.field)
+		}
+		return nil
+	}
+
+	if c.op != schemaCreate {
+		if err := client.DeleteIndex(ctx, c.index.Name); err != nil {
+			return errors.Wrap(err, "deleting index")
+		}
+	}
+	if c.op == schemaDrop {
+		return nil
+	}
+	opt := pilosa.IndexOptions{TrackExistence: true}
+	if c.index.Options.Keys != nil {
+		opt.Keys = *c.index.Options.Keys
+	}
+	if c.index.Options.TrackExistence != nil {
+		opt.TrackExistence = *c.index.Options.TrackExistence
+	}
+	if err := client.CreateIndex(ctx, c.index.Name, opt); err != nil {
+		return errors.Wrap(err, "creating index")
+	}
+	for _, fi := range c.index.Fields {
+		if err := createSchemaField(ctx, client, c.index.Name, fi); err != nil {
+			return errors.Wrapf(err, "field %s", fi.Name)
+		}
+	}
+	return nil
+}
+
+// createSchemaField creates a field with the options given in the schema
+// file. The server fills in the others.
+func createSchemaField(ctx context.Context, client *http.InternalClient, index string, fi *schemaFileField) error {
+	options, err := optionsMap(fi.Options)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(client.CreateFieldFromOptions(ctx, index, fi.Name, options), "creating field")
+}
+
+// parseSchemaFile parses a schema file in YAML or JSON, which is a subset of
+// YAML.
+func parseSchemaFile(buf []byte) (*schemaFile, error) {
+	file := &schemaFile{}
+	if err := yaml.UnmarshalStrict(buf, file); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]bool)
+	for _, ii := range file.Indexes {
+		if ii.Name == "" {
+			return nil, errors.New("index without a name")
+		} else if indexes[ii.Name] {
+			return nil, errors.Errorf("index %s appears twice", ii.Name)
+		}
+		indexes[ii.Name] = true
+
+		fields := make(map[string]bool)
+		for _, fi := range ii.Fields {
+			if fi.Name == "" {
+				return nil, errors.Errorf("field without a name in index %s", ii.Name)
+			} else if fields[fi.Name] {
+				return nil, errors.Errorf("field %s appears twice in index %s", fi.Name, ii.Name)
+			}
+			fields[fi.Name] = true
+		}
+	}
+	return file, nil
+}
+
+// optionsMap converts options to a map, with keys named as in the HTTP API.
+func optionsMap(options interface{}) (map[string]interface{}, error) {
+	buf, err := json.Marshal(options)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding options")
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, errors.Wrap(err, "decoding options")
+	}
+	return m, nil
+}
+
+// diffSchema returns the changes needed for the current schema to match file.
+func diffSchema(file *schemaFile, current []*pilosa.IndexInfo) ([]*schemaChange, error) {
+	var changes []*schemaChange
+	existing := make(map[string]*pilosa.IndexInfo, len(current))
+	for _, ii := range current {
+		existing[ii.Name] = ii
+	}
+
+	wanted := make(map[string]bool, len(file.Indexes))
+	for _, ii := range file.Indexes {
+		wanted[ii.Name] = true
+		cur := existing[ii.Name]
+		if cur == nil {
+			changes = append(changes, &schemaChange{op: schemaCreate, index: ii})
+			for _, fi := range ii.Fields {
+				changes = append(changes, &schemaChange{op: schemaCreate, index: ii, field: fi})
+			}
+			continue
+		}
+
+		reason, err := diffOptions(ii.Options, cur.Options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "comparing options of index %s", ii.Name)
+		} else if reason != "" {
+			changes = append(changes, &schemaChange{op: schemaRecreate, index: ii, reason: reason})
+			continue
+		}
+
+		curFields := make(map[string]*pilosa.FieldInfo, len(cur.Fields))
+		for _, fi := range cur.Fields {
+			curFields[fi.Name] = fi
+		}
+		wantedFields := make(map[string]bool, len(ii.Fields))
+		for _, fi := range ii.Fields {
+			wantedFields[fi.Name] = true
+			curField := curFields[fi.Name]
+			if curField == nil {
+				changes = append(changes, &schemaChange{op: schemaCreate, index: ii, field: fi})
+				continue
+			}
+			reason, err := diffOptions(fi.Options, &curField.Options)
+			if err != nil {
+				return nil, errors.Wrapf(err, "comparing options of field %s/%s", ii.Name, fi.Name)
+			} else if reason != "" {
+				changes = append(changes, &schemaChange{op: schemaRecreate, index: ii, field: fi, reason: reason})
+			}
+		}
+		for _, fi := range cur.Fields {
+			if !wantedFields[fi.Name] {
+				changes = append(changes, &schemaChange{op: schemaDrop, index: ii, field: &schemaFileField{Name: fi.Name}})
+			}
+		}
+	}
+
+	for _, ii := range current {
+		if !wanted[ii.Name] {
+			changes = append(changes, &schemaChange{op: schemaDrop, index: &schemaFileIndex{Name: ii.Name}})
+		}
+	}
+	return changes, nil
+}
+
+// diffOptions describes how the options given in a schema file differ from
+// the current options of an index or field. Options missing from the file
+// are not compared. It returns an empty string if they are the same.
+func diffOptions(want, current interface{}) (string, error) {
+	w, err := optionsMap(want)
+	if err != nil {
+		return "", err
+	}
+	h, err := optionsMap(current)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(w))
+	for key := range w {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, key := range keys {
+		wv, hv := w[key], h[key]
+		if hv == nil {
+			// Options which are zero may be left out of the current ones.
+			switch wv {
+			case false, float64(0), "":
+				continue
+			}
+		}
+		if fmt.Sprint(wv) != fmt.Sprint(hv) {
+			diffs = append(diffs, fmt.Sprintf("%s: %v -> %v", key, hv, wv))
+		}
+	}
+	return strings.Join(diffs, ", "), nil
+}
+
+func (cmd *SchemaApplyCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *SchemaApplyCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *SchemaApplyCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
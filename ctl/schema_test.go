@@ -0,0 +1,166 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+const testSchemaFile = `
+indexes:
+  - name: i
+    options:
+      keys: false
+    fields:
+      - name: f
+      - name: n
+        options:
+          type: int
+          min: 0
+          max: 1000
+      - name: t
+        options:
+          type: time
+          timeQuantum: Y
+`
+
+func TestSchemaApplyCommand_Run(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	// Index j isn't in the file, and field g of index i isn't either.
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "g")
+	cluster.CreateField(t, "j", pilosa.IndexOptions{}, "f")
+
+	file, err := ioutil.TempFile("", "pilosa-schema-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(testSchemaFile); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	run := func(plan, drop bool) string {
+		var buf bytes.Buffer
+		cm := NewSchemaApplyCommand(nil, &buf, ioutil.Discard)
+		cm.Host = cluster[0].API.Node().URI.HostPort()
+		cm.Path = file.Name()
+		cm.Plan = plan
+		cm.Drop = drop
+		if err := cm.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	// The plan changes nothing.
+	out := run(true, false)
+	for _, exp := range []string{
+		"+   field i/f\n",
+		"+   field i/n\n",
+		"-   field i/g: skipped, needs --drop\n",
+		"-   index j: skipped, needs --drop\n",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Fatalf("expected %q in plan:\n%s", exp, out)
+		}
+	}
+	if fields := cluster[0].API.Schema(context.Background())[0].Fields; len(fields) != 1 {
+		t.Fatalf("unexpected fields after plan: %+v", fields)
+	}
+
+	// Without --drop, only the fields are created.
+	run(false, false)
+	schema := cluster[0].API.Schema(context.Background())
+	if len(schema) != 2 || len(schema[0].Fields) != 4 {
+		t.Fatalf("unexpected schema: %+v", schema)
+	} else if opt := schema[0].Fields[2].Options; opt.Type != pilosa.FieldTypeInt || opt.Max != 1000 {
+		t.Fatalf("unexpected options of field n: %+v", opt)
+	}
+
+	// With --drop, the cluster matches the file, after which there is
+	// nothing left to do.
+	run(false, true)
+	schema = cluster[0].API.Schema(context.Background())
+	if len(schema) != 1 || len(schema[0].Fields) != 3 {
+		t.Fatalf("unexpected schema: %+v", schema)
+	}
+	if out := run(true, false); out != "No changes.\n" {
+		t.Fatalf("unexpected plan:\n%s", out)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	file, err := parseSchemaFile([]byte(testSchemaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := []*pilosa.IndexInfo{{
+		Name:    "i",
+		Options: pilosa.IndexOptions{TrackExistence: true},
+		Fields: []*pilosa.FieldInfo{
+			{Name: "f", Options: pilosa.FieldOptions{Type: pilosa.FieldTypeSet, CacheType: pilosa.CacheTypeRanked, CacheSize: 50000}},
+			{Name: "n", Options: pilosa.FieldOptions{Type: pilosa.FieldTypeInt, Min: 0, Max: 100}},
+			{Name: "t", Options: pilosa.FieldOptions{Type: pilosa.FieldTypeTime, TimeQuantum: "Y"}},
+		},
+	}}
+
+	changes, err := diffSchema(file, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	if exp := []string{"-/+ field i/n (max: 100 -> 1000)"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected changes: %q", got)
+	}
+
+	// A different index option recreates the whole index.
+	current[0].Options.Keys = true
+	if changes, err = diffSchema(file, current); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 1 || changes[0].String() != "-/+ index i (keys: true -> false)" {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+}
+
+func TestParseSchemaFile_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		err  string
+	}{
+		{file: "indexes:\n  - name: i\n  - name: i\n", err: "index i appears twice"},
+		{file: "indexes:\n  - name: i\n    fields:\n      - name: f\n      - name: f\n", err: "field f appears twice"},
+		{file: "indexes:\n  - name: i\n    field:\n      - name: f\n", err: "field field not found"},
+		{file: "indexes:\n  - options: {}\n", err: "index without a name"},
+	} {
+		if _, err := parseSchemaFile([]byte(tt.file)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parsing %q: expected error %q, got %v", tt.file, tt.err, err)
+		}
+	}
+}
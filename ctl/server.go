@@ -28,23 +28,50 @@ func BuildServerFlags(cmd *cobra.Command, srv *server.Command) {
 	flags.StringVarP(&srv.Config.Bind, "bind", "b", srv.Config.Bind, "Default URI on which pilosa should listen.")
 	flags.StringVar(&srv.Config.Advertise, "advertise", srv.Config.Advertise, "Address to advertise externally.")
 	flags.IntVarP(&srv.Config.MaxWritesPerRequest, "max-writes-per-request", "", srv.Config.MaxWritesPerRequest, "Number of write commands per request.")
+	flags.DurationVarP((*time.Duration)(&srv.Config.QueryTimeout), "query-timeout", "", (time.Duration)(srv.Config.QueryTimeout), "Maximum duration of a single query. Zero means no limit.")
 	flags.StringVar(&srv.Config.LogPath, "log-path", srv.Config.LogPath, "Log path")
 	flags.BoolVar(&srv.Config.Verbose, "verbose", srv.Config.Verbose, "Enable verbose logging")
+	flags.StringVar(&srv.Config.LogFormat, "log-format", srv.Config.LogFormat, "Log format: text, or json for one JSON object per line.")
 	flags.Uint64Var(&srv.Config.MaxMapCount, "max-map-count", srv.Config.MaxMapCount, "Limits the maximum number of active mmaps. Pilosa will fall back to reading files once this is exhausted. Set below your system's vm.max_map_count.")
 	flags.Uint64Var(&srv.Config.MaxFileCount, "max-file-count", srv.Config.MaxFileCount, "Soft limit on the maximum number of fragment files Pilosa keeps open simultaneously.")
+	flags.IntVar(&srv.Config.WorkerPoolSize, "worker-pool-size", srv.Config.WorkerPoolSize, "Number of goroutines processing queries. Zero means the number of CPUs.")
+	flags.IntVar(&srv.Config.ImportWorkerPoolSize, "import-worker-pool-size", srv.Config.ImportWorkerPoolSize, "Number of goroutines processing roaring imports. Zero means the number of CPUs.")
 
 	// TLS
 	SetTLSConfig(flags, &srv.Config.TLS.CertificatePath, &srv.Config.TLS.CertificateKeyPath, &srv.Config.TLS.CACertPath, &srv.Config.TLS.SkipVerify, &srv.Config.TLS.EnableClientVerification)
 
 	// Handler
-	flags.StringSliceVarP(&srv.Config.Handler.AllowedOrigins, "handler.allowed-origins", "", []string{}, "Comma separated list of allowed origin URIs (for CORS/WebUI).")
+	flags.StringSliceVarP(&srv.Config.Handler.AllowedOrigins, "handler.allowed-origins", "", srv.Config.Handler.AllowedOrigins, "Comma separated list of allowed origin URIs (for CORS/WebUI).")
+	flags.StringVarP(&srv.Config.Handler.AuditLogPath, "handler.audit-log-path", "", srv.Config.Handler.AuditLogPath, "Path of a file to which requests that change the schema or data are logged.")
+	flags.IntVar(&srv.Config.Handler.AuditLogMaxSize, "handler.audit-log-max-size", srv.Config.Handler.AuditLogMaxSize, "Size in megabytes past which the audit log is rotated. Zero never rotates it.")
+	flags.IntVar(&srv.Config.Handler.AuditLogMaxBackups, "handler.audit-log-max-backups", srv.Config.Handler.AuditLogMaxBackups, "Number of rotated audit logs kept.")
+
+	// Rate limits
+	flags.Float64Var(&srv.Config.RateLimit.Query, "rate-limit.query", srv.Config.RateLimit.Query, "Queries per second accepted for each index. Zero means no limit.")
+	flags.Float64Var(&srv.Config.RateLimit.Import, "rate-limit.import", srv.Config.RateLimit.Import, "Bits imported per second accepted for each index. Zero means no limit.")
+	flags.StringSliceVar(&srv.Config.RateLimit.IndexQuery, "rate-limit.index-query", srv.Config.RateLimit.IndexQuery, "Comma separated list of index=rate pairs overriding rate-limit.query for individual indexes.")
+	flags.StringSliceVar(&srv.Config.RateLimit.IndexImport, "rate-limit.index-import", srv.Config.RateLimit.IndexImport, "Comma separated list of index=rate pairs overriding rate-limit.import for individual indexes.")
+
+	// Auth
+	flags.BoolVar(&srv.Config.Auth.Enable, "auth.enable", srv.Config.Auth.Enable, "Require clients to authenticate with an API key or a JWT.")
+	flags.StringSliceVar(&srv.Config.Auth.APIKeys, "auth.api-keys", srv.Config.Auth.APIKeys, "Comma separated list of key=role pairs giving the accepted API keys and the roles they grant.")
+	flags.StringVar(&srv.Config.Auth.JWTSecret, "auth.jwt-secret", srv.Config.Auth.JWTSecret, "Shared secret with which bearer tokens are signed (HS256).")
+	flags.StringVar(&srv.Config.Auth.RolesClaim, "auth.roles-claim", srv.Config.Auth.RolesClaim, "JWT claim holding the token's roles.")
+	flags.StringSliceVar(&srv.Config.Auth.Grants, "auth.grants", srv.Config.Auth.Grants, "Comma separated list of role=index:permission or role=index/field:permission entries binding permissions to roles.")
+	flags.StringVar(&srv.Config.Auth.RolesPath, "auth.roles-path", srv.Config.Auth.RolesPath, "File in which role bindings changed through the API are kept.")
+	flags.StringVar(&srv.Config.Auth.NodeKey, "auth.node-key", srv.Config.Auth.NodeKey, "API key which nodes send to each other. Must be the same on every node.")
 
 	// Cluster
 	flags.BoolVarP(&srv.Config.Cluster.Disabled, "cluster.disabled", "", srv.Config.Cluster.Disabled, "Disabled multi-node cluster communication (used for testing)")
 	flags.BoolVarP(&srv.Config.Cluster.Coordinator, "cluster.coordinator", "", srv.Config.Cluster.Coordinator, "Host that will act as cluster coordinator during startup and resizing.")
-	flags.IntVarP(&srv.Config.Cluster.ReplicaN, "cluster.replicas", "", 1, "Number of hosts each piece of data should be stored on.")
-	flags.StringSliceVarP(&srv.Config.Cluster.Hosts, "cluster.hosts", "", []string{}, "Comma separated list of hosts in cluster. Only used for testing.")
-	flags.DurationVarP((*time.Duration)(&srv.Config.Cluster.LongQueryTime), "cluster.long-query-time", "", time.Minute, "Duration that will trigger log and stat messages for slow queries.")
+	flags.IntVarP(&srv.Config.Cluster.ReplicaN, "cluster.replicas", "", srv.Config.Cluster.ReplicaN, "Number of hosts each piece of data should be stored on.")
+	flags.StringSliceVarP(&srv.Config.Cluster.Hosts, "cluster.hosts", "", srv.Config.Cluster.Hosts, "Comma separated list of hosts in cluster. Only used for testing.")
+	flags.DurationVarP((*time.Duration)(&srv.Config.Cluster.LongQueryTime), "cluster.long-query-time", "", (time.Duration)(srv.Config.Cluster.LongQueryTime), "Duration that will trigger log and stat messages for slow queries.")
+
+	// Storage
+	flags.StringVarP(&srv.Config.Storage.Fsync, "storage.fsync", "", srv.Config.Storage.Fsync, "When the op log of a fragment is synced to disk: always, or on snapshot.")
+	flags.IntVarP(&srv.Config.Storage.MaxOpN, "storage.max-op-n", "", srv.Config.Storage.MaxOpN, "Number of operations in the op log of a fragment after which it is snapshotted.")
+	flags.BoolVarP(&srv.Config.Storage.Mmap, "storage.mmap", "", srv.Config.Storage.Mmap, "Map fragment files into memory rather than reading them onto the heap.")
 
 	// Translation
 	flags.StringVarP(&srv.Config.Translation.PrimaryURL, "translation.primary-url", "", srv.Config.Translation.PrimaryURL, "DEPRECATED: URL for primary translation node for replication.")
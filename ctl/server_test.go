@@ -16,6 +16,8 @@ package ctl
 
 import (
 	"bytes"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/pilosa/pilosa/v2/server"
@@ -35,3 +37,29 @@ func TestBuildServerFlags(t *testing.T) {
 		t.Fatal("log-path flag is required")
 	}
 }
+
+// Every key of the config file can be set by a flag of the same name.
+func TestBuildServerFlags_ConfigKeys(t *testing.T) {
+	cm := &cobra.Command{}
+	stdin, stdout, stderr := GetIO(bytes.Buffer{})
+	BuildServerFlags(cm, server.NewCommand(stdin, stdout, stderr))
+
+	var check func(prefix string, typ reflect.Type)
+	check = func(prefix string, typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			key := strings.Split(f.Tag.Get("toml"), ",")[0]
+			if key == "" || key == "-" {
+				continue
+			}
+			if f.Type.Kind() == reflect.Struct {
+				check(prefix+key+".", f.Type)
+				continue
+			}
+			if cm.Flags().Lookup(prefix+key) == nil {
+				t.Errorf("no flag for config key %s", prefix+key)
+			}
+		}
+	}
+	check("", reflect.TypeOf(server.Config{}))
+}
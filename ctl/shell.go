@@ -0,0 +1,583 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Output formats for ShellCommand.
+const (
+	ShellFormatTable = "table"
+	ShellFormatJSON  = "json"
+)
+
+// pqlCalls are the PQL calls offered by tab completion.
+var pqlCalls = []string{
+	"Clear", "ClearRow", "Count", "Difference", "GroupBy", "Intersect",
+	"Limit", "Max", "MaxRow", "Min", "MinRow", "Not", "Options", "Row", "Rows",
+	"Set", "SetColumnAttrs", "SetRowAttrs", "Shift", "Store", "Sum", "TopN",
+	"Union", "Xor",
+}
+
+const shellHelp = `Enter a PQL query to run it against the current index. A query continues
+over several lines until its parentheses are balanced.
+
+  \c INDEX          use INDEX for queries
+  \d                list indexes
+  \d INDEX          list the fields of INDEX
+  \format FORMAT    print results as a table or json
+  \history          print previous queries
+  \h                print this help
+  \q                quit
+`
+
+// ShellCommand represents an interactive shell for running queries.
+type ShellCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Index is the index queries run against. It can be changed with \c.
+	Index string
+
+	// Format is the output format of query results, table or json. It can
+	// be changed with \format.
+	Format string
+
+	// HistoryPath is the file queries are saved to. Nothing is saved if it
+	// is empty.
+	HistoryPath string
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+
+	client *http.InternalClient
+	schema []*pilosa.IndexInfo
+	color  bool
+}
+
+// NewShellCommand returns a new instance of ShellCommand.
+func NewShellCommand(stdin io.Reader, stdout, stderr io.Writer) *ShellCommand {
+	return &ShellCommand{
+		CmdIO:  pilosa.NewCmdIO(stdin, stdout, stderr),
+		Format: ShellFormatTable,
+	}
+}
+
+// shellReader reads the lines of input to a shell.
+type shellReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+}
+
+// scanReader is a shellReader for input which isn't a terminal, such as a
+// script piped to the shell. It has no prompt.
+type scanReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *scanReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *scanReader) SetPrompt(string) {}
+
+// Run reads queries and commands until the input ends or \q.
+func (cmd *ShellCommand) Run(ctx context.Context) error {
+	switch cmd.Format {
+	case ShellFormatTable, ShellFormatJSON:
+	default:
+		return errors.Errorf("unknown format: %q", cmd.Format)
+	}
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+	cmd.client = client
+	if err := cmd.refreshSchema(ctx); err != nil {
+		return err
+	}
+
+	// Edit lines on the terminal if there is one: this gives history and tab
+	// completion, and the terminal handles output while it is in raw mode.
+	var r shellReader = &scanReader{scanner: bufio.NewScanner(cmd.Stdin)}
+	var w io.Writer = cmd.Stdout
+	if f, ok := cmd.Stdin.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		state, err := terminal.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return errors.Wrap(err, "setting up terminal")
+		}
+		defer func() { _ = terminal.Restore(int(f.Fd()), state) }()
+
+		t := terminal.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{cmd.Stdin, cmd.Stdout}, "")
+		t.AutoCompleteCallback = cmd.complete
+		r, w = t, t
+		cmd.color = true
+		fmt.Fprintf(w, "Connected to %s. Enter \\h for help.\n", cmd.Host)
+	}
+
+	var lines []string
+	for {
+		if len(lines) > 0 {
+			r.SetPrompt("...> ")
+		} else if cmd.Index != "" {
+			r.SetPrompt("pilosa:" + cmd.Index + "> ")
+		} else {
+			r.SetPrompt("pilosa> ")
+		}
+
+		line, err := r.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "reading input")
+		}
+
+		if len(lines) == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			} else if strings.HasPrefix(trimmed, `\`) {
+				quit, err := cmd.runMeta(ctx, w, trimmed)
+				if err != nil {
+					fmt.Fprintf(w, "error: %s\n", err)
+				} else if quit {
+					return nil
+				}
+				continue
+			}
+		}
+
+		lines = append(lines, line)
+		query := strings.Join(lines, "\n")
+		if !pqlComplete(query) {
+			continue
+		}
+		lines = nil
+
+		if err := cmd.appendHistory(query); err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+		}
+		if err := cmd.query(ctx, w, query); err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+		}
+	}
+}
+
+// runMeta runs a backslash command, and returns true if the shell should
+// quit.
+func (cmd *ShellCommand) runMeta(ctx context.Context, w io.Writer, line string) (quit bool, err error) {
+	args := strings.Fields(line)
+	switch args[0] {
+	case `\q`, `\quit`:
+		return true, nil
+
+	case `\h`, `\help`, `\?`:
+		fmt.Fprint(w, shellHelp)
+
+	case `\c`, `\use`:
+		if len(args) != 2 {
+			return false, errors.New(`usage: \c INDEX`)
+		}
+		if cmd.findIndex(args[1]) == nil {
+			// The index may have been created since the schema was read.
+			if err := cmd.refreshSchema(ctx); err != nil {
+				return false, err
+			} else if cmd.findIndex(args[1]) == nil {
+				return false, errors.Wrap(pilosa.ErrIndexNotFound, args[1])
+			}
+		}
+		cmd.Index = args[1]
+
+	case `\d`:
+		if err := cmd.refreshSchema(ctx); err != nil {
+			return false, err
+		}
+		if len(args) == 1 {
+			for _, ii := range cmd.schema {
+				fmt.Fprintln(w, ii.Name)
+			}
+			return false, nil
+		}
+		ii := cmd.findIndex(args[1])
+		if ii == nil {
+			return false, errors.Wrap(pilosa.ErrIndexNotFound, args[1])
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "FIELD\tTYPE\tKEYS")
+		for _, fi := range ii.Fields {
+			fmt.Fprintf(tw, "%s\t%s\t%v\n", fi.Name, fi.Options.Type, fi.Options.Keys)
+		}
+		return false, tw.Flush()
+
+	case `\format`:
+		if len(args) != 2 || (args[1] != ShellFormatTable && args[1] != ShellFormatJSON) {
+			return false, errors.New(`usage: \format table|json`)
+		}
+		cmd.Format = args[1]
+
+	case `\history`:
+		if cmd.HistoryPath == "" {
+			return false, nil
+		}
+		buf, err := ioutil.ReadFile(cmd.HistoryPath)
+		if os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, errors.Wrap(err, "reading history")
+		}
+		for _, query := range strings.Split(strings.TrimSpace(string(buf)), "\n") {
+			if cmd.color {
+				query = highlightPQL(query)
+			}
+			fmt.Fprintln(w, query)
+		}
+
+	default:
+		return false, errors.Errorf(`unknown command %s, enter \h for help`, args[0])
+	}
+	return false, nil
+}
+
+// query runs a query against the current index and prints its results.
+func (cmd *ShellCommand) query(ctx context.Context, w io.Writer, query string) error {
+	if cmd.Index == "" {
+		return errors.New(`no index selected, use \c INDEX`)
+	}
+	resp, err := cmd.client.Query(ctx, cmd.Index, &pilosa.QueryRequest{Query: query})
+	if err != nil {
+		return err
+	} else if resp.Err != nil {
+		return resp.Err
+	}
+
+	if cmd.Format == ShellFormatJSON {
+		buf, err := json.MarshalIndent(resp, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "encoding results")
+		}
+		fmt.Fprintf(w, "%s\n", buf)
+		return nil
+	}
+	for _, result := range resp.Results {
+		if err := printShellResult(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printShellResult prints the result of a single call as a table. Results of
+// unknown types are printed as JSON.
+func printShellResult(w io.Writer, result interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	switch result := result.(type) {
+	case nil:
+		fmt.Fprintln(tw, "(none)")
+	case uint64, bool:
+		fmt.Fprintln(tw, result)
+	case *pilosa.Row:
+		if len(result.Keys) > 0 {
+			fmt.Fprintln(tw, "KEY")
+			for _, key := range result.Keys {
+				fmt.Fprintln(tw, key)
+			}
+			fmt.Fprintf(tw, "(%d columns)\n", len(result.Keys))
+		} else {
+			columns := result.Columns()
+			fmt.Fprintln(tw, "COLUMN")
+			for _, col := range columns {
+				fmt.Fprintln(tw, col)
+			}
+			fmt.Fprintf(tw, "(%d columns)\n", len(columns))
+		}
+	case pilosa.Pair:
+		printShellPairs(tw, []pilosa.Pair{result})
+	case []pilosa.Pair:
+		printShellPairs(tw, result)
+	case pilosa.ValCount:
+		fmt.Fprintln(tw, "VALUE\tCOUNT")
+		fmt.Fprintf(tw, "%d\t%d\n", result.Val, result.Count)
+	case pilosa.RowIDs:
+		fmt.Fprintln(tw, "ROW")
+		for _, id := range result {
+			fmt.Fprintln(tw, id)
+		}
+	case *pilosa.RowIdentifiers:
+		if len(result.Keys) > 0 {
+			fmt.Fprintln(tw, "KEY")
+			for _, key := range result.Keys {
+				fmt.Fprintln(tw, key)
+			}
+		} else {
+			fmt.Fprintln(tw, "ROW")
+			for _, id := range result.Rows {
+				fmt.Fprintln(tw, id)
+			}
+		}
+	case []pilosa.GroupCount:
+		if len(result) > 0 {
+			for _, fr := range result[0].Group {
+				fmt.Fprintf(tw, "%s\t", strings.ToUpper(fr.Field))
+			}
+			fmt.Fprintln(tw, "COUNT")
+		}
+		for _, gc := range result {
+			for _, fr := range gc.Group {
+				if fr.RowKey != "" {
+					fmt.Fprintf(tw, "%s\t", fr.RowKey)
+				} else {
+					fmt.Fprintf(tw, "%d\t", fr.RowID)
+				}
+			}
+			fmt.Fprintf(tw, "%d\n", gc.Count)
+		}
+	default:
+		buf, err := json.Marshal(result)
+		if err != nil {
+			return errors.Wrap(err, "encoding result")
+		}
+		fmt.Fprintf(tw, "%s\n", buf)
+	}
+	return tw.Flush()
+}
+
+// printShellPairs prints the pairs returned by TopN and similar calls.
+func printShellPairs(w io.Writer, pairs []pilosa.Pair) {
+	fmt.Fprintln(w, "ROW\tCOUNT")
+	for _, p := range pairs {
+		if p.Key != "" {
+			fmt.Fprintf(w, "%s\t%d\n", p.Key, p.Count)
+		} else {
+			fmt.Fprintf(w, "%d\t%d\n", p.ID, p.Count)
+		}
+	}
+}
+
+// appendHistory adds query to the history file, on a single line.
+func (cmd *ShellCommand) appendHistory(query string) error {
+	if cmd.HistoryPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(cmd.HistoryPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "opening history")
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, strings.Join(strings.Fields(query), " ")); err != nil {
+		return errors.Wrap(err, "writing history")
+	}
+	return nil
+}
+
+// refreshSchema reads the indexes and fields used by completion.
+func (cmd *ShellCommand) refreshSchema(ctx context.Context) error {
+	schema, err := cmd.client.Schema(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting schema")
+	}
+	cmd.schema = schema
+	return nil
+}
+
+// findIndex returns the index named name from the schema, or nil.
+func (cmd *ShellCommand) findIndex(name string) *pilosa.IndexInfo {
+	for _, ii := range cmd.schema {
+		if ii.Name == name {
+			return ii
+		}
+	}
+	return nil
+}
+
+// complete is the terminal's completion callback. On tab, it completes the
+// word before the cursor with an index name after \c and \d, and with a PQL
+// call or a field of the current index otherwise.
+func (cmd *ShellCommand) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	var candidates []string
+	if fields := strings.Fields(line[:pos]); len(fields) > 0 && strings.HasPrefix(fields[0], `\`) {
+		for _, ii := range cmd.schema {
+			candidates = append(candidates, ii.Name)
+		}
+	} else {
+		candidates = append(candidates, pqlCalls...)
+		if ii := cmd.findIndex(cmd.Index); ii != nil {
+			for _, fi := range ii.Fields {
+				candidates = append(candidates, fi.Name)
+			}
+		}
+	}
+	return completeWord(line, pos, candidates)
+}
+
+// completeWord extends the word which ends at pos with the longest prefix
+// shared by the candidates it starts.
+func completeWord(line string, pos int, candidates []string) (string, int, bool) {
+	start := pos
+	for start > 0 && isIdentByte(line[start-1]) {
+		start--
+	}
+	word := line[start:pos]
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	sort.Strings(matches)
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) == len(word) {
+		return "", 0, false
+	}
+	return line[:start] + prefix + line[pos:], start + len(prefix), true
+}
+
+// isIdentByte returns true if c may be part of an index, field or call name.
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// pqlComplete returns true if the parentheses of query are balanced, outside
+// of quoted strings.
+func pqlComplete(query string) bool {
+	var depth int
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+	}
+	return depth <= 0 && quote == 0
+}
+
+// ANSI escape codes used by highlightPQL.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBlue   = "\x1b[34m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// highlightPQL colors the calls, strings and numbers of a query for display
+// on a terminal.
+func highlightPQL(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(query) && query[j] != c {
+				if query[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(query) {
+				j++
+			} else {
+				j = len(query)
+			}
+			b.WriteString(ansiGreen + query[i:j] + ansiReset)
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			switch word := query[i:j]; {
+			case j < len(query) && query[j] == '(':
+				b.WriteString(ansiBlue + word + ansiReset)
+			case c == '-' || c >= '0' && c <= '9':
+				b.WriteString(ansiYellow + word + ansiReset)
+			default:
+				b.WriteString(word)
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func (cmd *ShellCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *ShellCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *ShellCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
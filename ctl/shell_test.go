@@ -0,0 +1,135 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestShellCommand_Run(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1) Set(2, f=1) Set(3, f=2)")
+	cluster[0].MustRecalculateCaches(t) // for TopN
+
+	dir, err := ioutil.TempDir("", "pilosa-shell-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := strings.Join([]string{
+		`\d i`,
+		`\c i`,
+		`Count(`,
+		`  Row(f=1))`,
+		`TopN(f)`,
+		`\format json`,
+		`Row(f=2)`,
+		`Row(g=1)`,
+		`\q`,
+		`Count(Row(f=2))`,
+	}, "\n")
+	var stdout bytes.Buffer
+	cm := NewShellCommand(strings.NewReader(script), &stdout, ioutil.Discard)
+	cm.Host = cluster[0].API.Node().URI.HostPort()
+	cm.HistoryPath = filepath.Join(dir, "history")
+	if err := cm.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := stdout.String()
+	for _, exp := range []string{
+		"FIELD  TYPE  KEYS\nf      set   false\n",
+		"\n2\n",
+		"ROW  COUNT\n1    2\n2    1\n",
+		`"columns": [` + "\n" + `        3`,
+		"error: ",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Fatalf("expected %q in output:\n%s", exp, out)
+		}
+	}
+	// Nothing runs after \q.
+	if strings.Count(out, "error: ") != 1 {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	// Multi-line queries are saved on one line.
+	if buf, err := ioutil.ReadFile(cm.HistoryPath); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(string(buf), "Count( Row(f=1))\nTopN(f)\n") {
+		t.Fatalf("unexpected history:\n%s", buf)
+	}
+}
+
+func TestPQLComplete(t *testing.T) {
+	for query, exp := range map[string]bool{
+		"Row(f=1)":             true,
+		"Count(":               false,
+		"Count(\nRow(f=1))":    true,
+		`Row(f="(")`:           true,
+		`Set(1, f="a\")`:       false,
+		`Set(1, f="a\")(")`:    true,
+		"Row(f=1) Union(":      false,
+		"Row(f=1) Row(f=2)":    true,
+		"Union(Row(f=1), Row(": false,
+	} {
+		if got := pqlComplete(query); got != exp {
+			t.Errorf("pqlComplete(%q) = %v, expected %v", query, got, exp)
+		}
+	}
+}
+
+func TestCompleteWord(t *testing.T) {
+	candidates := []string{"Count", "Clear", "ClearRow", "Row", "field1", "field2"}
+	for _, tt := range []struct {
+		line    string
+		pos     int
+		expLine string
+		expPos  int
+		ok      bool
+	}{
+		{line: "Cou", pos: 3, expLine: "Count", expPos: 5, ok: true},
+		{line: "Cle", pos: 3, expLine: "Clear", expPos: 5, ok: true},
+		{line: "Count(Row(fi=1))", pos: 12, expLine: "Count(Row(field=1))", expPos: 15, ok: true},
+		{line: "C", pos: 1, ok: false},
+		{line: "Xor", pos: 3, ok: false},
+	} {
+		line, pos, ok := completeWord(tt.line, tt.pos, candidates)
+		if ok != tt.ok || line != tt.expLine || pos != tt.expPos {
+			t.Errorf("completeWord(%q, %d) = %q, %d, %v", tt.line, tt.pos, line, pos, ok)
+		}
+	}
+}
+
+func TestHighlightPQL(t *testing.T) {
+	got := highlightPQL(`Row(f="a")`)
+	exp := ansiBlue + "Row" + ansiReset + "(f=" + ansiGreen + `"a"` + ansiReset + ")"
+	if got != exp {
+		t.Fatalf("unexpected highlighting: %q", got)
+	}
+}
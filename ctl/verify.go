@@ -0,0 +1,263 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/http"
+	"github.com/pilosa/pilosa/v2/server"
+	"github.com/pkg/errors"
+)
+
+// VerifyCommand represents a command for checking that the replicas of each
+// fragment hold the same data.
+type VerifyCommand struct {
+	// Remote host and port.
+	Host string
+
+	// Names of the indexes to verify. All indexes if empty.
+	Indexes []string
+
+	// Repair runs anti-entropy for each fragment whose replicas differ.
+	Repair bool
+
+	// Standard input/output
+	*pilosa.CmdIO
+
+	TLS server.TLSConfig
+
+	// API key sent with every request, if set.
+	APIKey string
+}
+
+// NewVerifyCommand returns a new instance of VerifyCommand.
+func NewVerifyCommand(stdin io.Reader, stdout, stderr io.Writer) *VerifyCommand {
+	return &VerifyCommand{
+		CmdIO: pilosa.NewCmdIO(stdin, stdout, stderr),
+	}
+}
+
+// replicaDiff describes how the replicas of a fragment differ.
+type replicaDiff struct {
+	// missing holds the hosts which own the fragment but don't have it.
+	missing []string
+
+	// blocks holds the IDs of the blocks whose checksums differ between the
+	// hosts which have the fragment.
+	blocks []int
+}
+
+// empty returns true if the replicas match.
+func (d *replicaDiff) empty() bool {
+	return len(d.missing) == 0 && len(d.blocks) == 0
+}
+
+func (d *replicaDiff) String() string {
+	var a []string
+	if len(d.missing) > 0 {
+		a = append(a, "missing on "+strings.Join(d.missing, ", "))
+	}
+	if len(d.blocks) > 0 {
+		ids := make([]string, len(d.blocks))
+		for i, id := range d.blocks {
+			ids[i] = fmt.Sprint(id)
+		}
+		a = append(a, "blocks differ: "+strings.Join(ids, ", "))
+	}
+	return strings.Join(a, "; ")
+}
+
+// Run compares the block checksums of each fragment on every node which
+// owns it, and prints the fragments whose replicas differ. It returns an
+// error if any still differ when it is done.
+func (cmd *VerifyCommand) Run(ctx context.Context) error {
+	logger := cmd.Logger()
+
+	// Create a client to the server.
+	client, err := commandClient(cmd)
+	if err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+
+	schema, err := client.SchemaWithViews(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting schema")
+	}
+	indexes, err := selectIndexes(schema, cmd.Indexes)
+	if err != nil {
+		return err
+	}
+
+	maxShards, err := client.MaxShardByIndex(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting shard count")
+	}
+
+	var checked, differ, single int
+	for _, ii := range indexes {
+		for shard := uint64(0); shard <= maxShards[ii.Name]; shard++ {
+			nodes, err := client.FragmentNodes(ctx, ii.Name, shard)
+			if err != nil {
+				return errors.Wrap(err, "getting fragment nodes")
+			} else if len(nodes) < 2 {
+				single++
+				continue
+			}
+
+			for _, fi := range ii.Fields {
+				for _, vi := range fi.Views {
+					name := fmt.Sprintf("%s/%s/%s/%d", ii.Name, fi.Name, vi.Name, shard)
+					diff, ok, err := compareReplicas(ctx, client, nodes, ii.Name, fi.Name, vi.Name, shard)
+					if err != nil {
+						return errors.Wrapf(err, "comparing fragment %s", name)
+					} else if !ok {
+						continue
+					}
+					checked++
+					if diff.empty() {
+						continue
+					}
+					fmt.Fprintf(cmd.Stdout, "%s: %s\n", name, diff)
+					if !cmd.Repair {
+						differ++
+						continue
+					}
+
+					if diff, err = repairReplicas(ctx, client, nodes, diff, ii.Name, fi.Name, vi.Name, shard); err != nil {
+						return errors.Wrapf(err, "repairing fragment %s", name)
+					} else if !diff.empty() {
+						fmt.Fprintf(cmd.Stdout, "%s: still differs after repair: %s\n", name, diff)
+						differ++
+					} else {
+						fmt.Fprintf(cmd.Stdout, "%s: repaired\n", name)
+					}
+				}
+			}
+		}
+	}
+
+	if single > 0 {
+		logger.Printf("skipped %d shards with a single replica", single)
+	}
+	if differ > 0 {
+		return errors.Errorf("%d of %d fragments differ between replicas", differ, checked)
+	}
+	fmt.Fprintf(cmd.Stdout, "Verified %d fragments.\n", checked)
+	return nil
+}
+
+// repairReplicas runs anti-entropy for a fragment on one of the nodes which
+// has it, and returns how the replicas differ afterwards.
+func repairReplicas(ctx context.Context, client *http.InternalClient, nodes []*pilosa.Node, diff *replicaDiff, index, field, view string, shard uint64) (*replicaDiff, error) {
+	missing := make(map[string]bool, len(diff.missing))
+	for _, host := range diff.missing {
+		missing[host] = true
+	}
+	var node *pilosa.Node
+	for _, n := range nodes {
+		if !missing[n.URI.HostPort()] {
+			node = n
+			break
+		}
+	}
+	if err := client.SyncFragment(ctx, &node.URI, index, field, view, shard); err != nil {
+		return nil, errors.Wrapf(err, "syncing on %s", node.URI.HostPort())
+	}
+	diff, _, err := compareReplicas(ctx, client, nodes, index, field, view, shard)
+	return diff, err
+}
+
+// compareReplicas reads the blocks of a fragment from each node which owns
+// it. It returns false if no node has the fragment.
+func compareReplicas(ctx context.Context, client *http.InternalClient, nodes []*pilosa.Node, index, field, view string, shard uint64) (*replicaDiff, bool, error) {
+	diff := &replicaDiff{}
+	var blockSets [][]pilosa.FragmentBlock
+	for _, node := range nodes {
+		blocks, err := client.FragmentBlocks(ctx, &node.URI, index, field, view, shard)
+		if err == pilosa.ErrFragmentNotFound {
+			diff.missing = append(diff.missing, node.URI.HostPort())
+			continue
+		} else if err != nil {
+			return nil, false, errors.Wrapf(err, "getting blocks from %s", node.URI.HostPort())
+		}
+		blockSets = append(blockSets, blocks)
+	}
+	if len(blockSets) == 0 {
+		return diff, false, nil
+	}
+
+	diff.blocks = diffBlocks(blockSets)
+
+	// A replica without the fragment only matters if the others hold data.
+	empty := true
+	for _, blocks := range blockSets {
+		if len(blocks) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		diff.missing = nil
+	}
+	return diff, true, nil
+}
+
+// diffBlocks returns the IDs of the blocks whose checksums differ between the
+// block sets. A block missing from a set differs from one which is present.
+func diffBlocks(blockSets [][]pilosa.FragmentBlock) []int {
+	checksums := make(map[int][][]byte)
+	var ids []int
+	for i, blocks := range blockSets {
+		for _, blk := range blocks {
+			if _, ok := checksums[blk.ID]; !ok {
+				checksums[blk.ID] = make([][]byte, len(blockSets))
+				ids = append(ids, blk.ID)
+			}
+			checksums[blk.ID][i] = blk.Checksum
+		}
+	}
+
+	sort.Ints(ids)
+
+	var a []int
+	for _, id := range ids {
+		sums := checksums[id]
+		for _, sum := range sums[1:] {
+			if sums[0] == nil || sum == nil || !bytes.Equal(sums[0], sum) {
+				a = append(a, id)
+				break
+			}
+		}
+	}
+	return a
+}
+
+func (cmd *VerifyCommand) TLSHost() string {
+	return cmd.Host
+}
+
+func (cmd *VerifyCommand) TLSConfiguration() server.TLSConfig {
+	return cmd.TLS
+}
+
+func (cmd *VerifyCommand) AuthAPIKey() string {
+	return cmd.APIKey
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/test"
+)
+
+func TestVerifyCommand_Run(t *testing.T) {
+	cluster := test.MustNewCluster(t, 2)
+	for _, c := range cluster {
+		c.Config.Cluster.ReplicaN = 2
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "g")
+	cluster.Query(t, "i", "Set(1, f=1)")
+
+	// Write to one replica only: f differs, and g is missing from node1.
+	if _, err := cluster[1].Server.Holder().Field("i", "f").SetBit(2, 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster[0].Server.Holder().Field("i", "g").SetBit(1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(repair bool) (string, error) {
+		var buf bytes.Buffer
+		cm := NewVerifyCommand(nil, &buf, ioutil.Discard)
+		cm.Host = cluster[0].API.Node().URI.HostPort()
+		cm.Repair = repair
+		err := cm.Run(context.Background())
+		return buf.String(), err
+	}
+
+	out, err := run(false)
+	if err == nil || !strings.Contains(err.Error(), "2 of 2 fragments differ") {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	node1 := cluster[1].API.Node().URI.HostPort()
+	for _, exp := range []string{
+		"i/f/standard/0: blocks differ: 0\n",
+		"i/g/standard/0: missing on " + node1 + "\n",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Fatalf("expected %q in output:\n%s", exp, out)
+		}
+	}
+
+	if out, err = run(true); err != nil {
+		t.Fatal(err)
+	} else if strings.Count(out, ": repaired\n") != 2 {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	if out, err = run(false); err != nil {
+		t.Fatal(err)
+	} else if out != "Verified 2 fragments.\n" {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestDiffBlocks(t *testing.T) {
+	a := []pilosa.FragmentBlock{{ID: 0, Checksum: []byte("a")}, {ID: 2, Checksum: []byte("c")}, {ID: 3, Checksum: []byte("d")}}
+	b := []pilosa.FragmentBlock{{ID: 0, Checksum: []byte("a")}, {ID: 1, Checksum: []byte("b")}, {ID: 3, Checksum: []byte("x")}}
+	if got := diffBlocks([][]pilosa.FragmentBlock{a, b}); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("unexpected blocks: %v", got)
+	}
+	if got := diffBlocks([][]pilosa.FragmentBlock{a, a}); got != nil {
+		t.Fatalf("unexpected blocks: %v", got)
+	}
+}
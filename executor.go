@@ -18,7 +18,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +29,7 @@ import (
 	"github.com/pilosa/pilosa/v2/shardwidth"
 	"github.com/pilosa/pilosa/v2/tracing"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 // defaultField is the field used if one is not specified.
@@ -54,6 +58,9 @@ type executor struct {
 	// Maximum number of Set() or Clear() commands per request.
 	MaxWritesPerRequest int
 
+	// Maximum duration of a single query. Zero means no limit.
+	QueryTimeout time.Duration
+
 	workersWG      sync.WaitGroup
 	workerPoolSize int
 	work           chan job
@@ -114,6 +121,14 @@ func (e *executor) Execute(ctx context.Context, index string, q *pql.Query, shar
 	span, ctx := tracing.StartSpanFromContext(ctx, "Executor.Execute")
 	defer span.Finish()
 
+	// Bound the query by the configured timeout. Cancelling the context
+	// also aborts any outstanding requests to remote nodes.
+	if e.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.QueryTimeout)
+		defer cancel()
+	}
+
 	resp := QueryResponse{}
 
 	// Check for query cancellation.
@@ -254,6 +269,11 @@ func (e *executor) execute(ctx context.Context, index string, q *pql.Query, shar
 		return e.executeBulkSetRowAttrs(ctx, index, q.Calls, opt)
 	}
 
+	// Read-only calls are independent of each other so run them concurrently.
+	if len(q.Calls) > 1 && hasOnlyReadCalls(q.Calls) {
+		return e.executeConcurrent(ctx, index, q.Calls, shards, opt)
+	}
+
 	// Execute each call serially.
 	results := make([]interface{}, 0, len(q.Calls))
 	for _, call := range q.Calls {
@@ -270,6 +290,27 @@ func (e *executor) execute(ctx context.Context, index string, q *pql.Query, shar
 	return results, nil
 }
 
+// executeConcurrent executes each call in its own goroutine and returns the
+// results in the order of calls.
+func (e *executor) executeConcurrent(ctx context.Context, index string, calls []*pql.Call, shards []uint64, opt *execOptions) ([]interface{}, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "Executor.executeConcurrent")
+	defer span.Finish()
+
+	results := make([]interface{}, len(calls))
+	eg, ctx := errgroup.WithContext(ctx)
+	for i, call := range calls {
+		i, call := i, call
+		eg.Go(func() (err error) {
+			results[i], err = e.executeCall(ctx, index, call, shards, opt)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 // executeCall executes a call.
 func (e *executor) executeCall(ctx context.Context, index string, c *pql.Call, shards []uint64, opt *execOptions) (interface{}, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx, "Executor.executeCall")
@@ -332,6 +373,9 @@ func (e *executor) executeCall(ctx context.Context, index string, c *pql.Call, s
 		return e.executeGroupBy(ctx, index, c, shards, opt)
 	case "Options":
 		return e.executeOptionsCall(ctx, index, c, shards, opt)
+	case "Limit":
+		e.Holder.Stats.CountWithCustomTags(c.Name, 1, 1.0, []string{indexTag})
+		return e.executeLimitCall(ctx, index, c, shards, opt)
 	default:
 		e.Holder.Stats.CountWithCustomTags(c.Name, 1, 1.0, []string{indexTag})
 		return e.executeBitmapCall(ctx, index, c, shards, opt)
@@ -647,6 +691,83 @@ func (e *executor) executeBitmapCall(ctx context.Context, index string, c *pql.C
 	return row, nil
 }
 
+// executeLimitCall executes a Limit() call. The child row is computed in full
+// and then the requested page of its columns is returned. With a cursor,
+// only columns after it are returned, and shards before it are not queried.
+func (e *executor) executeLimitCall(ctx context.Context, index string, c *pql.Call, shards []uint64, opt *execOptions) (*Row, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "Executor.executeLimitCall")
+	defer span.Finish()
+
+	if len(c.Children) != 1 {
+		return nil, errors.New("Limit() must specify exactly one row query")
+	}
+	limit, hasLimit, err := c.UintArg("limit")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting limit")
+	} else if !hasLimit {
+		limit = math.MaxUint64
+	}
+	offset, _, err := c.UintArg("offset")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting offset")
+	}
+	after, hasAfter, err := c.UintArg("after")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting after")
+	}
+
+	if hasAfter {
+		remaining := make([]uint64, 0, len(shards))
+		for _, shard := range shards {
+			if shard >= after/ShardWidth {
+				remaining = append(remaining, shard)
+			}
+		}
+		if len(remaining) == 0 {
+			return NewRow(), nil
+		}
+		shards = remaining
+	}
+
+	row, err := e.executeBitmapCall(ctx, index, c.Children[0], shards, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip whole segments while the offset allows, then collect the page.
+	other := &Row{Attrs: row.Attrs}
+	for _, seg := range row.Segments() {
+		if limit == 0 {
+			break
+		}
+
+		var columns []uint64
+		if hasAfter && seg.shard == after/ShardWidth {
+			columns = seg.Columns()
+			columns = columns[sort.Search(len(columns), func(i int) bool { return columns[i] > after }):]
+		} else if n := seg.Count(); offset >= n {
+			offset -= n
+			continue
+		} else {
+			columns = seg.Columns()
+		}
+		if n := uint64(len(columns)); offset >= n {
+			offset -= n
+			continue
+		}
+
+		for _, col := range columns[offset:] {
+			if limit == 0 {
+				break
+			}
+			other.SetBit(col)
+			limit--
+		}
+		offset = 0
+	}
+	return other, nil
+}
+
 // executeBitmapCallShard executes a bitmap call for a single shard.
 func (e *executor) executeBitmapCallShard(ctx context.Context, index string, c *pql.Call, shard uint64) (*Row, error) {
 	if err := validateQueryContext(ctx); err != nil {
@@ -1104,7 +1225,8 @@ func (e *executor) executeGroupBy(ctx context.Context, index string, c *pql.Call
 		if err != nil {
 			return nil, errors.Wrap(err, "getting column")
 		}
-		if hasLimit || hasCol { // we need to perform this query cluster-wide ahead of executeGroupByShard
+		_, hasLike := child.Args["like"]
+		if hasLimit || hasCol || hasLike { // we need to perform this query cluster-wide ahead of executeGroupByShard
 			childRows[i], err = e.executeRows(ctx, index, child, shards, opt)
 			if err != nil {
 				return nil, errors.Wrap(err, "getting rows for ")
@@ -1289,6 +1411,11 @@ func (e *executor) executeRows(ctx context.Context, index string, c *pql.Call, s
 		shards = []uint64{columnID / ShardWidth}
 	}
 
+	// A like pattern which matched no keys selects no rows.
+	if like, ok := c.Args["like"].([]uint64); ok && len(like) == 0 {
+		return RowIDs{}, nil
+	}
+
 	// Execute calls in bulk on each remote node and merge.
 	mapFn := func(shard uint64) (interface{}, error) {
 		return e.executeRowsShard(ctx, index, fieldName, c, shard)
@@ -1425,13 +1552,25 @@ func (e *executor) executeRowsShard(_ context.Context, index string, fieldName s
 		limit = int(lim)
 	}
 
+	// The like argument has been translated into a list of matching rows.
+	like, hasLike, err := c.UintSliceArg("like")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting like")
+	}
+
 	for _, view := range views {
 		frag := e.Holder.fragment(index, fieldName, view, shard)
 		if frag == nil {
 			continue
 		}
 
-		viewRows := frag.rows(start, filters...)
+		viewFilters := filters
+		if hasLike {
+			// filterWithRows keeps state, so each view needs its own.
+			viewFilters = append([]rowFilter{filterWithRows(like)}, filters...)
+		}
+
+		viewRows := frag.rows(start, viewFilters...)
 		rowIDs = rowIDs.merge(viewRows, limit)
 	}
 
@@ -1797,13 +1936,15 @@ func (e *executor) executeCount(ctx context.Context, index string, c *pql.Call,
 		return 0, errors.New("Count() only accepts a single bitmap input")
 	}
 
+	// executeCountShard may stop before reaching every operand, so check
+	// the fields they read here.
+	if err := e.validateFields(index, c.Children[0]); err != nil {
+		return 0, err
+	}
+
 	// Execute calls in bulk on each remote node and merge.
 	mapFn := func(shard uint64) (interface{}, error) {
-		row, err := e.executeBitmapCallShard(ctx, index, c.Children[0], shard)
-		if err != nil {
-			return 0, err
-		}
-		return row.Count(), nil
+		return e.executeCountShard(ctx, index, c.Children[0], shard)
 	}
 
 	// Merge returned results at coordinating node.
@@ -1821,6 +1962,66 @@ func (e *executor) executeCount(ctx context.Context, index string, c *pql.Call,
 	return n, nil
 }
 
+// validateFields returns an error if a Row() or Range() call in c reads a
+// field which does not exist, as executing the call would.
+func (e *executor) validateFields(index string, c *pql.Call) error {
+	if e.Holder.Index(index) == nil {
+		return newNotFoundError(ErrIndexNotFound, index)
+	}
+	var err error
+	c.Walk(func(c *pql.Call) bool {
+		if err != nil {
+			return false
+		} else if c.Name != "Row" && c.Name != "Range" {
+			return true
+		}
+		fieldName, ferr := c.FieldArg()
+		if ferr != nil {
+			err = fmt.Errorf("%s() argument required: field", c.Name)
+		} else if e.Holder.Field(index, fieldName) == nil {
+			err = newNotFoundError(ErrFieldNotFound, fieldName)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// executeCountShard returns the number of columns set in the result of c for
+// a single shard. An Intersect() input is counted directly from its last two
+// operands so that the final intersection is never materialized, and evaluation
+// stops early once an operand is empty.
+func (e *executor) executeCountShard(ctx context.Context, index string, c *pql.Call, shard uint64) (uint64, error) {
+	if c.Name != "Intersect" || len(c.Children) < 2 {
+		row, err := e.executeBitmapCallShard(ctx, index, c, shard)
+		if err != nil {
+			return 0, err
+		}
+		return row.Count(), nil
+	}
+
+	var other *Row
+	for i, input := range c.Children {
+		row, err := e.executeBitmapCallShard(ctx, index, input, shard)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case i == 0:
+			other = row
+		case i == len(c.Children)-1:
+			return other.intersectionCount(row), nil
+		default:
+			other = other.Intersect(row)
+		}
+
+		if !other.Any() {
+			return 0, nil
+		}
+	}
+	return 0, nil
+}
+
 // executeClearBit executes a Clear() call.
 func (e *executor) executeClearBit(ctx context.Context, index string, c *pql.Call, opt *execOptions) (bool, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx, "Executor.executeClearBit")
@@ -2484,7 +2685,7 @@ func (e *executor) mapReduce(ctx context.Context, index string, shards []uint64,
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, errors.Wrap(ctx.Err(), "context done")
+			return nil, errors.Wrap(validateQueryContext(ctx), "context done")
 		case resp := <-ch:
 			// On error retry against remaining nodes. If an error returns then
 			// the context will cancel and cause all open goroutines to return.
@@ -2591,7 +2792,7 @@ func (e *executor) mapperLocal(ctx context.Context, shards []uint64, mapFn mapFu
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, validateQueryContext(ctx)
 		case resp := <-ch:
 			if resp.err != nil {
 				return nil, resp.err
@@ -2701,6 +2902,12 @@ func (e *executor) translateCall(index string, idx *Index, c *pql.Call) error {
 				return errors.New("string 'row' value not allowed unless field 'keys' option enabled")
 			}
 		}
+
+		if c.Name == "Rows" {
+			if err := translateRowsLike(field, c); err != nil {
+				return errors.Wrap(err, "translating like")
+			}
+		}
 	}
 
 	// Translate child calls.
@@ -2713,6 +2920,102 @@ func (e *executor) translateCall(index string, idx *Index, c *pql.Call) error {
 	return nil
 }
 
+// translateRowsLike replaces the like argument of a Rows() call, a pattern
+// in which '%' matches any sequence of characters and '_' matches a single
+// character, with the ascending list of row ids whose keys match it.
+//
+// If the translate store implements TranslateKeyScanner, only keys that
+// begin with the literal prefix of the pattern, the part before the first
+// wildcard, are read from it. Otherwise, and for a pattern that begins with
+// a wildcard, every key in the field is read.
+func translateRowsLike(field *Field, c *pql.Call) error {
+	arg, ok := c.Args["like"]
+	if !ok {
+		return nil
+	}
+	pattern, ok := arg.(string)
+	if !ok {
+		return errors.New("'like' value must be a string")
+	} else if !field.keys() {
+		return errors.New("'like' not allowed unless field 'keys' option enabled")
+	}
+
+	re, err := likeRegexp(pattern)
+	if err != nil {
+		return errors.Wrap(err, "compiling pattern")
+	}
+
+	prefix := pattern
+	if i := strings.IndexAny(pattern, "%_"); i >= 0 {
+		prefix = pattern[:i]
+	}
+	entries, err := keysWithPrefix(field.translateStore, prefix)
+	if err != nil {
+		return errors.Wrap(err, "reading keys")
+	}
+
+	rowIDs := make([]uint64, 0)
+	for _, entry := range entries {
+		if re.MatchString(entry.Key) {
+			rowIDs = append(rowIDs, entry.ID)
+		}
+	}
+	sort.Slice(rowIDs, func(i, j int) bool { return rowIDs[i] < rowIDs[j] })
+	c.Args["like"] = rowIDs
+	return nil
+}
+
+// keysWithPrefix returns the key/ID pairs of store whose keys begin with
+// prefix. Stores which do not implement TranslateKeyScanner are read in
+// full, in batches of IDs.
+func keysWithPrefix(store TranslateStore, prefix string) ([]TranslateEntry, error) {
+	if s, ok := store.(TranslateKeyScanner); ok {
+		return s.KeysWithPrefix(prefix)
+	}
+
+	maxID, err := store.MaxID()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting max id")
+	}
+	const batchSize = 1000
+	var entries []TranslateEntry
+	ids := make([]uint64, 0, batchSize)
+	for start := uint64(1); start <= maxID; start += batchSize {
+		ids = ids[:0]
+		for id := start; id <= maxID && id < start+batchSize; id++ {
+			ids = append(ids, id)
+		}
+		keys, err := store.TranslateIDs(ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "translating ids")
+		}
+		for i, key := range keys {
+			if key != "" && strings.HasPrefix(key, prefix) {
+				entries = append(entries, TranslateEntry{ID: ids[i], Key: key})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// likeRegexp converts a like pattern to an anchored regular expression.
+func likeRegexp(pattern string) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	buf.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			buf.WriteString(".*")
+		case '_':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
 func (e *executor) translateGroupByCall(index string, idx *Index, c *pql.Call) error {
 	if c.Name != "GroupBy" {
 		panic("translateGroupByCall called with '" + c.Name + "'")
@@ -2973,6 +3276,22 @@ func hasOnlySetRowAttrs(calls []*pql.Call) bool {
 	return true
 }
 
+// hasOnlyReadCalls returns true if calls, including nested calls, neither
+// write data nor change query options.
+func hasOnlyReadCalls(calls []*pql.Call) bool {
+	readOnly := true
+	for _, call := range calls {
+		call.Walk(func(c *pql.Call) bool {
+			switch c.Name {
+			case "Set", "Clear", "ClearRow", "Store", "SetRowAttrs", "SetColumnAttrs", "Options":
+				readOnly = false
+			}
+			return readOnly
+		})
+	}
+	return readOnly
+}
+
 func needsShards(calls []*pql.Call) bool {
 	if len(calls) == 0 {
 		return false
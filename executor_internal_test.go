@@ -195,6 +195,72 @@ func TestFilterWithRows(t *testing.T) {
 
 }
 
+func TestLikeRegexp(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"foo%", "foo", true},
+		{"foo%", "foobar", true},
+		{"foo%", "barfoo", false},
+		{"%bar", "foobar", true},
+		{"f_o", "foo", true},
+		{"f_o", "fooo", false},
+		{"a.c", "abc", false},
+		{"a.c", "a.c", true},
+		{"%", "", true},
+		{"", "a", false},
+	} {
+		re, err := likeRegexp(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		} else if match := re.MatchString(tt.key); match != tt.match {
+			t.Errorf("%q like %q: expected %v, got %v", tt.key, tt.pattern, tt.match, match)
+		}
+	}
+}
+
+// fullScanTranslateStore hides the TranslateKeyScanner implementation of
+// the store it wraps.
+type fullScanTranslateStore struct {
+	TranslateStore
+}
+
+func TestKeysWithPrefix(t *testing.T) {
+	s := NewInMemTranslateStore("i", "f")
+	for i := 0; i < 1500; i++ {
+		if _, err := s.TranslateKey(fmt.Sprintf("k%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.TranslateKey("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{"k1", "k", "", "none"} {
+		exp, err := s.KeysWithPrefix(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := keysWithPrefix(fullScanTranslateStore{s}, prefix)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != len(exp) {
+			t.Fatalf("prefix %q: expected %d entries, got %d", prefix, len(exp), len(entries))
+		}
+		ids := make(map[uint64]string, len(exp))
+		for _, entry := range exp {
+			ids[entry.ID] = entry.Key
+		}
+		for _, entry := range entries {
+			if ids[entry.ID] != entry.Key {
+				t.Fatalf("prefix %q: unexpected entry %+v", prefix, entry)
+			}
+		}
+	}
+}
+
 func TestFieldRowMarshalJSON(t *testing.T) {
 	fr := FieldRow{
 		Field:  "blah",
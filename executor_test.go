@@ -36,6 +36,7 @@ import (
 	"github.com/pilosa/pilosa/v2/http"
 	"github.com/pilosa/pilosa/v2/server"
 	"github.com/pilosa/pilosa/v2/test"
+	"github.com/pilosa/pilosa/v2/toml"
 	"github.com/pkg/errors"
 )
 
@@ -466,6 +467,69 @@ func TestExecutor_Execute_Count(t *testing.T) {
 		}
 	})
 
+	t.Run("Intersect", func(t *testing.T) {
+		c := test.MustRunCluster(t, 1)
+		defer c.Close()
+		hldr := test.Holder{Holder: c[0].Server.Holder()}
+
+		hldr.SetBit("i", "f", 10, 1)
+		hldr.SetBit("i", "f", 10, 2)
+		hldr.SetBit("i", "f", 10, ShardWidth+1)
+		hldr.SetBit("i", "f", 10, ShardWidth+2)
+		hldr.SetBit("i", "f", 11, 2)
+		hldr.SetBit("i", "f", 11, ShardWidth+1)
+		hldr.SetBit("i", "f", 11, ShardWidth+2)
+		hldr.SetBit("i", "f", 12, ShardWidth+2)
+		hldr.SetBit("i", "f", 12, ShardWidth+3)
+
+		for _, tt := range []struct {
+			query string
+			n     uint64
+		}{
+			{query: `Count(Intersect(Row(f=10)))`, n: 4},
+			{query: `Count(Intersect(Row(f=10), Row(f=11)))`, n: 3},
+			{query: `Count(Intersect(Row(f=10), Row(f=11), Row(f=12)))`, n: 1},
+			{query: `Count(Intersect(Row(f=13), Row(f=10), Row(f=11)))`, n: 0},
+			{query: `Count(Intersect(Union(Row(f=10), Row(f=12)), Row(f=11)))`, n: 3},
+		} {
+			if res, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: tt.query}); err != nil {
+				t.Fatal(err)
+			} else if res.Results[0] != tt.n {
+				t.Fatalf("%s: unexpected n: %d", tt.query, res.Results[0])
+			}
+		}
+
+		// Operands after an empty one are still checked.
+		for _, query := range []string{
+			`Count(Intersect(Row(f=13), Row(g=10)))`,
+			`Count(Intersect(Row(f=13), Row(f=10), Union(Row(f=11), Row(g=10))))`,
+		} {
+			if _, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: query}); errors.Cause(err) != pilosa.ErrFieldNotFound {
+				t.Fatalf("%s: expected field not found error, got %v", query, err)
+			}
+		}
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		c := test.MustRunCluster(t, 1)
+		defer c.Close()
+		hldr := test.Holder{Holder: c[0].Server.Holder()}
+
+		hldr.SetBit("i", "f", 10, 1)
+		hldr.SetBit("i", "f", 11, 1)
+		hldr.SetBit("i", "f", 11, ShardWidth+1)
+		hldr.SetBit("i", "f", 12, 1)
+		hldr.SetBit("i", "f", 12, 2)
+		hldr.SetBit("i", "f", 12, ShardWidth+1)
+
+		res, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: `Count(Row(f=12)) Count(Row(f=10)) Count(Row(f=13)) Count(Row(f=11))`})
+		if err != nil {
+			t.Fatal(err)
+		} else if exp := []interface{}{uint64(3), uint64(1), uint64(0), uint64(2)}; !reflect.DeepEqual(res.Results, exp) {
+			t.Fatalf("unexpected results: %v", res.Results)
+		}
+	})
+
 	t.Run("RowIDColumnKey", func(t *testing.T) {
 		writeQuery := `
 			Set("three", f=10)
@@ -508,6 +572,46 @@ func TestExecutor_Execute_Count(t *testing.T) {
 
 }
 
+// Ensure a Limit() query can page through a row's columns.
+func TestExecutor_Execute_Limit(t *testing.T) {
+	c := test.MustRunCluster(t, 1)
+	defer c.Close()
+	hldr := test.Holder{Holder: c[0].Server.Holder()}
+	hldr.SetBit("i", "general", 10, 1)
+	hldr.SetBit("i", "general", 10, 2)
+	hldr.SetBit("i", "general", 10, ShardWidth+1)
+	hldr.SetBit("i", "general", 10, 2*ShardWidth+1)
+	hldr.SetBit("i", "general", 10, 2*ShardWidth+2)
+
+	for _, tt := range []struct {
+		query string
+		exp   []uint64
+	}{
+		{query: `Limit(Row(general=10), limit=2)`, exp: []uint64{1, 2}},
+		{query: `Limit(Row(general=10), limit=2, offset=2)`, exp: []uint64{ShardWidth + 1, 2*ShardWidth + 1}},
+		{query: `Limit(Row(general=10), offset=4)`, exp: []uint64{2*ShardWidth + 2}},
+		{query: `Limit(Row(general=10), limit=3, offset=1)`, exp: []uint64{2, ShardWidth + 1, 2*ShardWidth + 1}},
+		{query: `Limit(Row(general=10), offset=5)`, exp: []uint64{}},
+		{query: `Limit(Row(general=10), limit=0)`, exp: []uint64{}},
+		{query: `Limit(Row(general=10), limit=2, after=1)`, exp: []uint64{2, ShardWidth + 1}},
+		{query: `Limit(Row(general=10), limit=2, after=2)`, exp: []uint64{ShardWidth + 1, 2*ShardWidth + 1}},
+		{query: fmt.Sprintf(`Limit(Row(general=10), after=%d)`, ShardWidth), exp: []uint64{ShardWidth + 1, 2*ShardWidth + 1, 2*ShardWidth + 2}},
+		{query: fmt.Sprintf(`Limit(Row(general=10), limit=1, offset=1, after=%d)`, ShardWidth+1), exp: []uint64{2*ShardWidth + 2}},
+		{query: fmt.Sprintf(`Limit(Row(general=10), after=%d)`, 2*ShardWidth+2), exp: []uint64{}},
+		{query: fmt.Sprintf(`Limit(Row(general=10), after=%d)`, 5*ShardWidth), exp: []uint64{}},
+	} {
+		if res, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: tt.query}); err != nil {
+			t.Fatal(err)
+		} else if columns := res.Results[0].(*pilosa.Row).Columns(); !reflect.DeepEqual(columns, tt.exp) {
+			t.Fatalf("%s: unexpected columns: %+v", tt.query, columns)
+		}
+	}
+
+	if _, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: `Limit(limit=1)`}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 // Ensure a set query can be executed.
 func TestExecutor_Execute_Set(t *testing.T) {
 	t.Run("RowIDColumnID", func(t *testing.T) {
@@ -2525,6 +2629,22 @@ func TestExecutor_Execute_ErrMaxWritesPerRequest(t *testing.T) {
 	}
 }
 
+// Ensure executor cancels a query which exceeds the configured timeout.
+func TestExecutor_Execute_ErrQueryTimeout(t *testing.T) {
+	c := test.MustNewCluster(t, 1)
+	c[0].Config.QueryTimeout = toml.Duration(time.Nanosecond)
+	err := c.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	hldr := test.Holder{Holder: c[0].Server.Holder()}
+	hldr.MustCreateIndexIfNotExists("i", pilosa.IndexOptions{})
+	if _, err := c[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: `Count(All())`}); errors.Cause(err) != pilosa.ErrQueryTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Ensure SetColumnAttrs doesn't save `field` as an attribute
 func TestExecutor_SetColumnAttrs_ExcludeField(t *testing.T) {
 	c := test.MustRunCluster(t, 1)
@@ -3603,6 +3723,30 @@ func TestExecutor_Execute_Rows_Keys(t *testing.T) {
 			q:   `Rows(f, previous="1", limit=0, column="0")`,
 			exp: []string{},
 		},
+		{
+			q:   `Rows(f, like="1%")`,
+			exp: []string{"1", "10", "11", "12", "13", "14", "15", "16", "17", "18"},
+		},
+		{
+			q:   `Rows(f, like="1_")`,
+			exp: []string{"10", "11", "12", "13", "14", "15", "16", "17", "18"},
+		},
+		{
+			q:   `Rows(f, like="%5")`,
+			exp: []string{"5", "15"},
+		},
+		{
+			q:   `Rows(f, like="1%", previous="12", limit=2)`,
+			exp: []string{"13", "14"},
+		},
+		{
+			q:   `Rows(f, like="1%", column="3")`,
+			exp: []string{"1"},
+		},
+		{
+			q:   `Rows(f, like="x%")`,
+			exp: []string{},
+		},
 	}
 
 	for i, test := range tests {
@@ -90,6 +90,7 @@ type Field struct {
 	logger logger.Logger
 
 	snapshotQueue chan *fragment
+	storageConfig *StorageConfig
 
 	// Instantiates new translation store on open.
 	OpenTranslateStore OpenTranslateStoreFunc
@@ -858,6 +859,10 @@ func (f *Field) createViewIfNotExists(name string) (*view, error) {
 // createViewIfNotExistsBase returns the named view, creating it if necessary.
 // The returned bool indicates whether the view was created or not.
 func (f *Field) createViewIfNotExistsBase(name string) (*view, bool, error) {
+	if view := f.view(name); view != nil {
+		return view, false, nil
+	}
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -877,11 +882,12 @@ func (f *Field) createViewIfNotExistsBase(name string) (*view, bool, error) {
 
 func (f *Field) newView(path, name string) *view {
 	view := newView(path, f.index, f.name, name, f.options)
-	view.logger = f.logger
+	view.logger = f.logger.With("index", f.index).With("field", f.name).With("view", name)
 	view.rowAttrStore = f.rowAttrStore
 	view.stats = f.Stats
 	view.broadcaster = f.broadcaster
 	view.snapshotQueue = f.snapshotQueue
+	view.storageConfig = f.storageConfig
 	return view
 }
 
@@ -21,6 +21,7 @@ import (
 	"container/heap"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"hash"
 	"io"
@@ -142,6 +143,12 @@ type fragment struct {
 	// so that they can be mmapped and heap utilization can be kept low.
 	MaxOpN int
 
+	// Whether each write to the op log is synced to disk before it returns.
+	FsyncOps bool
+
+	// Whether the data file is read onto the heap instead of being mmapped.
+	DisableMmap bool
+
 	// Logger used for out-of-band log entries.
 	Logger logger.Logger
 
@@ -184,19 +191,19 @@ func (f *fragment) cachePath() string { return f.path + cacheExt }
 
 // newSnapshotQueue makes a new snapshot queue, of depth N, and spawns a
 // goroutine for it.
-func newSnapshotQueue(n int, w int, l logger.Logger) chan *fragment {
+func newSnapshotQueue(n int, w int) chan *fragment {
 	ch := make(chan *fragment, n)
 	for i := 0; i < w; i++ {
-		go snapshotQueueWorker(ch, l)
+		go snapshotQueueWorker(ch)
 	}
 	return ch
 }
 
-func snapshotQueueWorker(snapshotQueue chan *fragment, l logger.Logger) {
+func snapshotQueueWorker(snapshotQueue chan *fragment) {
 	for f := range snapshotQueue {
 		err := f.protectedSnapshot(true)
 		if err != nil {
-			l.Printf("snapshot error: %v", err)
+			f.Logger.Errorf("snapshot error: %v", err)
 		}
 		f.snapshotCond.Broadcast()
 	}
@@ -221,7 +228,7 @@ func (f *fragment) enqueueSnapshot() {
 			f.snapshotDelays++
 			f.snapshotDelayTime += time.Since(before)
 			if f.snapshotDelays >= 10 {
-				f.Logger.Printf("snapshotting %s: last ten enqueue delays took %v", f.path, f.snapshotDelayTime)
+				f.Logger.Warnf("snapshotting: last ten enqueue delays took %v", f.snapshotDelayTime)
 				f.snapshotDelays = 0
 				f.snapshotDelayTime = 0
 			}
@@ -231,7 +238,7 @@ func (f *fragment) enqueueSnapshot() {
 		// to handle these snapshots.
 		err := f.snapshot()
 		if err != nil {
-			f.Logger.Printf("snapshot failed: %v", err)
+			f.Logger.Errorf("snapshot failed: %v", err)
 		}
 		f.snapshotting = false
 		f.snapshotCond.Broadcast()
@@ -245,13 +252,13 @@ func (f *fragment) Open() error {
 
 	if err := func() error {
 		// Initialize storage in a function so we can close if anything goes wrong.
-		f.Logger.Debugf("open storage for index/field/view/fragment: %s/%s/%s/%d", f.index, f.field, f.view, f.shard)
+		f.Logger.Debugf("open storage")
 		if err := f.openStorage(true); err != nil {
 			return errors.Wrap(err, "opening storage")
 		}
 
 		// Fill cache with rows persisted to disk.
-		f.Logger.Debugf("open cache for index/field/view/fragment: %s/%s/%s/%d", f.index, f.field, f.view, f.shard)
+		f.Logger.Debugf("open cache")
 		if err := f.openCache(); err != nil {
 			return errors.Wrap(err, "opening cache")
 		}
@@ -268,7 +275,7 @@ func (f *fragment) Open() error {
 		return err
 	}
 
-	f.Logger.Debugf("successfully opened index/field/view/fragment: %s/%s/%s/%d", f.index, f.field, f.view, f.shard)
+	f.Logger.Debugf("successfully opened fragment")
 	return nil
 }
 
@@ -279,11 +286,32 @@ func (f *fragment) reopen() (mustClose bool, err error) {
 		if err != nil {
 			return mustClose, fmt.Errorf("open file: %s", err)
 		}
-		f.storage.OpWriter = f.file
+		f.storage.OpWriter = f.opLog()
 	}
 	return mustClose, nil
 }
 
+// opLog returns the writer to which the storage bitmap appends its ops.
+func (f *fragment) opLog() io.Writer {
+	if f.FsyncOps {
+		return &syncWriter{f.file}
+	}
+	return f.file
+}
+
+// syncWriter syncs a file to disk after every write to it.
+type syncWriter struct {
+	file *os.File
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.file.Sync()
+}
+
 // openStorage opens the storage bitmap. Usually you also want to read in
 // the storage, but in the case where we just wrote that file, such as
 // unprotectedWriteToFragment, we could also just... not. If we didn't
@@ -366,20 +394,25 @@ func (f *fragment) openStorage(unmarshalData bool) error {
 		unmarshalData = false
 		f.rowCache = &simpleCache{make(map[uint64]*Row)}
 	} else {
-		// Mmap the underlying file so it can be zero copied.
-		data, err = syswrap.Mmap(int(f.file.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
-		if err == syswrap.ErrMaxMapCountReached {
-			f.Logger.Debugf("maximum number of maps reached, reading file instead")
-			if unmarshalData {
-				data, err = ioutil.ReadAll(file)
-				if err != nil {
-					return errors.Wrap(err, "failure file readall")
-				}
+		// Mmap the underlying file so it can be zero copied, unless mmap
+		// is disabled.
+		mapped := false
+		if !f.DisableMmap {
+			data, err = syswrap.Mmap(int(f.file.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+			if err == syswrap.ErrMaxMapCountReached {
+				f.Logger.Debugf("maximum number of maps reached, reading file instead")
+			} else if err != nil {
+				return errors.Wrap(err, "mmap failed")
+			} else {
+				newStorageData = data
+				mapped = true
+			}
+		}
+		if !mapped && unmarshalData {
+			data, err = ioutil.ReadAll(file)
+			if err != nil {
+				return errors.Wrap(err, "failure file readall")
 			}
-		} else if err != nil {
-			return errors.Wrap(err, "mmap failed")
-		} else {
-			newStorageData = data
 		}
 	}
 
@@ -395,7 +428,7 @@ func (f *fragment) openStorage(unmarshalData bool) error {
 			defer func() {
 				unmapErr := syswrap.Munmap(oldStorageData)
 				if unmapErr != nil {
-					f.Logger.Printf("unmap of old storage failed: %s", err)
+					f.Logger.Errorf("unmap of old storage failed: %s", unmapErr)
 				}
 			}()
 		}
@@ -428,7 +461,7 @@ func (f *fragment) openStorage(unmarshalData bool) error {
 		if oldStorageData != nil {
 			unmapErr := syswrap.Munmap(oldStorageData)
 			if unmapErr != nil {
-				f.Logger.Printf("unmap of old storage failed: %s", err)
+				f.Logger.Errorf("unmap of old storage failed: %s", unmapErr)
 			}
 		}
 		if mappedAny {
@@ -452,7 +485,7 @@ func (f *fragment) openStorage(unmarshalData bool) error {
 	}
 
 	// Attach the file to the bitmap to act as a write-ahead log.
-	f.storage.OpWriter = f.file
+	f.storage.OpWriter = f.opLog()
 
 	return lastError
 }
@@ -484,7 +517,7 @@ func (f *fragment) openCache() error {
 	// Unmarshal cache data.
 	var pb internal.Cache
 	if err := proto.Unmarshal(buf, &pb); err != nil {
-		f.Logger.Printf("error unmarshaling cache data, skipping: path=%s, err=%s", path, err)
+		f.Logger.With("path", path).Warnf("error unmarshaling cache data, skipping: err=%s", err)
 		return nil
 	}
 
@@ -531,13 +564,13 @@ func (f *fragment) unprotectedAwaitSnapshot() {
 func (f *fragment) close() error {
 	// Flush cache if closing gracefully.
 	if err := f.flushCache(); err != nil {
-		f.Logger.Printf("fragment: error flushing cache on close: err=%s, path=%s", err, f.path)
+		f.Logger.Errorf("fragment: error flushing cache on close: err=%s", err)
 		return errors.Wrap(err, "flushing cache")
 	}
 
 	// Close underlying storage.
 	if err := f.closeStorage(true); err != nil {
-		f.Logger.Printf("fragment: error closing storage: err=%s, path=%s", err, f.path)
+		f.Logger.Errorf("fragment: error closing storage: err=%s", err)
 		return errors.Wrap(err, "closing storage")
 	}
 
@@ -739,6 +772,31 @@ func (f *fragment) clearBit(rowID, columnID uint64) (bool, error) {
 	return f.unprotectedClearBit(rowID, columnID)
 }
 
+// clearColumn clears every bit set for columnID within the fragment and
+// returns the number of bits cleared.
+func (f *fragment) clearColumn(columnID uint64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	mustClose, err := f.reopen()
+	if err != nil {
+		return 0, errors.Wrap(err, "reopening")
+	}
+	if mustClose {
+		defer f.safeClose()
+	}
+
+	var n int
+	for _, rowID := range f.unprotectedRows(0, filterColumn(columnID)) {
+		changed, err := f.unprotectedClearBit(rowID, columnID)
+		if err != nil {
+			return n, errors.Wrapf(err, "clearing row %d", rowID)
+		} else if changed {
+			n++
+		}
+	}
+	return n, nil
+}
+
 // unprotectedClearBit TODO should be replaced by an invocation of
 // importPositions with a single bit to clear.
 func (f *fragment) unprotectedClearBit(rowID, columnID uint64) (changed bool, err error) {
@@ -2345,7 +2403,7 @@ func (f *fragment) snapshot() error {
 // unprotectedWriteToFragment writes the fragment f with bm as the data. It is unprotected, and
 // f.mu must be locked when calling it.
 func unprotectedWriteToFragment(f *fragment, bm *roaring.Bitmap) (n int64, err error) { // nolint: interfacer
-	completeMessage := fmt.Sprintf("fragment: snapshot complete %s/%s/%s/%d", f.index, f.field, f.view, f.shard)
+	completeMessage := "fragment: snapshot complete"
 	start := time.Now()
 	defer track(start, completeMessage, f.stats, f.Logger)
 
@@ -2367,6 +2425,12 @@ func unprotectedWriteToFragment(f *fragment, bm *roaring.Bitmap) (n int64, err e
 		return n, fmt.Errorf("flush: %s", err)
 	}
 
+	// Make sure the snapshot is on disk before it replaces the data file so
+	// a crash cannot leave a truncated fragment behind.
+	if err := file.Sync(); err != nil {
+		return n, fmt.Errorf("sync snapshot: %s", err)
+	}
+
 	// Close current storage.
 	if err := f.closeStorage(false); err != nil {
 		return n, fmt.Errorf("close storage: %s", err)
@@ -2432,6 +2496,22 @@ func (f *fragment) flushCache() error {
 	return nil
 }
 
+// fragmentArchiveVersion is the version of the fragment archives written by
+// WriteTo. It changes whenever ReadFrom could not read an archive correctly
+// without knowing the difference.
+const fragmentArchiveVersion = 1
+
+// fragmentArchiveHeader is the first entry of a fragment archive. It
+// describes the archive and the fragment it was written from.
+type fragmentArchiveHeader struct {
+	Version    int    `json:"version"`
+	Index      string `json:"index"`
+	Field      string `json:"field"`
+	View       string `json:"view"`
+	Shard      uint64 `json:"shard"`
+	ShardWidth uint64 `json:"shardWidth"`
+}
+
 // WriteTo writes the fragment's data to w.
 func (f *fragment) WriteTo(w io.Writer) (n int64, err error) {
 	// Force cache flush.
@@ -2439,8 +2519,11 @@ func (f *fragment) WriteTo(w io.Writer) (n int64, err error) {
 		return 0, errors.Wrap(err, "flushing cache")
 	}
 
-	// Write out data and cache to a tar archive.
+	// Write out a header, data and cache to a tar archive.
 	tw := tar.NewWriter(w)
+	if err := f.writeHeaderToArchive(tw); err != nil {
+		return 0, fmt.Errorf("write header: %s", err)
+	}
 	if err := f.writeStorageToArchive(tw); err != nil {
 		return 0, fmt.Errorf("write storage: %s", err)
 	}
@@ -2450,6 +2533,33 @@ func (f *fragment) WriteTo(w io.Writer) (n int64, err error) {
 	return 0, nil
 }
 
+func (f *fragment) writeHeaderToArchive(tw *tar.Writer) error {
+	buf, err := json.Marshal(fragmentArchiveHeader{
+		Version:    fragmentArchiveVersion,
+		Index:      f.index,
+		Field:      f.field,
+		View:       f.view,
+		Shard:      f.shard,
+		ShardWidth: ShardWidth,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshalling")
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    "header",
+		Mode:    0600,
+		Size:    int64(len(buf)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return errors.Wrap(err, "writing header")
+	}
+	if _, err := tw.Write(buf); err != nil {
+		return errors.Wrap(err, "writing")
+	}
+	return nil
+}
+
 func (f *fragment) writeStorageToArchive(tw *tar.Writer) error {
 	// Open separate file descriptor to read from.
 	file, err := os.Open(f.path)
@@ -2523,39 +2633,78 @@ func (f *fragment) writeCacheToArchive(tw *tar.Writer) error {
 	return nil
 }
 
-// ReadFrom reads a data file from r and loads it into the fragment.
+// ReadFrom reads a data file from r and loads it into the fragment. Archives
+// written before the header entry was added are still accepted, but one
+// with a header of another version, or written with another shard width,
+// is rejected before anything is loaded.
 func (f *fragment) ReadFrom(r io.Reader) (n int64, err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	tr := tar.NewReader(r)
-	for {
+	for i := 0; ; i++ {
 		// Read next tar header.
 		hdr, err := tr.Next()
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return 0, errors.Wrap(err, "opening")
+			return 0, NewBadRequestError(errors.Wrap(err, "opening"))
 		}
 
 		// Process file based on file name.
 		switch hdr.Name {
+		case "header":
+			if i > 0 {
+				return 0, NewBadRequestError(errors.New("fragment archive header is not the first file"))
+			}
+			if err := readArchiveHeader(archiveReader{tr}); err != nil {
+				return 0, errors.Wrap(err, "reading header")
+			}
 		case "data":
-			if err := f.readStorageFromArchive(tr); err != nil {
+			if err := f.readStorageFromArchive(archiveReader{tr}); err != nil {
 				return 0, errors.Wrap(err, "reading storage")
 			}
 		case "cache":
-			if err := f.readCacheFromArchive(tr); err != nil {
+			if err := f.readCacheFromArchive(archiveReader{tr}); err != nil {
 				return 0, errors.Wrap(err, "reading cache")
 			}
 		default:
-			return 0, fmt.Errorf("invalid fragment archive file: %s", hdr.Name)
+			return 0, NewBadRequestError(fmt.Errorf("invalid fragment archive file: %s", hdr.Name))
 		}
 	}
 
 	return 0, nil
 }
 
+// archiveReader wraps errors reading a fragment archive in a
+// BadRequestError, so that a malformed archive can be told apart from a
+// failure writing local storage.
+type archiveReader struct {
+	r io.Reader
+}
+
+func (r archiveReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = NewBadRequestError(err)
+	}
+	return n, err
+}
+
+// readArchiveHeader returns an error if the archive header read from r is of
+// an unknown version or was written with another shard width.
+func readArchiveHeader(r io.Reader) error {
+	var hdr fragmentArchiveHeader
+	if err := json.NewDecoder(r).Decode(&hdr); err != nil {
+		return NewBadRequestError(errors.Wrap(err, "decoding"))
+	} else if hdr.Version != fragmentArchiveVersion {
+		return NewBadRequestError(errors.Errorf("unsupported fragment archive version %d, expected %d", hdr.Version, fragmentArchiveVersion))
+	} else if hdr.ShardWidth != ShardWidth {
+		return NewBadRequestError(errors.Errorf("fragment archive of %s/%s/%s/%d has shard width %d, expected %d", hdr.Index, hdr.Field, hdr.View, hdr.Shard, hdr.ShardWidth, ShardWidth))
+	}
+	return nil
+}
+
 func (f *fragment) readStorageFromArchive(r io.Reader) error {
 	// Create a temporary file to copy into.
 	path := f.path + copyExt
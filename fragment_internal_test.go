@@ -15,8 +15,10 @@
 package pilosa
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -24,6 +26,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"sync/atomic"
@@ -105,6 +108,33 @@ func TestFragment_ClearBit(t *testing.T) {
 	}
 }
 
+// Ensure a fragment can clear a column from every row.
+func TestFragment_ClearColumn(t *testing.T) {
+	f := mustOpenFragment("i", "f", viewStandard, 0, "")
+	defer f.Clean(t)
+
+	for _, rowID := range []uint64{1, 2, 1000} {
+		if _, err := f.setBit(rowID, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.setBit(2, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := f.clearColumn(3); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("unexpected cleared count: %d", n)
+	}
+
+	if rows := f.rows(0, filterColumn(3)); len(rows) != 0 {
+		t.Fatalf("unexpected rows: %v", rows)
+	} else if cols := f.row(2).Columns(); !reflect.DeepEqual(cols, []uint64{4}) {
+		t.Fatalf("unexpected columns: %v", cols)
+	}
+}
+
 // What about rowcache timing.
 func TestFragment_RowcacheMap(t *testing.T) {
 	var done int64
@@ -926,6 +956,46 @@ func TestFragment_Snapshot(t *testing.T) {
 	}
 }
 
+// Ensure a fragment can sync every op and be read without mmap.
+func TestFragment_StorageConfig(t *testing.T) {
+	f := mustOpenFragment("i", "f", viewStandard, 0, "")
+	defer f.Clean(t)
+
+	f.FsyncOps = true
+	f.DisableMmap = true
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := f.storage.OpWriter.(*syncWriter); !ok {
+		t.Fatalf("unexpected op writer: %T", f.storage.OpWriter)
+	}
+
+	if _, err := f.setBit(1000, 1); err != nil {
+		t.Fatal(err)
+	} else if _, err := f.setBit(1000, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ops are read back from the file, which isn't mapped.
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	} else if f.storageData != nil {
+		t.Fatal("expected fragment not to be mmapped")
+	} else if n := f.row(1000).Count(); n != 2 {
+		t.Fatalf("unexpected count (reopen): %d", n)
+	}
+
+	// Snapshots are read the same way.
+	if err := f.Snapshot(); err != nil {
+		t.Fatal(err)
+	} else if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	} else if f.storageData != nil {
+		t.Fatal("expected fragment not to be mmapped")
+	} else if n := f.row(1000).Count(); n != 2 {
+		t.Fatalf("unexpected count (snapshot): %d", n)
+	}
+}
+
 // Ensure a fragment can iterate over all bits in order.
 func TestFragment_ForEachBit(t *testing.T) {
 	f := mustOpenFragment("i", "f", viewStandard, 0, "")
@@ -1408,6 +1478,143 @@ func TestFragment_WriteTo_ReadFrom(t *testing.T) {
 	}
 }
 
+// Ensure a malformed archive is reported as a bad request, and a failure
+// writing local storage is not.
+func TestFragment_ReadFrom_Errors(t *testing.T) {
+	f0 := mustOpenFragment("i", "f", viewStandard, 0, "")
+	defer f0.Clean(t)
+	if _, err := f0.setBit(1000, 2); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if _, err := f0.WriteTo(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	var unknown bytes.Buffer
+	tw := tar.NewWriter(&unknown)
+	if err := tw.WriteHeader(&tar.Header{Name: "foo", Mode: 0666, Size: 0}); err != nil {
+		t.Fatal(err)
+	} else if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f1 := mustOpenFragment("i", "f", viewStandard, 0, "")
+	defer f1.Clean(t)
+	for name, data := range map[string][]byte{
+		"Garbage":        []byte("garbage"),
+		"Truncated":      archive.Bytes()[:520],
+		"TruncatedData":  archive.Bytes()[:1544],
+		"Unknown":        unknown.Bytes(),
+		"BadHeader":      mustArchive(t, "header", []byte("{")),
+		"UnknownVersion": mustArchive(t, "header", []byte(`{"version":99,"shardWidth":1048576}`)),
+		"ShardWidth":     mustArchive(t, "header", []byte(fmt.Sprintf(`{"version":%d,"shardWidth":%d}`, fragmentArchiveVersion, ShardWidth*2))),
+		"HeaderNotFirst": mustArchive(t, "foo", nil, "header", []byte("{}")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := f1.ReadFrom(bytes.NewReader(data))
+			if _, ok := errors.Cause(err).(BadRequestError); !ok {
+				t.Fatalf("expected bad request error, got %#v", err)
+			}
+		})
+	}
+
+	t.Run("Storage", func(t *testing.T) {
+		path := f1.path
+		f1.path = filepath.Join(path, "nosuchdir", "0")
+		defer func() { f1.path = path }()
+
+		_, err := f1.ReadFrom(bytes.NewReader(archive.Bytes()))
+		if err == nil {
+			t.Fatal("expected error")
+		} else if _, ok := errors.Cause(err).(BadRequestError); ok {
+			t.Fatalf("unexpected bad request error: %v", err)
+		}
+	})
+}
+
+// Ensure a fragment archive starts with a header describing the fragment and
+// that archives without one are still read.
+func TestFragment_WriteTo_Header(t *testing.T) {
+	f0 := mustOpenFragment("i", "f", viewStandard, 3, "")
+	defer f0.Clean(t)
+	if _, err := f0.setBit(7, 3*ShardWidth+1); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if _, err := f0.WriteTo(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(archive.Bytes()))
+	if hdr, err := tr.Next(); err != nil {
+		t.Fatal(err)
+	} else if hdr.Name != "header" {
+		t.Fatalf("unexpected first file: %q", hdr.Name)
+	}
+	var hdr fragmentArchiveHeader
+	if err := json.NewDecoder(tr).Decode(&hdr); err != nil {
+		t.Fatal(err)
+	} else if exp := (fragmentArchiveHeader{
+		Version:    fragmentArchiveVersion,
+		Index:      "i",
+		Field:      "f",
+		View:       viewStandard,
+		Shard:      3,
+		ShardWidth: ShardWidth,
+	}); hdr != exp {
+		t.Fatalf("unexpected header: %#v", hdr)
+	}
+
+	// Rewrite the archive without its header.
+	var legacy bytes.Buffer
+	tw := tar.NewWriter(&legacy)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		} else if _, err := io.Copy(tw, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f1 := mustOpenFragment("i", "f", viewStandard, 3, "")
+	defer f1.Clean(t)
+	if _, err := f1.ReadFrom(&legacy); err != nil {
+		t.Fatal(err)
+	} else if n := f1.row(7).Count(); n != 1 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+}
+
+// mustArchive returns a tar archive of the given pairs of file names and
+// contents.
+func mustArchive(t *testing.T, files ...interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		data, _ := files[i+1].([]byte)
+		if err := tw.WriteHeader(&tar.Header{Name: files[i].(string), Mode: 0666, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		} else if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
 func BenchmarkFragment_Blocks(b *testing.B) {
 	if *FragmentPath == "" {
 		b.Skip("no fragment specified")
@@ -2579,7 +2786,7 @@ func mustOpenFragmentFlags(index, field, view string, shard uint64, cacheType st
 	f.RowAttrStore = &memAttrStore{
 		store: make(map[uint64]map[string]interface{}),
 	}
-	f.snapshotQueue = newSnapshotQueue(1, 1, nil)
+	f.snapshotQueue = newSnapshotQueue(1, 1)
 
 	if err := f.Open(); err != nil {
 		panic(err)
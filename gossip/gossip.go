@@ -101,6 +101,24 @@ func (g *memberSet) Open() (err error) {
 	return nil
 }
 
+// Ready returns an error unless the member set has been opened and is still
+// an alive member of the gossip cluster. A member set which has been closed
+// has left the cluster and shut down its transport.
+func (g *memberSet) Ready() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.memberlist == nil {
+		return errors.New("gossip has not been started")
+	}
+	name := g.memberlist.LocalNode().Name
+	for _, m := range g.memberlist.Members() {
+		if m.Name == name {
+			return nil
+		}
+	}
+	return errors.New("node is no longer a gossip member")
+}
+
 // Close attempts to gracefully leave the cluster, and finally calls shutdown
 // after (at most) a timeout period.
 func (g *memberSet) Close() error {
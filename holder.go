@@ -46,6 +46,27 @@ const (
 	existenceFieldName = "_exists"
 )
 
+// StorageConfig holds options for tuning how fragments are stored.
+type StorageConfig struct {
+	// FsyncOps syncs the op log of a fragment to disk after every write,
+	// instead of only when the fragment is snapshotted or closed.
+	FsyncOps bool
+
+	// MaxOpN is the number of operations in the op log of a fragment after
+	// which it is snapshotted.
+	MaxOpN int
+
+	// DisableMmap reads fragment files onto the heap instead of mmapping them.
+	DisableMmap bool
+}
+
+// NewStorageConfig returns a StorageConfig with default options.
+func NewStorageConfig() *StorageConfig {
+	return &StorageConfig{
+		MaxOpN: defaultFragmentMaxOpN,
+	}
+}
+
 // Holder represents a container for indexes.
 type Holder struct {
 	mu sync.RWMutex
@@ -77,6 +98,9 @@ type Holder struct {
 
 	snapshotQueue chan *fragment
 
+	// Tuning options passed down to each fragment.
+	storageConfig *StorageConfig
+
 	// Manages replication from the primary node.
 	primaryTranslateNode     *Node
 	translateStoreReplicator *holderTranslateStoreReplicator
@@ -129,6 +153,8 @@ func NewHolder() *Holder {
 
 		Logger: logger.NopLogger,
 
+		storageConfig: NewStorageConfig(),
+
 		OpenTranslateStore: OpenInMemTranslateStore,
 	}
 }
@@ -160,7 +186,7 @@ func (h *Holder) Open() error {
 	// Run snapshots asynchronously. The snapshotQueue will have a background
 	// task associated with it which flushes it and waits until this channel
 	// is closed, so we should always close this channel when done.
-	h.snapshotQueue = newSnapshotQueue(100, 2, h.Logger)
+	h.snapshotQueue = newSnapshotQueue(100, 2)
 
 	for _, fi := range fis {
 		// Skip files or hidden directories.
@@ -280,6 +306,24 @@ func (h *Holder) availableShardsByIndex() map[string]*roaring.Bitmap {
 	return m
 }
 
+// localShards returns, by index, the shards of which this node holds at
+// least one fragment.
+func (h *Holder) localShards() map[string][]uint64 {
+	m := make(map[string][]uint64)
+	for _, index := range h.Indexes() {
+		b := roaring.NewBitmap()
+		for _, field := range index.Fields() {
+			for _, view := range field.views() {
+				for _, frag := range view.allFragments() {
+					_, _ = b.Add(frag.shard) // ignore error, no writer attached
+				}
+			}
+		}
+		m[index.Name()] = b.Slice()
+	}
+	return m
+}
+
 // Schema returns schema information for all indexes, fields, and views.
 func (h *Holder) Schema() []*IndexInfo {
 	var a []*IndexInfo
@@ -300,8 +344,9 @@ func (h *Holder) Schema() []*IndexInfo {
 	return a
 }
 
-// limitedSchema returns schema information for all indexes and fields.
-func (h *Holder) limitedSchema() []*IndexInfo {
+// limitedSchema returns schema information for all indexes and fields, and
+// the views of each field if views is true.
+func (h *Holder) limitedSchema(views bool) []*IndexInfo {
 	var a []*IndexInfo
 	for _, index := range h.Indexes() {
 		di := &IndexInfo{
@@ -314,6 +359,12 @@ func (h *Holder) limitedSchema() []*IndexInfo {
 				continue
 			}
 			fi := &FieldInfo{Name: field.Name(), Options: field.Options()}
+			if views {
+				for _, view := range field.views() {
+					fi.Views = append(fi.Views, &ViewInfo{Name: view.name})
+				}
+				sort.Sort(viewInfoSlice(fi.Views))
+			}
 			di.Fields = append(di.Fields, fi)
 		}
 		sort.Sort(fieldInfoSlice(di.Fields))
@@ -390,6 +441,10 @@ func (h *Holder) CreateIndex(name string, opt IndexOptions) (*Index, error) {
 // CreateIndexIfNotExists returns an index by name.
 // The index is created if it does not already exist.
 func (h *Holder) CreateIndexIfNotExists(name string, opt IndexOptions) (*Index, error) {
+	if index := h.Index(name); index != nil {
+		return index, nil
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -442,6 +497,7 @@ func (h *Holder) newIndex(path, name string) (*Index, error) {
 	index.newAttrStore = h.NewAttrStore
 	index.columnAttrs = h.NewAttrStore(filepath.Join(index.path, ".data"))
 	index.snapshotQueue = h.snapshotQueue
+	index.storageConfig = h.storageConfig
 	index.holder = h
 	index.OpenTranslateStore = h.OpenTranslateStore
 	return index, nil
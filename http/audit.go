@@ -0,0 +1,180 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/pql"
+)
+
+// AuditEntry is a single line of the audit log.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remoteAddr"`
+	User       string    `json:"user,omitempty"`
+	UserAgent  string    `json:"userAgent"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+
+	// Remote is true if the request claimed to be forwarded by another
+	// node. Such requests are only recorded when that cannot be verified,
+	// because authentication is disabled or the caller did not use the
+	// node key.
+	Remote bool `json:"remote,omitempty"`
+}
+
+// OptHandlerAuditLog enables the audit log. One JSON object is written to w
+// for every request which changes the schema or data, and for every request
+// refused with 401 Unauthorized or 403 Forbidden.
+func OptHandlerAuditLog(w io.Writer) handlerOption {
+	return func(h *Handler) error {
+		h.auditLog = w
+		return nil
+	}
+}
+
+// auditRequests is middleware which records mutating requests in the audit
+// log. It runs before authentication, so that refused requests are recorded
+// too. Read-only requests are recorded only when they are refused. Requests
+// which nodes send to each other, or forward from a client, are not
+// recorded when they are authenticated with the node key, so that each
+// change is logged once, by the node which received it from a client.
+func (h *Handler) auditRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.auditLog == nil || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := AuditEntry{
+			Time:       time.Now().UTC(),
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Remote:     r.URL.Query().Get("remote") == "true",
+		}
+
+		mutating := isMutatingRequest(r)
+		authenticated, node := h.authenticator == nil, false
+		if h.authenticator != nil {
+			if id, err := h.authenticator.authenticate(r); err == nil {
+				authenticated, node = true, id.Node
+				entry.User = id.Subject
+			}
+		}
+		if node && (entry.Remote || strings.HasPrefix(r.URL.Path, "/internal/")) {
+			mutating = false
+		}
+
+		// Queries are sent with POST whether or not they write, so the query
+		// itself decides. Requests which cannot be read are left to the
+		// handler to reject, and the bodies of requests which will be
+		// refused are not read.
+		if mutating && authenticated && mux.CurrentRoute(r).GetName() == "PostQuery" {
+			req, err := h.peekQueryRequest(r)
+			if err != nil || !isWriteQuery(req.Query) {
+				mutating = false
+			} else {
+				entry.Query = req.Query
+				entry.Remote = entry.Remote || req.Remote
+				mutating = !(node && entry.Remote)
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		entry.Status = rec.status
+		if !mutating && rec.status != http.StatusUnauthorized && rec.status != http.StatusForbidden {
+			return
+		}
+
+		h.auditMu.Lock()
+		defer h.auditMu.Unlock()
+		if err := json.NewEncoder(h.auditLog).Encode(entry); err != nil {
+			h.logger.Printf("writing audit log: %s", err)
+		}
+	})
+}
+
+// isMutatingRequest returns true if r may change the schema or data.
+func isMutatingRequest(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+// peekQueryRequest parses the query request in r without consuming its body.
+func (h *Handler) peekQueryRequest(r *http.Request) (*pilosa.QueryRequest, error) {
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	other := *r
+	other.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	return h.readQueryRequest(&other)
+}
+
+// isWriteQuery returns true if the PQL in s contains a mutating call.
+func isWriteQuery(s string) bool {
+	q, err := pql.ParseString(s)
+	if err != nil {
+		return false
+	}
+	var write bool
+	for _, call := range q.Calls {
+		call.Walk(func(c *pql.Call) bool {
+			switch c.Name {
+			case "Set", "Clear", "ClearRow", "Store", "SetRowAttrs", "SetColumnAttrs":
+				write = true
+			}
+			return !write
+		})
+	}
+	return write
+}
+
+// statusRecorder records the status code written to a ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// APIKeyHeader is the header in which clients send an API key.
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator checks the credentials of incoming requests. A request is
+// accepted if it carries one of the API keys in the X-API-Key header, or a
+// JWT signed with the shared secret as a bearer token.
+type Authenticator struct {
+	// APIKeys maps each accepted API key to the roles it grants.
+	APIKeys map[string][]string
+
+	// JWTSecret is the key with which bearer tokens are signed, using
+	// HMAC-SHA256. Bearer tokens are refused if it is empty.
+	JWTSecret []byte
+
+	// RolesClaim is the JWT claim holding the token's roles, either a
+	// string or a list of strings. Defaults to "roles".
+	RolesClaim string
+
+	// NodeKey is the API key which nodes send to each other. Callers with
+	// it are granted the admin role, and are trusted to forward requests
+	// which other nodes have already audited and rate limited.
+	NodeKey string
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject is the "sub" claim of a JWT. For an API key it is "api-key:"
+	// followed by the start of the key's SHA-256 hash, so that callers with
+	// different keys can be told apart without recording the keys.
+	Subject string
+	Roles   []string
+
+	// Node is true if the caller authenticated with the node key.
+	Node bool
+}
+
+// NodeSubject is the subject of the identity of a node.
+const NodeSubject = "node"
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity of the caller, if the request was
+// authenticated.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// OptHandlerAuthenticator requires requests to carry credentials accepted by
+// a. Health, readiness and version checks and CORS preflight requests are
+// exempt.
+func OptHandlerAuthenticator(a *Authenticator) handlerOption {
+	return func(h *Handler) error {
+		h.authenticator = a
+		return nil
+	}
+}
+
+// authenticate returns the identity of the caller of r.
+func (a *Authenticator) authenticate(r *http.Request) (*Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		if a.NodeKey != "" && subtle.ConstantTimeCompare([]byte(a.NodeKey), []byte(key)) == 1 {
+			return &Identity{Subject: NodeSubject, Roles: []string{AdminRole}, Node: true}, nil
+		}
+		for k, roles := range a.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return &Identity{Subject: apiKeySubject(k), Roles: roles}, nil
+			}
+		}
+		return nil, errors.New("invalid API key")
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errors.New("missing credentials")
+	}
+	if len(a.JWTSecret) == 0 {
+		return nil, errors.New("bearer tokens are not accepted")
+	}
+	return a.parseJWT(strings.TrimPrefix(auth, "Bearer "), time.Now())
+}
+
+// apiKeySubject returns the subject of the identity for an API key.
+func apiKeySubject(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:6])
+}
+
+// parseJWT verifies an HS256 signed JWT and returns the identity it carries.
+func (a *Authenticator) parseJWT(token string, now time.Time) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "decoding token header")
+	} else if header.Alg != "HS256" {
+		return nil, errors.Errorf("unsupported token algorithm: %q", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding token signature")
+	}
+	mac := hmac.New(sha256.New, a.JWTSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decoding token claims")
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return nil, errors.New("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	id := &Identity{}
+	id.Subject, _ = claims["sub"].(string)
+	rolesClaim := a.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	switch roles := claims[rolesClaim].(type) {
+	case string:
+		id.Roles = []string{roles}
+	case []interface{}:
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				id.Roles = append(id.Roles, s)
+			}
+		}
+	}
+	return id, nil
+}
+
+func decodeJWTPart(s string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// authenticateRequests is middleware which rejects requests without valid
+// credentials with 401 Unauthorized, and records the identity of the caller
+// in the request context otherwise.
+func (h *Handler) authenticateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.authenticator == nil || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !requiresCredentials(mux.CurrentRoute(r).GetName()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := h.authenticator.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+// requiresCredentials returns false for the routes which anyone may request
+// when authentication is enabled. Nodes probe /version without credentials
+// to confirm that a node which memberlist reports as gone is really down.
+func requiresCredentials(route string) bool {
+	switch route {
+	case "GetHealth", "GetReady", "GetVersion":
+		return false
+	}
+	return true
+}
+
+// fromNode returns true if r was authenticated with the node key. When
+// authentication is disabled, requests from nodes cannot be told apart from
+// those of clients, so this is always false.
+func fromNode(r *http.Request) bool {
+	id, ok := IdentityFromContext(r.Context())
+	return ok && id.Node
+}
+
+// apiKeyTransport adds an API key to every request it sends, so that nodes
+// can authenticate to each other.
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it was given.
+	other := *req
+	other.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		other.Header[k] = v
+	}
+	other.Header.Set(APIKeyHeader, t.key)
+	return t.base.RoundTrip(&other)
+}
+
+// WithAPIKey returns a copy of c which sends key with every request.
+func WithAPIKey(c *http.Client, key string) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	other := *c
+	other.Transport = &apiKeyTransport{key: key, base: base}
+	return &other
+}
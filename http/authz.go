@@ -0,0 +1,455 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/pql"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// Permission is the access a grant gives. Each permission includes the ones
+// before it.
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	PermissionAdmin Permission = "admin"
+)
+
+// rank orders permissions, returning zero for unknown ones.
+func (p Permission) rank() int {
+	switch p {
+	case PermissionRead:
+		return 1
+	case PermissionWrite:
+		return 2
+	case PermissionAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Grant gives a permission on an index, or on a single field of an index.
+type Grant struct {
+	// Index is the name of the index, or "*" for every index.
+	Index string `json:"index"`
+
+	// Field limits the grant to one field. Empty means every field.
+	Field string `json:"field,omitempty"`
+
+	Permission Permission `json:"permission"`
+}
+
+// AdminRole is granted admin on every index, whatever the role bindings say.
+const AdminRole = "admin"
+
+// Authorizer decides what authenticated callers may do, from the grants
+// bound to their roles. Permissions are only checked by the HTTP handler,
+// for each request it receives; the executor and the API do not check
+// them.
+type Authorizer struct {
+	mu    sync.RWMutex
+	roles map[string][]Grant
+
+	// path is the file to which role bindings are saved, if set.
+	path string
+}
+
+// NewAuthorizer returns an Authorizer with the given role bindings. If path
+// is set, role bindings saved there by an earlier SetRoles take precedence,
+// and later changes are saved there.
+func NewAuthorizer(roles map[string][]Grant, path string) (*Authorizer, error) {
+	if err := validateRoles(roles); err != nil {
+		return nil, err
+	}
+	a := &Authorizer{roles: roles, path: path}
+	if path == "" {
+		return a, nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading role bindings")
+	}
+	a.roles = nil
+	if err := json.Unmarshal(buf, &a.roles); err != nil {
+		return nil, errors.Wrap(err, "decoding role bindings")
+	}
+	return a, nil
+}
+
+// OptHandlerAuthorizer checks every authenticated request against the role
+// bindings of a. It has no effect unless an authenticator is also set.
+func OptHandlerAuthorizer(a *Authorizer) handlerOption {
+	return func(h *Handler) error {
+		h.authorizer = a
+		return nil
+	}
+}
+
+// OptHandlerInternalClient sets the client with which changes to role
+// bindings are sent to the other nodes of the cluster.
+func OptHandlerInternalClient(c *InternalClient) handlerOption {
+	return func(h *Handler) error {
+		h.client = c
+		return nil
+	}
+}
+
+// Roles returns the role bindings.
+func (a *Authorizer) Roles() map[string][]Grant {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.roles
+}
+
+// SetRoles replaces the role bindings, saving them if the Authorizer has a
+// path.
+func (a *Authorizer) SetRoles(roles map[string][]Grant) error {
+	if err := validateRoles(roles); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.path != "" {
+		buf, err := json.Marshal(roles)
+		if err != nil {
+			return errors.Wrap(err, "encoding role bindings")
+		}
+		tmp := a.path + ".tmp"
+		if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+			return errors.Wrap(err, "writing role bindings")
+		}
+		if err := os.Rename(tmp, a.path); err != nil {
+			return errors.Wrap(err, "renaming role bindings")
+		}
+		if err := syncDir(filepath.Dir(a.path)); err != nil {
+			return errors.Wrap(err, "syncing role bindings")
+		}
+	}
+	a.roles = roles
+	return nil
+}
+
+// validateRoles returns an error if a grant in roles has no index or an
+// unknown permission.
+func validateRoles(roles map[string][]Grant) error {
+	for role, grants := range roles {
+		for _, g := range grants {
+			if g.Index == "" {
+				return pilosa.NewBadRequestError(errors.Errorf("grant for role %s has no index", role))
+			} else if g.Permission.rank() == 0 {
+				return pilosa.NewBadRequestError(errors.Errorf("grant for role %s has invalid permission: %q", role, g.Permission))
+			}
+		}
+	}
+	return nil
+}
+
+// syncDir flushes the directory entries of dir to disk.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// allowed returns true if id has perm on field of index. An empty field
+// requires a grant on every field of the index, and an empty index a grant
+// on every index.
+func (a *Authorizer) allowed(id *Identity, index, field string, perm Permission) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, role := range id.Roles {
+		if role == AdminRole {
+			return true
+		}
+		for _, g := range a.roles[role] {
+			if g.Permission.rank() < perm.rank() {
+				continue
+			} else if g.Index != "*" && g.Index != index {
+				continue
+			} else if g.Field != "" && g.Field != field {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns true if id has perm on index, or on at least one of its
+// fields.
+func (a *Authorizer) visible(id *Identity, index string, perm Permission) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, role := range id.Roles {
+		if role == AdminRole {
+			return true
+		}
+		for _, g := range a.roles[role] {
+			if g.Permission.rank() >= perm.rank() && (g.Index == "*" || g.Index == index) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// visibleSchema returns the indexes of schema which the caller of r may
+// read, with only the fields it may read. Without authorization, every
+// index is returned.
+func (h *Handler) visibleSchema(r *http.Request, schema []*pilosa.IndexInfo) []*pilosa.IndexInfo {
+	id, ok := IdentityFromContext(r.Context())
+	if h.authorizer == nil || !ok {
+		return schema
+	}
+	indexes := make([]*pilosa.IndexInfo, 0, len(schema))
+	for _, ii := range schema {
+		if !h.authorizer.visible(id, ii.Name, PermissionRead) {
+			continue
+		}
+		other := *ii
+		other.Fields = make([]*pilosa.FieldInfo, 0, len(ii.Fields))
+		for _, fi := range ii.Fields {
+			if h.authorizer.allowed(id, ii.Name, fi.Name, PermissionRead) {
+				other.Fields = append(other.Fields, fi)
+			}
+		}
+		indexes = append(indexes, &other)
+	}
+	return indexes
+}
+
+// visibleShards returns the entries of maxShards whose index the caller of r
+// may read, or one of whose fields it may read.
+func (h *Handler) visibleShards(r *http.Request, maxShards map[string]uint64) map[string]uint64 {
+	id, ok := IdentityFromContext(r.Context())
+	if h.authorizer == nil || !ok {
+		return maxShards
+	}
+	m := make(map[string]uint64, len(maxShards))
+	for index, shard := range maxShards {
+		if h.authorizer.visible(id, index, PermissionRead) {
+			m[index] = shard
+		}
+	}
+	return m
+}
+
+// visibleLocalStatus returns status without the indexes which the caller of
+// r may not read, and none of whose fields it may read.
+func (h *Handler) visibleLocalStatus(r *http.Request, status *pilosa.LocalStatus) *pilosa.LocalStatus {
+	id, ok := IdentityFromContext(r.Context())
+	if h.authorizer == nil || !ok {
+		return status
+	}
+	other := &pilosa.LocalStatus{
+		Shards:           make(map[string][]uint64),
+		TranslateOffsets: make(pilosa.TranslateOffsetMap),
+	}
+	for index, shards := range status.Shards {
+		if h.authorizer.visible(id, index, PermissionRead) {
+			other.Shards[index] = shards
+		}
+	}
+	for index, offsets := range status.TranslateOffsets {
+		if h.authorizer.visible(id, index, PermissionRead) {
+			other.TranslateOffsets[index] = offsets
+		}
+	}
+	return other
+}
+
+// access is a permission needed on a field of an index.
+type access struct {
+	index, field string
+	perm         Permission
+}
+
+// requiredAccess returns the permissions a request needs. Routes which are
+// not listed need admin on every index.
+func (h *Handler) requiredAccess(r *http.Request) ([]access, error) {
+	vars := mux.Vars(r)
+	switch mux.CurrentRoute(r).GetName() {
+	case "Home", "GetSpec", "GetHealth", "GetReady", "GetVersion", "GetStatus", "GetInfo":
+		return nil, nil
+	case "GetSchema", "GetIndexes", "GetShardsMax", "GetLocalStatus":
+		// Any caller may list the indexes, but only sees those it can
+		// read. Import, export and backup need the number of shards, and
+		// cluster-status the shards on each node.
+		return nil, nil
+	case "GetIndex":
+		return []access{{index: vars["index"], perm: PermissionRead}}, nil
+	case "PostIndex", "DeleteIndex":
+		return []access{{index: vars["index"], perm: PermissionAdmin}}, nil
+	case "PostField", "DeleteField":
+		return []access{{index: vars["index"], field: vars["field"], perm: PermissionAdmin}}, nil
+	case "DeleteColumn":
+		return []access{{index: vars["index"], perm: PermissionWrite}}, nil
+	case "PostImport", "PostImportRoaring":
+		return []access{{index: vars["index"], field: vars["field"], perm: PermissionWrite}}, nil
+	case "GetExport", "GetFragmentData":
+		q := r.URL.Query()
+		return []access{{index: q.Get("index"), field: q.Get("field"), perm: PermissionRead}}, nil
+	case "GetFragmentNodes":
+		return []access{{index: r.URL.Query().Get("index"), perm: PermissionRead}}, nil
+	case "PostIndexAttrDiff", "PostFieldAttrDiff":
+		// These only read attributes; backup needs them.
+		return []access{{index: vars["index"], field: vars["field"], perm: PermissionRead}}, nil
+	case "PostQuery":
+		req, err := h.peekQueryRequest(r)
+		if err != nil {
+			return nil, err
+		}
+		q, err := pql.ParseString(req.Query)
+		if err != nil {
+			return nil, err
+		}
+		return queryAccess(vars["index"], q), nil
+	}
+	return []access{{perm: PermissionAdmin}}, nil
+}
+
+// queryAccess returns the permissions needed to run q against index.
+func queryAccess(index string, q *pql.Query) []access {
+	var a []access
+	for _, call := range q.Calls {
+		call.Walk(func(c *pql.Call) bool {
+			perm := PermissionRead
+			switch c.Name {
+			case "Set", "Clear", "ClearRow", "Store", "SetRowAttrs", "SetColumnAttrs":
+				perm = PermissionWrite
+			}
+
+			switch c.Name {
+			case "Count", "Intersect", "Union", "Difference", "Xor", "Shift", "Limit", "Options", "GroupBy":
+				// These only combine the results of their children.
+			case "Set", "Clear", "ClearRow", "Store", "Row", "Range":
+				// The executor reads the field from whichever argument is
+				// not reserved, so every such argument is checked. A call
+				// with none needs access to every field.
+				var found bool
+				for arg := range c.Args {
+					if !pql.IsReservedArg(arg) {
+						a = append(a, access{index: index, field: arg, perm: perm})
+						found = true
+					}
+				}
+				if !found {
+					a = append(a, access{index: index, perm: perm})
+				}
+			case "Sum", "Min", "Max", "MinRow", "MaxRow", "TopN", "Rows", "SetRowAttrs":
+				field, ok := c.Args["_field"].(string)
+				if !ok {
+					field, _ = c.Args["field"].(string)
+				}
+				a = append(a, access{index: index, field: field, perm: perm})
+			default:
+				// Calls such as All, Not and SetColumnAttrs see or change
+				// whole columns.
+				a = append(a, access{index: index, perm: perm})
+			}
+			return true
+		})
+	}
+	return a
+}
+
+// authorizeRequests is middleware which rejects requests with 403 Forbidden
+// unless the caller's roles grant the permissions they need.
+func (h *Handler) authorizeRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFromContext(r.Context())
+		if h.authorizer == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accesses, err := h.requiredAccess(r)
+		if err != nil {
+			// Leave malformed requests for the handler to reject, as long as
+			// the caller could run any query against the index.
+			accesses = []access{{index: mux.Vars(r)["index"], perm: PermissionWrite}}
+		}
+		for _, a := range accesses {
+			if !h.authorizer.allowed(id, a.index, a.field, a.perm) {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleGetRoles handles GET /auth/roles requests.
+func (h *Handler) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	if h.authorizer == nil {
+		http.Error(w, "authorization is not enabled", http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.authorizer.Roles()); err != nil {
+		h.logger.Printf("write roles response error: %s", err)
+	}
+}
+
+// handlePutRoles handles PUT /auth/roles requests. The role bindings are
+// replaced on this node, then sent to every other node of the cluster unless
+// the request was itself forwarded by a node.
+func (h *Handler) handlePutRoles(w http.ResponseWriter, r *http.Request) {
+	if h.authorizer == nil {
+		http.Error(w, "authorization is not enabled", http.StatusNotFound)
+		return
+	}
+	resp := successResponse{h: h}
+	var roles map[string][]Grant
+	if err := json.NewDecoder(r.Body).Decode(&roles); err != nil {
+		resp.write(w, pilosa.NewBadRequestError(errors.Wrap(err, "decoding role bindings")))
+		return
+	}
+	if err := h.authorizer.SetRoles(roles); err != nil {
+		resp.write(w, err)
+		return
+	}
+	if r.URL.Query().Get("remote") == "true" || h.client == nil {
+		resp.write(w, nil)
+		return
+	}
+
+	var eg errgroup.Group
+	for _, node := range pilosa.Nodes(h.api.Hosts(r.Context())).FilterID(h.api.Node().ID) {
+		node := node
+		eg.Go(func() error {
+			return errors.Wrapf(h.client.PutRoles(r.Context(), &node.URI, roles, true), "sending role bindings to %s", node.URI)
+		})
+	}
+	resp.write(w, eg.Wait())
+}
@@ -105,9 +105,20 @@ func (c *InternalClient) maxShardByIndex(ctx context.Context) (map[string]uint64
 func (c *InternalClient) Schema(ctx context.Context) ([]*pilosa.IndexInfo, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.Schema")
 	defer span.Finish()
+	return c.schema(ctx, "/schema")
+}
+
+// SchemaWithViews returns all index and field schema information, along with
+// the views of each field.
+func (c *InternalClient) SchemaWithViews(ctx context.Context) ([]*pilosa.IndexInfo, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.SchemaWithViews")
+	defer span.Finish()
+	return c.schema(ctx, "/schema?views=true")
+}
 
+func (c *InternalClient) schema(ctx context.Context, path string) ([]*pilosa.IndexInfo, error) {
 	// Execute request against the host.
-	u := c.defaultURI.Path("/schema")
+	u := c.defaultURI.Path(path)
 
 	// Build request.
 	req, err := http.NewRequest("GET", u, nil)
@@ -159,6 +170,33 @@ func (c *InternalClient) PostSchema(ctx context.Context, uri *pilosa.URI, s *pil
 	return nil
 }
 
+// PutRoles replaces the role bindings of the node at uri.
+func (c *InternalClient) PutRoles(ctx context.Context, uri *pilosa.URI, roles map[string][]Grant, remote bool) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.PutRoles")
+	defer span.Finish()
+
+	if uri == nil {
+		uri = c.defaultURI
+	}
+	buf, err := json.Marshal(roles)
+	if err != nil {
+		return errors.Wrap(err, "marshalling role bindings")
+	}
+	req, err := http.NewRequest("PUT", uri.Path(fmt.Sprintf("/auth/roles?remote=%v", remote)), bytes.NewReader(buf))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "executing request")
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
 // CreateIndex creates a new index on the server.
 func (c *InternalClient) CreateIndex(ctx context.Context, index string, opt pilosa.IndexOptions) error {
 	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.CreateIndex")
@@ -257,6 +295,140 @@ func (c *InternalClient) Nodes(ctx context.Context) ([]*pilosa.Node, error) {
 	return a, nil
 }
 
+// Status returns the state of the cluster and its nodes, as seen by the node
+// at uri.
+func (c *InternalClient) Status(ctx context.Context, uri *pilosa.URI) (string, []*pilosa.Node, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.Status")
+	defer span.Finish()
+
+	if uri == nil {
+		uri = c.defaultURI
+	}
+	u := uriPathToURL(uri, "/status")
+
+	// Build request.
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "creating request")
+	}
+
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+	req.Header.Set("Accept", "application/json")
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	var status getStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", nil, errors.Wrap(err, "json decode")
+	}
+	return status.State, status.Nodes, nil
+}
+
+// LocalStatus returns the shards and key translation offsets of the node at
+// uri itself.
+func (c *InternalClient) LocalStatus(ctx context.Context, uri *pilosa.URI) (*pilosa.LocalStatus, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.LocalStatus")
+	defer span.Finish()
+
+	if uri == nil {
+		uri = c.defaultURI
+	}
+	u := uriPathToURL(uri, "/internal/status")
+
+	// Build request.
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+	req.Header.Set("Accept", "application/json")
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status pilosa.LocalStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, errors.Wrap(err, "json decode")
+	}
+	return &status, nil
+}
+
+// Ready returns nil if the node at uri is ready to serve queries, and the
+// reason it is not otherwise.
+func (c *InternalClient) Ready(ctx context.Context, uri *pilosa.URI) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.Ready")
+	defer span.Finish()
+
+	if uri == nil {
+		uri = c.defaultURI
+	}
+	u := uriPathToURL(uri, "/ready")
+
+	// Build request.
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
+// RemoveNode asks the coordinator at uri to remove the node with the given
+// ID from the cluster. With force, the node is removed without moving its
+// data to the remaining nodes first.
+func (c *InternalClient) RemoveNode(ctx context.Context, uri *pilosa.URI, id string, force bool) (*pilosa.Node, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.RemoveNode")
+	defer span.Finish()
+
+	if uri == nil {
+		uri = c.defaultURI
+	}
+	u := uriPathToURL(uri, "/cluster/resize/remove-node")
+
+	buf, err := json.Marshal(removeNodeRequest{ID: id, Force: force})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling request")
+	}
+
+	// Build request.
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(buf))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rsp removeNodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rsp); err != nil {
+		return nil, errors.Wrap(err, "json decode")
+	}
+	return rsp.Remove, nil
+}
+
 // Query executes query against the index.
 func (c *InternalClient) Query(ctx context.Context, index string, queryRequest *pilosa.QueryRequest) (*pilosa.QueryResponse, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.Query")
@@ -667,6 +839,34 @@ func (c *InternalClient) ImportRoaring(ctx context.Context, uri *pilosa.URI, ind
 	return nil
 }
 
+// DeleteColumn clears a column from every field of an index and removes its
+// column attributes.
+func (c *InternalClient) DeleteColumn(ctx context.Context, uri *pilosa.URI, index string, columnID uint64, remote bool) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.DeleteColumn")
+	defer span.Finish()
+
+	if index == "" {
+		return pilosa.ErrIndexRequired
+	}
+	if uri == nil {
+		uri = c.defaultURI
+	}
+
+	u := uri.Path(fmt.Sprintf("/index/%s/column/%d?remote=%v", index, columnID, remote))
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "executing request")
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
 // ExportCSV bulk exports data for a single shard from a host to CSV format.
 func (c *InternalClient) ExportCSV(ctx context.Context, index, field string, shard uint64, w io.Writer) error {
 	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.ExportCSV")
@@ -775,6 +975,83 @@ func (c *InternalClient) RetrieveShardFromURI(ctx context.Context, index, field,
 	return resp.Body, nil
 }
 
+// ImportFragmentData replaces a single fragment on the host with data
+// previously retrieved with RetrieveShardFromURI.
+func (c *InternalClient) ImportFragmentData(ctx context.Context, uri *pilosa.URI, index, field, view string, shard uint64, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.ImportFragmentData")
+	defer span.Finish()
+
+	if index == "" {
+		return pilosa.ErrIndexRequired
+	} else if field == "" {
+		return pilosa.ErrFieldRequired
+	}
+	if uri == nil {
+		uri = c.defaultURI
+	}
+
+	u := uriPathToURL(uri, "/internal/fragment/data")
+	u.RawQuery = url.Values{
+		"index": {index},
+		"field": {field},
+		"view":  {view},
+		"shard": {strconv.FormatUint(shard, 10)},
+	}.Encode()
+
+	// Build request.
+	req, err := http.NewRequest("POST", u.String(), r)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
+// SyncFragment runs anti-entropy for a single fragment on the host, which
+// must own the fragment's shard.
+func (c *InternalClient) SyncFragment(ctx context.Context, uri *pilosa.URI, index, field, view string, shard uint64) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.SyncFragment")
+	defer span.Finish()
+
+	if index == "" {
+		return pilosa.ErrIndexRequired
+	} else if field == "" {
+		return pilosa.ErrFieldRequired
+	}
+	if uri == nil {
+		uri = c.defaultURI
+	}
+
+	u := uriPathToURL(uri, "/internal/fragment/sync")
+	u.RawQuery = url.Values{
+		"index": {index},
+		"field": {field},
+		"view":  {view},
+		"shard": {strconv.FormatUint(shard, 10)},
+	}.Encode()
+
+	// Build request.
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	// Execute request.
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
 func (c *InternalClient) CreateField(ctx context.Context, index, field string) error {
 	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.CreateField")
 	defer span.Finish()
@@ -805,10 +1082,30 @@ func (c *InternalClient) CreateFieldWithOptions(ctx context.Context, index, fiel
 		fieldOpt.TimeQuantum = &opt.TimeQuantum
 	}
 
+	return c.postField(ctx, index, field, fieldOpt)
+}
+
+// CreateFieldFromOptions creates a new field on the server, with options named
+// as in the HTTP API. The server fills in defaults for missing options.
+func (c *InternalClient) CreateFieldFromOptions(ctx context.Context, index, field string, options map[string]interface{}) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.CreateFieldFromOptions")
+	defer span.Finish()
+
+	if index == "" {
+		return pilosa.ErrIndexRequired
+	}
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return c.postField(ctx, index, field, options)
+}
+
+// postField sends a request to create a field with options.
+func (c *InternalClient) postField(ctx context.Context, index, field string, options interface{}) error {
 	// TODO: remove buf completely? (depends on whether importer needs to create specific field types)
 	// Encode query request.
-	buf, err := json.Marshal(&postFieldRequest{
-		Options: fieldOpt,
+	buf, err := json.Marshal(map[string]interface{}{
+		"options": options,
 	})
 	if err != nil {
 		return errors.Wrap(err, "marshaling")
@@ -837,6 +1134,37 @@ func (c *InternalClient) CreateFieldWithOptions(ctx context.Context, index, fiel
 	return errors.Wrap(resp.Body.Close(), "closing response body")
 }
 
+// DeleteIndex deletes an index, and all of its data.
+func (c *InternalClient) DeleteIndex(ctx context.Context, index string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.DeleteIndex")
+	defer span.Finish()
+	return c.deleteSchemaObject(ctx, fmt.Sprintf("/index/%s", index))
+}
+
+// DeleteField deletes a field of an index, and all of its data.
+func (c *InternalClient) DeleteField(ctx context.Context, index, field string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx, "InternalClient.DeleteField")
+	defer span.Finish()
+	return c.deleteSchemaObject(ctx, fmt.Sprintf("/index/%s/field/%s", index, field))
+}
+
+// deleteSchemaObject sends a DELETE request for the index or field at path.
+func (c *InternalClient) deleteSchemaObject(ctx context.Context, path string) error {
+	u := uriPathToURL(c.defaultURI, path)
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pilosa/"+pilosa.Version)
+
+	resp, err := c.executeRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.Body.Close(), "closing response body")
+}
+
 // FragmentBlocks returns a list of block checksums for a fragment on a host.
 // Only returns blocks which contain data.
 func (c *InternalClient) FragmentBlocks(ctx context.Context, uri *pilosa.URI, index, field, view string, shard uint64) ([]pilosa.FragmentBlock, error) {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	gohttp "net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -1030,6 +1031,49 @@ func TestClient_ImportExistence(t *testing.T) {
 	})
 }
 
+// Ensure client can copy a fragment from one host to another.
+func TestClient_ImportFragmentData(t *testing.T) {
+	src := test.MustRunCluster(t, 1)
+	defer src.Close()
+	dst := test.MustRunCluster(t, 1)
+	defer dst.Close()
+
+	for _, cluster := range []test.Cluster{src, dst} {
+		if _, err := cluster[0].API.CreateIndex(context.Background(), "i", pilosa.IndexOptions{}); err != nil {
+			t.Fatalf("creating index: %v", err)
+		}
+		if _, err := cluster[0].API.CreateField(context.Background(), "i", "f", pilosa.OptFieldTypeDefault()); err != nil {
+			t.Fatalf("creating field: %v", err)
+		}
+	}
+	if _, err := src[0].API.Query(context.Background(), &pilosa.QueryRequest{Index: "i", Query: "Set(1, f=0) Set(2, f=0) Set(3, f=10)"}); err != nil {
+		t.Fatalf("querying: %v", err)
+	}
+
+	c := MustNewClient(src[0].URL(), http.GetHTTPClient(nil))
+	rd, err := c.RetrieveShardFromURI(context.Background(), "i", "f", "standard", 0, src[0].API.Node().URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+	if err := c.ImportFragmentData(context.Background(), &dst[0].API.Node().URI, "i", "f", "standard", 0, rd); err != nil {
+		t.Fatal(err)
+	}
+
+	hldr := test.Holder{Holder: dst[0].Server.Holder()}
+	if a := hldr.Row("i", "f", 0).Columns(); !reflect.DeepEqual(a, []uint64{1, 2}) {
+		t.Fatalf("unexpected columns: %+v", a)
+	}
+	if a := hldr.Row("i", "f", 10).Columns(); !reflect.DeepEqual(a, []uint64{3}) {
+		t.Fatalf("unexpected columns: %+v", a)
+	}
+
+	// Ensure an unknown field is rejected.
+	if err := c.ImportFragmentData(context.Background(), &dst[0].API.Node().URI, "i", "nosuchfield", "standard", 0, strings.NewReader("")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 // Ensure client can retrieve a list of all checksums for blocks in a fragment.
 func TestClient_FragmentBlocks(t *testing.T) {
 	cluster := test.MustRunCluster(t, 1)
@@ -1087,3 +1131,22 @@ func makeImportRoaringRequest(clear bool, viewData string) *pilosa.ImportRoaring
 		},
 	}
 }
+
+func TestClient_Status(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+
+	c := MustNewClient(cluster[0].URL(), http.GetHTTPClient(nil))
+	state, nodes, err := c.Status(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if state != pilosa.ClusterStateNormal {
+		t.Fatalf("unexpected state: %s", state)
+	} else if len(nodes) != 1 || nodes[0].ID != cluster[0].API.Node().ID {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+
+	if err := c.Ready(context.Background(), nil); err != nil {
+		t.Fatalf("expected node to be ready: %s", err)
+	}
+}
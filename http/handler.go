@@ -31,6 +31,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/handlers"
@@ -46,6 +47,9 @@ import (
 type Handler struct {
 	Handler http.Handler
 
+	// router is the unwrapped router, used to describe the API.
+	router *mux.Router
+
 	logger logger.Logger
 
 	// Keeps the query argument validators for each handler
@@ -58,6 +62,22 @@ type Handler struct {
 	closeTimeout time.Duration
 
 	server *http.Server
+
+	// auditLog receives a line for every mutating request, if set.
+	auditLog io.Writer
+	auditMu  sync.Mutex
+
+	// rateLimiter limits the queries and imports accepted per index, if set.
+	rateLimiter *rateLimiter
+
+	// authenticator checks the credentials of requests, if set.
+	authenticator *Authenticator
+
+	// authorizer checks authenticated requests against role bindings, if set.
+	authorizer *Authorizer
+
+	// client sends role bindings to the other nodes, if set.
+	client *InternalClient
 }
 
 // externalPrefixFlag denotes endpoints that are intended to be exposed to clients.
@@ -126,7 +146,8 @@ func NewHandler(opts ...handlerOption) (*Handler, error) {
 		logger:       logger.NopLogger,
 		closeTimeout: time.Second * 30,
 	}
-	handler.Handler = newRouter(handler)
+	handler.router = newRouter(handler)
+	handler.Handler = handler.router
 	handler.populateValidators()
 
 	for _, opt := range opts {
@@ -185,24 +206,35 @@ func (h *Handler) populateValidators() {
 	h.validators["PostTranslateKeys"] = queryValidationSpecRequired()
 	h.validators["PostField"] = queryValidationSpecRequired()
 	h.validators["DeleteField"] = queryValidationSpecRequired()
+	h.validators["DeleteColumn"] = queryValidationSpecRequired().Optional("remote")
 	h.validators["PostImport"] = queryValidationSpecRequired().Optional("clear", "ignoreKeyCheck")
 	h.validators["PostImportRoaring"] = queryValidationSpecRequired().Optional("remote", "clear")
 	h.validators["PostQuery"] = queryValidationSpecRequired().Optional("shards", "columnAttrs", "excludeRowAttrs", "excludeColumns")
 	h.validators["GetInfo"] = queryValidationSpecRequired()
+	h.validators["GetLogLevel"] = queryValidationSpecRequired()
+	h.validators["PutLogLevel"] = queryValidationSpecRequired()
 	h.validators["RecalculateCaches"] = queryValidationSpecRequired()
-	h.validators["GetSchema"] = queryValidationSpecRequired()
+	h.validators["GetSchema"] = queryValidationSpecRequired().Optional("views")
+	h.validators["GetSpec"] = queryValidationSpecRequired()
+	h.validators["GetRoles"] = queryValidationSpecRequired()
+	h.validators["PutRoles"] = queryValidationSpecRequired().Optional("remote")
 	h.validators["PostSchema"] = queryValidationSpecRequired().Optional("remote")
 	h.validators["GetStatus"] = queryValidationSpecRequired()
+	h.validators["GetHealth"] = queryValidationSpecRequired()
+	h.validators["GetReady"] = queryValidationSpecRequired()
 	h.validators["GetVersion"] = queryValidationSpecRequired()
 	h.validators["PostClusterMessage"] = queryValidationSpecRequired()
 	h.validators["GetFragmentBlockData"] = queryValidationSpecRequired()
 	h.validators["GetFragmentBlocks"] = queryValidationSpecRequired("index", "field", "view", "shard")
 	h.validators["GetFragmentData"] = queryValidationSpecRequired("index", "field", "view", "shard")
+	h.validators["PostFragmentData"] = queryValidationSpecRequired("index", "field", "view", "shard")
+	h.validators["PostFragmentSync"] = queryValidationSpecRequired("index", "field", "view", "shard")
 	h.validators["GetFragmentNodes"] = queryValidationSpecRequired("shard", "index")
 	h.validators["PostIndexAttrDiff"] = queryValidationSpecRequired()
 	h.validators["PostFieldAttrDiff"] = queryValidationSpecRequired()
 	h.validators["GetNodes"] = queryValidationSpecRequired()
 	h.validators["GetShardMax"] = queryValidationSpecRequired()
+	h.validators["GetLocalStatus"] = queryValidationSpecRequired()
 }
 
 func (h *Handler) queryArgValidator(next http.Handler) http.Handler {
@@ -270,23 +302,30 @@ func (h *Handler) collectStats(next http.Handler) http.Handler {
 	})
 }
 
-// newRouter creates a new mux http router.
+// newRouter creates a new mux http router. Responses from the query and
+// export endpoints, which can be large, are compressed for clients which
+// send an Accept-Encoding header.
 func newRouter(handler *Handler) *mux.Router {
 	router := mux.NewRouter()
 	router.HandleFunc("/", handler.handleHome).Methods("GET").Name("Home")
+	router.HandleFunc("/api/spec", handler.handleGetSpec).Methods("GET").Name("GetSpec")
+	router.HandleFunc("/auth/roles", handler.handleGetRoles).Methods("GET").Name("GetRoles")
+	router.HandleFunc("/auth/roles", handler.handlePutRoles).Methods("PUT").Name("PutRoles")
 	router.HandleFunc("/cluster/resize/abort", handler.handlePostClusterResizeAbort).Methods("POST").Name("PostClusterResizeAbort")
 	router.HandleFunc("/cluster/resize/remove-node", handler.handlePostClusterResizeRemoveNode).Methods("POST").Name("PostClusterResizeRemoveNode")
 	router.HandleFunc("/cluster/resize/set-coordinator", handler.handlePostClusterResizeSetCoordinator).Methods("POST").Name("PostClusterResizeSetCoordinator")
 	router.PathPrefix("/debug/pprof/").Handler(http.DefaultServeMux).Methods("GET")
 	router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
 	router.Handle("/metrics", promhttp.Handler())
-	router.HandleFunc("/export", handler.handleGetExport).Methods("GET").Name("GetExport")
+	router.Handle("/export", handlers.CompressHandler(http.HandlerFunc(handler.handleGetExport))).Methods("GET").Name("GetExport")
+	router.HandleFunc("/health", handler.handleGetHealth).Methods("GET").Name("GetHealth")
 	router.HandleFunc("/index", handler.handleGetIndexes).Methods("GET").Name("GetIndexes")
 	router.HandleFunc("/index", handler.handlePostIndex).Methods("POST").Name("PostIndex")
 	router.HandleFunc("/index/", handler.handlePostIndex).Methods("POST").Name("PostIndex")
 	router.HandleFunc("/index/{index}", handler.handleGetIndex).Methods("GET").Name("GetIndex")
 	router.HandleFunc("/index/{index}", handler.handlePostIndex).Methods("POST").Name("PostIndex")
 	router.HandleFunc("/index/{index}", handler.handleDeleteIndex).Methods("DELETE").Name("DeleteIndex")
+	router.HandleFunc("/index/{index}/column/{column}", handler.handleDeleteColumn).Methods("DELETE").Name("DeleteColumn")
 	//router.HandleFunc("/index/{index}/field", handler.handleGetFields).Methods("GET") // Not implemented.
 	router.HandleFunc("/index/{index}/field/{field}", handler.handlePostField).Methods("POST").Name("PostField")
 	router.HandleFunc("/index/{index}/field", handler.handlePostField).Methods("POST").Name("PostField")
@@ -294,8 +333,11 @@ func newRouter(handler *Handler) *mux.Router {
 	router.HandleFunc("/index/{index}/field/{field}", handler.handleDeleteField).Methods("DELETE").Name("DeleteField")
 	router.HandleFunc("/index/{index}/field/{field}/import", handler.handlePostImport).Methods("POST").Name("PostImport")
 	router.HandleFunc("/index/{index}/field/{field}/import-roaring/{shard}", handler.handlePostImportRoaring).Methods("POST").Name("PostImportRoaring")
-	router.HandleFunc("/index/{index}/query", handler.handlePostQuery).Methods("POST").Name("PostQuery")
+	router.Handle("/index/{index}/query", handlers.CompressHandler(http.HandlerFunc(handler.handlePostQuery))).Methods("POST").Name("PostQuery")
 	router.HandleFunc("/info", handler.handleGetInfo).Methods("GET").Name("GetInfo")
+	router.HandleFunc("/log-level", handler.handleGetLogLevel).Methods("GET").Name("GetLogLevel")
+	router.HandleFunc("/log-level", handler.handlePutLogLevel).Methods("PUT").Name("PutLogLevel")
+	router.HandleFunc("/ready", handler.handleGetReady).Methods("GET").Name("GetReady")
 	router.HandleFunc("/recalculate-caches", handler.handleRecalculateCaches).Methods("POST").Name("RecalculateCaches")
 	router.HandleFunc("/schema", handler.handleGetSchema).Methods("GET").Name("GetSchema")
 	router.HandleFunc("/schema", handler.handlePostSchema).Methods("POST").Name("PostSchema")
@@ -308,7 +350,9 @@ func newRouter(handler *Handler) *mux.Router {
 	router.HandleFunc("/internal/fragment/block/data", handler.handleGetFragmentBlockData).Methods("GET").Name("GetFragmentBlockData")
 	router.HandleFunc("/internal/fragment/blocks", handler.handleGetFragmentBlocks).Methods("GET").Name("GetFragmentBlocks")
 	router.HandleFunc("/internal/fragment/data", handler.handleGetFragmentData).Methods("GET").Name("GetFragmentData")
+	router.HandleFunc("/internal/fragment/data", handler.handlePostFragmentData).Methods("POST").Name("PostFragmentData")
 	router.HandleFunc("/internal/fragment/nodes", handler.handleGetFragmentNodes).Methods("GET").Name("GetFragmentNodes")
+	router.HandleFunc("/internal/fragment/sync", handler.handlePostFragmentSync).Methods("POST").Name("PostFragmentSync")
 	router.HandleFunc("/internal/index/{index}/attr/diff", handler.handlePostIndexAttrDiff).Methods("POST").Name("PostIndexAttrDiff")
 	router.HandleFunc("/internal/translate/data", handler.handlePostTranslateData).Methods("POST").Name("PostTranslateData")
 	router.HandleFunc("/internal/translate/keys", handler.handlePostTranslateKeys).Methods("POST").Name("PostTranslateKeys")
@@ -316,8 +360,13 @@ func newRouter(handler *Handler) *mux.Router {
 	router.HandleFunc("/internal/index/{index}/field/{field}/remote-available-shards/{shardID}", handler.handleDeleteRemoteAvailableShard).Methods("DELETE")
 	router.HandleFunc("/internal/nodes", handler.handleGetNodes).Methods("GET").Name("GetNodes")
 	router.HandleFunc("/internal/shards/max", handler.handleGetShardsMax).Methods("GET").Name("GetShardsMax") // TODO: deprecate, but it's being used by the client
+	router.HandleFunc("/internal/status", handler.handleGetLocalStatus).Methods("GET").Name("GetLocalStatus")
 
+	router.Use(handler.auditRequests)
+	router.Use(handler.authenticateRequests)
+	router.Use(handler.authorizeRequests)
 	router.Use(handler.queryArgValidator)
+	router.Use(handler.limitRequests)
 	router.Use(handler.extractTracing)
 	router.Use(handler.collectStats)
 	return router
@@ -353,19 +402,7 @@ func (r *successResponse) check(err error) (statusCode int) {
 		return 0
 	}
 
-	cause := errors.Cause(err)
-
-	// Determine HTTP status code based on the error type.
-	switch cause.(type) {
-	case pilosa.BadRequestError:
-		statusCode = http.StatusBadRequest
-	case pilosa.ConflictError:
-		statusCode = http.StatusConflict
-	case pilosa.NotFoundError:
-		statusCode = http.StatusNotFound
-	default:
-		statusCode = http.StatusInternalServerError
-	}
+	statusCode = errorStatusCode(err)
 
 	r.Success = false
 	r.Error = &Error{Message: err.Error()}
@@ -373,6 +410,30 @@ func (r *successResponse) check(err error) (statusCode int) {
 	return statusCode
 }
 
+// errorStatusCode returns the HTTP status code for err. The error chain is
+// walked from the outermost error inwards and the first typed error found
+// determines the status code. Errors which carry no type information map to
+// http.StatusInternalServerError.
+func errorStatusCode(err error) int {
+	for err != nil {
+		switch err.(type) {
+		case pilosa.BadRequestError:
+			return http.StatusBadRequest
+		case pilosa.ConflictError:
+			return http.StatusConflict
+		case pilosa.NotFoundError:
+			return http.StatusNotFound
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return http.StatusInternalServerError
+}
+
 // write sends a response to the http.ResponseWriter based on the success
 // status and the error.
 func (r *successResponse) write(w http.ResponseWriter, err error) {
@@ -431,12 +492,35 @@ func (h *Handler) handleGetSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	schema := h.api.Schema(r.Context())
+	var schema []*pilosa.IndexInfo
+	if r.URL.Query().Get("views") == "true" {
+		schema = h.api.SchemaWithViews(r.Context())
+	} else {
+		schema = h.api.Schema(r.Context())
+	}
+	schema = h.visibleSchema(r, schema)
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{"indexes": schema}); err != nil { // TODO: use pilosa.Schema instead of map[string]interface{} here?
 		h.logger.Printf("write schema response error: %s", err)
 	}
 }
 
+// handleGetSpec handles GET /api/spec requests.
+func (h *Handler) handleGetSpec(w http.ResponseWriter, r *http.Request) {
+	if !validHeaderAcceptJSON(r.Header) {
+		http.Error(w, "JSON only acceptable response", http.StatusNotAcceptable)
+		return
+	}
+
+	spec, err := h.buildSpec()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(spec); err != nil {
+		h.logger.Printf("write spec response error: %s", err)
+	}
+}
+
 func (h *Handler) handlePostSchema(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	remoteStr := q.Get("remote")
@@ -474,6 +558,25 @@ func (h *Handler) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGetHealth handles GET /health requests. It reports that the process
+// is serving HTTP and does not check any dependencies.
+func (h *Handler) handleGetHealth(w http.ResponseWriter, r *http.Request) {
+	resp := successResponse{h: h}
+	resp.write(w, nil)
+}
+
+// handleGetReady handles GET /ready requests. It responds with 503 Service
+// Unavailable while the node cannot serve requests.
+func (h *Handler) handleGetReady(w http.ResponseWriter, r *http.Request) {
+	resp := successResponse{h: h}
+	if resp.check(h.api.Ready()) != 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Printf("write ready response error: %s", err)
+	}
+}
+
 func (h *Handler) handleGetInfo(w http.ResponseWriter, r *http.Request) {
 	if !validHeaderAcceptJSON(r.Header) {
 		http.Error(w, "JSON only acceptable response", http.StatusNotAcceptable)
@@ -555,7 +658,7 @@ func (h *Handler) handleGetShardsMax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := json.NewEncoder(w).Encode(getShardsMaxResponse{
-		Standard: h.api.MaxShards(r.Context()),
+		Standard: h.visibleShards(r, h.api.MaxShards(r.Context())),
 	}); err != nil {
 		h.logger.Printf("write shards-max response error: %s", err)
 	}
@@ -565,6 +668,22 @@ type getShardsMaxResponse struct {
 	Standard map[string]uint64 `json:"standard"`
 }
 
+// handleGetLocalStatus handles GET /internal/status requests.
+func (h *Handler) handleGetLocalStatus(w http.ResponseWriter, r *http.Request) {
+	if !validHeaderAcceptJSON(r.Header) {
+		http.Error(w, "JSON only acceptable response", http.StatusNotAcceptable)
+		return
+	}
+	status, err := h.api.LocalStatus(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.visibleLocalStatus(r, status)); err != nil {
+		h.logger.Printf("write local status response error: %s", err)
+	}
+}
+
 // handleGetIndexes handles GET /index request.
 func (h *Handler) handleGetIndexes(w http.ResponseWriter, r *http.Request) {
 	h.handleGetSchema(w, r)
@@ -937,6 +1056,26 @@ func (h *Handler) handleDeleteField(w http.ResponseWriter, r *http.Request) {
 	resp.write(w, err)
 }
 
+// handleDeleteColumn handles DELETE /index/{index}/column/{column} request.
+func (h *Handler) handleDeleteColumn(w http.ResponseWriter, r *http.Request) {
+	if !validHeaderAcceptJSON(r.Header) {
+		http.Error(w, "JSON only acceptable response", http.StatusNotAcceptable)
+		return
+	}
+
+	indexName := mux.Vars(r)["index"]
+	columnID, err := strconv.ParseUint(mux.Vars(r)["column"], 10, 64)
+	if err != nil {
+		http.Error(w, "column should be an unsigned integer", http.StatusBadRequest)
+		return
+	}
+	remote := r.URL.Query().Get("remote") == "true"
+
+	resp := successResponse{h: h}
+	err = h.api.DeleteColumn(r.Context(), indexName, columnID, remote)
+	resp.write(w, err)
+}
+
 // handleDeleteRemoteAvailableShard handles DELETE /field/{field}/available-shards/{shardID} request.
 func (h *Handler) handleDeleteRemoteAvailableShard(w http.ResponseWriter, r *http.Request) {
 	if !validHeaderAcceptJSON(r.Header) {
@@ -1127,6 +1266,9 @@ func (h *Handler) handlePostImport(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if !h.limitImport(w, r, indexName, importBits(req.ColumnIDs, req.ColumnKeys)) {
+			return
+		}
 
 		if err := h.api.ImportValue(r.Context(), req, opts...); err != nil {
 			switch errors.Cause(err) {
@@ -1145,6 +1287,9 @@ func (h *Handler) handlePostImport(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if !h.limitImport(w, r, indexName, importBits(req.ColumnIDs, req.ColumnKeys)) {
+			return
+		}
 
 		if err := h.api.Import(r.Context(), req, opts...); err != nil {
 			switch errors.Cause(err) {
@@ -1330,6 +1475,35 @@ func (h *Handler) handleGetFragmentData(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handlePostFragmentData handles POST /internal/fragment/data requests.
+func (h *Handler) handlePostFragmentData(w http.ResponseWriter, r *http.Request) {
+	// Read shard parameter.
+	q := r.URL.Query()
+	shard, err := strconv.ParseUint(q.Get("shard"), 10, 64)
+	if err != nil {
+		http.Error(w, "shard required", http.StatusBadRequest)
+		return
+	}
+	// Replace fragment with the request body.
+	resp := successResponse{h: h}
+	err = h.api.ImportFragmentData(r.Context(), q.Get("index"), q.Get("field"), q.Get("view"), shard, r.Body)
+	resp.write(w, err)
+}
+
+// handlePostFragmentSync handles POST /internal/fragment/sync requests.
+func (h *Handler) handlePostFragmentSync(w http.ResponseWriter, r *http.Request) {
+	// Read shard parameter.
+	q := r.URL.Query()
+	shard, err := strconv.ParseUint(q.Get("shard"), 10, 64)
+	if err != nil {
+		http.Error(w, "shard required", http.StatusBadRequest)
+		return
+	}
+	resp := successResponse{h: h}
+	err = h.api.SyncFragment(r.Context(), q.Get("index"), q.Get("field"), q.Get("view"), shard)
+	resp.write(w, err)
+}
+
 // handleGetVersion handles /version requests.
 func (h *Handler) handleGetVersion(w http.ResponseWriter, r *http.Request) {
 	if !validHeaderAcceptJSON(r.Header) {
@@ -1346,6 +1520,52 @@ func (h *Handler) handleGetVersion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// levelLogger is a logger whose level can be changed while it is in use.
+type levelLogger interface {
+	Level() logger.Level
+	SetLevel(logger.Level)
+}
+
+// logLevel is the body of /log-level requests and responses.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// handleGetLogLevel handles GET /log-level requests.
+func (h *Handler) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.logger.(levelLogger)
+	if !ok {
+		http.Error(w, "log level cannot be changed", http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(logLevel{Level: l.Level().String()}); err != nil {
+		h.logger.Printf("write log level response error: %s", err)
+	}
+}
+
+// handlePutLogLevel handles PUT /log-level requests.
+func (h *Handler) handlePutLogLevel(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.logger.(levelLogger)
+	if !ok {
+		http.Error(w, "log level cannot be changed", http.StatusNotFound)
+		return
+	}
+	resp := successResponse{h: h}
+	var req logLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.write(w, pilosa.NewBadRequestError(errors.Wrap(err, "decoding log level")))
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		resp.write(w, pilosa.NewBadRequestError(err))
+		return
+	}
+	l.SetLevel(level)
+	h.logger.Printf("log level set to %s", level)
+	resp.write(w, nil)
+}
+
 // QueryResult types.
 const (
 	QueryResultTypeRow uint32 = iota
@@ -1426,7 +1646,11 @@ func (h *Handler) handlePostClusterResizeRemoveNode(w http.ResponseWriter, r *ht
 		return
 	}
 
-	removeNode, err := h.api.RemoveNode(req.ID)
+	removeNode := h.api.RemoveNode
+	if req.Force {
+		removeNode = h.api.ForceRemoveNode
+	}
+	node, err := removeNode(req.ID)
 	if err != nil {
 		if errors.Cause(err) == pilosa.ErrNodeIDNotExists {
 			http.Error(w, "removing node: "+err.Error(), http.StatusNotFound)
@@ -1438,7 +1662,7 @@ func (h *Handler) handlePostClusterResizeRemoveNode(w http.ResponseWriter, r *ht
 
 	// Encode response.
 	if err := json.NewEncoder(w).Encode(removeNodeResponse{
-		Remove: removeNode,
+		Remove: node,
 	}); err != nil {
 		h.logger.Printf("response encoding error: %s", err)
 	}
@@ -1446,6 +1670,9 @@ func (h *Handler) handlePostClusterResizeRemoveNode(w http.ResponseWriter, r *ht
 
 type removeNodeRequest struct {
 	ID string `json:"id"`
+
+	// Force removes the node without moving its data first.
+	Force bool `json:"force"`
 }
 
 type removeNodeResponse struct {
@@ -1668,6 +1895,16 @@ func (h *Handler) handlePostImportRoaring(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if h.rateLimiter != nil {
+		n, err := importRoaringBits(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if !h.limitImport(w, r, indexName, n) {
+			return
+		}
+	}
+
 	resp := &pilosa.ImportResponse{}
 	// TODO give meaningful stats for import
 	err = h.api.ImportRoaring(ctx, indexName, fieldName, shard, remote, req)
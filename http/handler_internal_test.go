@@ -16,12 +16,27 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/pql"
+	"github.com/pilosa/pilosa/v2/roaring"
+	"github.com/pkg/errors"
 )
 
 // Test custom UnmarshalJSON for postIndexRequest object
@@ -178,3 +193,606 @@ func TestFieldOptionValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{err: errors.New("boom"), code: http.StatusInternalServerError},
+		{err: errors.Wrap(pilosa.ErrIndexNotFound, "querying"), code: http.StatusInternalServerError},
+		{err: pilosa.NewBadRequestError(errors.New("bad")), code: http.StatusBadRequest},
+		{err: errors.Wrap(pilosa.NewBadRequestError(errors.New("bad")), "parsing"), code: http.StatusBadRequest},
+		{err: errors.Wrap(pilosa.NotFoundError{Name: "i"}, "deleting index"), code: http.StatusNotFound},
+	}
+	for i, test := range tests {
+		if code := errorStatusCode(test.err); code != test.code {
+			t.Errorf("test %d: expected status code %d, but got %d", i, test.code, code)
+		}
+	}
+}
+
+func TestHandler_BuildSpec(t *testing.T) {
+	h := &Handler{}
+	h.router = newRouter(h)
+	h.populateValidators()
+
+	spec, err := h.buildSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Path variables and validator arguments become parameters.
+	op := spec.Paths["/index/{index}/field/{field}/import-roaring/{shard}"]["post"]
+	if op == nil {
+		t.Fatal("expected import-roaring operation")
+	} else if op.OperationID != "PostImportRoaring" {
+		t.Fatalf("unexpected operation id: %s", op.OperationID)
+	}
+	exp := []openAPIParameter{
+		{Name: "index", In: "path", Required: true, Schema: openAPISchema{Type: "string"}},
+		{Name: "field", In: "path", Required: true, Schema: openAPISchema{Type: "string"}},
+		{Name: "shard", In: "path", Required: true, Schema: openAPISchema{Type: "integer"}},
+		{Name: "clear", In: "query", Schema: openAPISchema{Type: "boolean"}},
+		{Name: "remote", In: "query", Schema: openAPISchema{Type: "boolean"}},
+	}
+	if !reflect.DeepEqual(op.Parameters, exp) {
+		t.Fatalf("unexpected parameters: %+v", op.Parameters)
+	}
+
+	// Routes have their own responses, with schemas for their bodies.
+	query := spec.Paths["/index/{index}/query"]["post"]
+	if query == nil {
+		t.Fatal("expected query operation")
+	} else if schema := query.Responses["200"].Content["application/json"].Schema; schema.Ref != "#/components/schemas/QueryResponse" {
+		t.Fatalf("unexpected query response schema: %+v", schema)
+	} else if _, ok := query.Responses["413"]; !ok {
+		t.Fatalf("expected 413 response: %+v", query.Responses)
+	} else if _, ok := query.Responses["401"]; ok {
+		t.Fatal("unexpected 401 response without auth")
+	} else if shards := query.Parameters[len(query.Parameters)-1]; shards.Name != "shards" || shards.Schema.Type != "array" || shards.Schema.Items.Type != "integer" {
+		t.Fatalf("unexpected shards parameter: %+v", shards)
+	}
+	if _, ok := spec.Paths["/schema"]["post"].Responses["204"]; !ok {
+		t.Fatal("expected 204 response to POST /schema")
+	}
+
+	// Every route has a response for success, or at least one response,
+	// and every schema it refers to is defined.
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			if _, ok := op.Responses["default"]; ok {
+				t.Errorf("no responses for %s %s", method, path)
+			}
+			for code, resp := range op.Responses {
+				for _, mt := range resp.Content {
+					if name := strings.TrimPrefix(mt.Schema.Ref, "#/components/schemas/"); name != "" {
+						if _, ok := spec.Components.Schemas[name]; !ok {
+							t.Errorf("%s %s %s: undefined schema %s", method, path, code, name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Middleware responses are described when it is enabled.
+	h.authenticator = &Authenticator{}
+	spec, err = h.buildSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Paths["/index/{index}/query"]["post"].Responses["401"]; !ok {
+		t.Fatal("expected 401 response with auth")
+	} else if _, ok := spec.Paths["/version"]["get"].Responses["401"]; ok {
+		t.Fatal("unexpected 401 response to /version")
+	}
+
+	// Operation IDs are unique even when routes share a name.
+	ids := make(map[string]struct{})
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			if _, ok := ids[op.OperationID]; ok {
+				t.Fatalf("duplicate operation id %s at %s %s", op.OperationID, method, path)
+			}
+			ids[op.OperationID] = struct{}{}
+		}
+	}
+
+	// Unnamed routes are not described.
+	if _, ok := spec.Paths["/metrics"]; ok {
+		t.Fatal("unexpected /metrics path")
+	}
+}
+
+func TestHandler_AuditRequests(t *testing.T) {
+	var buf bytes.Buffer
+	h := &Handler{
+		auditLog: &buf,
+		authenticator: &Authenticator{
+			APIKeys: map[string][]string{"client-key": {AdminRole}},
+			NodeKey: "node-key",
+		},
+	}
+
+	var body string
+	router := mux.NewRouter()
+	router.Use(h.auditRequests)
+	router.Use(h.authenticateRequests)
+	record := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}
+	router.HandleFunc("/index/{index}/query", record).Methods("POST").Name("PostQuery")
+	router.HandleFunc("/index/{index}", record).Methods("GET", "POST").Name("Index")
+	router.HandleFunc("/internal/index/{index}", record).Methods("POST").Name("Internal")
+
+	for _, test := range []struct {
+		method, path, body, key string
+		logged                  bool
+		status                  int
+	}{
+		{method: "POST", path: "/index/i/query", body: "Set(1, f=1)", key: "client-key", logged: true},
+		{method: "POST", path: "/index/i/query", body: "Count(Row(f=1))", key: "client-key"},
+		{method: "POST", path: "/index/i", body: "{}", key: "client-key", logged: true},
+		{method: "GET", path: "/index/i", key: "client-key"},
+
+		// Only nodes are trusted to forward requests and use internal routes.
+		{method: "POST", path: "/index/i/query?remote=true", body: "Set(1, f=1)", key: "client-key", logged: true},
+		{method: "POST", path: "/index/i?remote=true", body: "{}", key: "client-key", logged: true},
+		{method: "POST", path: "/internal/index/i", body: "{}", key: "client-key", logged: true},
+		{method: "POST", path: "/index/i/query?remote=true", body: "Set(1, f=1)", key: "node-key"},
+		{method: "POST", path: "/internal/index/i", body: "{}", key: "node-key"},
+		{method: "POST", path: "/index/i", body: "{}", key: "node-key", logged: true},
+
+		// Refused requests are logged, whether or not they would write.
+		{method: "GET", path: "/index/i", key: "wrong-key", logged: true, status: http.StatusUnauthorized},
+		{method: "POST", path: "/index/i/query", body: "Count(Row(f=1))", logged: true, status: http.StatusUnauthorized},
+	} {
+		buf.Reset()
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.key != "" {
+			r.Header.Set(APIKeyHeader, test.key)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		status := test.status
+		if status == 0 {
+			status = http.StatusOK
+
+			// The wrapped handler still sees the whole body.
+			if body != test.body {
+				t.Fatalf("%s %s: unexpected body: %q", test.method, test.path, body)
+			}
+		}
+		body = ""
+
+		if !test.logged {
+			if buf.Len() != 0 {
+				t.Fatalf("%s %s: unexpected audit entry: %s", test.method, test.path, buf.String())
+			}
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s %s: decoding audit entry: %s", test.method, test.path, err)
+		} else if entry.Method != test.method || entry.Path != strings.Split(test.path, "?")[0] || entry.Status != status {
+			t.Fatalf("%s %s: unexpected audit entry: %+v", test.method, test.path, entry)
+		} else if entry.Remote != strings.Contains(test.path, "remote=true") {
+			t.Fatalf("%s %s: unexpected remote flag: %+v", test.method, test.path, entry)
+		} else if status == http.StatusOK && entry.Path == "/index/i/query" && entry.Query != test.body {
+			t.Fatalf("unexpected query in audit entry: %q", entry.Query)
+		}
+
+		switch test.key {
+		case "client-key":
+			if entry.User != apiKeySubject("client-key") {
+				t.Fatalf("unexpected user: %q", entry.User)
+			}
+		case "node-key":
+			if entry.User != NodeSubject {
+				t.Fatalf("unexpected user: %q", entry.User)
+			}
+		default:
+			if entry.User != "" {
+				t.Fatalf("unexpected user: %q", entry.User)
+			}
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(RateLimit{Query: 2}, map[string]RateLimit{"busy": {Query: 0.5, Import: 1}})
+	l.now = func() time.Time { return now }
+
+	// The default budget allows two queries a second for each index.
+	for _, index := range []string{"i", "i", "j", "j"} {
+		if ok, _ := l.take(index, false, 1); !ok {
+			t.Fatalf("expected query on %s to be allowed", index)
+		}
+	}
+	if ok, wait := l.take("i", false, 1); ok {
+		t.Fatal("expected query to be limited")
+	} else if wait != 500*time.Millisecond {
+		t.Fatalf("unexpected wait: %s", wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.take("i", false, 1); !ok {
+		t.Fatal("expected query to be allowed after waiting")
+	}
+
+	// Imports are not limited by default.
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.take("i", true, 1); !ok {
+			t.Fatal("expected import to be allowed")
+		}
+	}
+
+	// Imports are charged one token per bit. An import larger than the
+	// bucket is accepted when it is full, and later imports wait until the
+	// debt has been paid off.
+	if ok, _ := l.take("busy", true, 3); !ok {
+		t.Fatal("expected import to be allowed")
+	} else if ok, wait := l.take("busy", true, 1); ok {
+		t.Fatal("expected import to be limited")
+	} else if wait != 3*time.Second {
+		t.Fatalf("unexpected wait: %s", wait)
+	}
+
+	// Index limits override the default.
+	if ok, _ := l.take("busy", false, 1); !ok {
+		t.Fatal("expected first query to be allowed")
+	} else if ok, wait := l.take("busy", false, 1); ok {
+		t.Fatal("expected second query to be limited")
+	} else if wait != 2*time.Second {
+		t.Fatalf("unexpected wait: %s", wait)
+	}
+
+	// Buckets which have refilled are removed by the next sweep, so that
+	// requests for many index names do not accumulate buckets.
+	if n := len(l.buckets); n != 4 {
+		t.Fatalf("unexpected number of buckets: %d", n)
+	}
+	now = now.Add(rateLimitSweepInterval)
+	if ok, _ := l.take("j", false, 1); !ok {
+		t.Fatal("expected query to be allowed")
+	} else if _, ok := l.buckets[rateLimitKey{index: "j"}]; !ok || len(l.buckets) != 1 {
+		t.Fatalf("unexpected buckets after sweep: %v", l.buckets)
+	}
+}
+
+func TestHandler_LimitRequests(t *testing.T) {
+	h := &Handler{
+		rateLimiter:   newRateLimiter(RateLimit{Query: 1, Import: 10}, nil),
+		authenticator: &Authenticator{APIKeys: map[string][]string{"client-key": {AdminRole}}, NodeKey: "node-key"},
+	}
+
+	router := mux.NewRouter()
+	router.Use(h.authenticateRequests)
+	router.Use(h.limitRequests)
+	router.HandleFunc("/index/{index}/query", func(w http.ResponseWriter, r *http.Request) {}).Methods("POST").Name("PostQuery")
+	router.HandleFunc("/index/{index}/import", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.ParseUint(r.URL.Query().Get("bits"), 10, 64)
+		h.limitImport(w, r, mux.Vars(r)["index"], n)
+	}).Methods("POST").Name("PostImport")
+
+	for i, test := range []struct {
+		path, key string
+		code      int
+	}{
+		{path: "/index/i/query", key: "client-key", code: http.StatusOK},
+		{path: "/index/i/query", key: "client-key", code: http.StatusTooManyRequests},
+		{path: "/index/i/query?remote=true", key: "client-key", code: http.StatusTooManyRequests},
+		{path: "/index/i/query?remote=true", key: "node-key", code: http.StatusOK},
+		{path: "/index/j/query", key: "client-key", code: http.StatusOK},
+
+		// Imports are charged by the number of bits they set.
+		{path: "/index/i/import?bits=100", key: "client-key", code: http.StatusOK},
+		{path: "/index/i/import?bits=1", key: "client-key", code: http.StatusTooManyRequests},
+		{path: "/index/i/import?bits=1&remote=true", key: "node-key", code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", test.path, nil)
+		r.Header.Set(APIKeyHeader, test.key)
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Fatalf("test %d: expected status %d, got %d", i, test.code, w.Code)
+		} else if w.Code == http.StatusTooManyRequests && strings.Contains(test.path, "query") && w.Header().Get("Retry-After") != "1" {
+			t.Fatalf("test %d: unexpected Retry-After: %q", i, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestImportRoaringBits(t *testing.T) {
+	req := &pilosa.ImportRoaringRequest{Views: make(map[string][]byte)}
+	for view, bits := range map[string][]uint64{"": {1, 2, 3}, "standard_2019": {1, 1 << 40}} {
+		var buf bytes.Buffer
+		if _, err := roaring.NewBitmap(bits...).WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		req.Views[view] = buf.Bytes()
+	}
+	if n, err := importRoaringBits(req); err != nil {
+		t.Fatal(err)
+	} else if n != 5 {
+		t.Fatalf("unexpected bit count: %d", n)
+	}
+
+	req.Views["bad"] = []byte("garbage")
+	if _, err := importRoaringBits(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestAuthenticator_ParseJWT(t *testing.T) {
+	a := &Authenticator{JWTSecret: []byte("secret")}
+	now := time.Unix(1000, 0)
+
+	sign := func(secret, header, claims string) string {
+		s := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(s))
+		return s + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+
+	id, err := a.parseJWT(sign("secret", hs256, `{"sub":"alice","roles":["reader","writer"],"exp":2000}`), now)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(id, &Identity{Subject: "alice", Roles: []string{"reader", "writer"}}) {
+		t.Fatalf("unexpected identity: %+v", id)
+	}
+
+	for _, token := range []string{
+		sign("other", hs256, `{"sub":"alice"}`),
+		sign("secret", `{"alg":"none"}`, `{"sub":"alice"}`),
+		sign("secret", hs256, `{"sub":"alice","exp":1000}`),
+		sign("secret", hs256, `{"sub":"alice","nbf":1001}`),
+		"not.a-token",
+	} {
+		if _, err := a.parseJWT(token, now); err == nil {
+			t.Fatalf("expected error for token %s", token)
+		}
+	}
+}
+
+func TestHandler_AuthenticateRequests(t *testing.T) {
+	h := &Handler{authenticator: &Authenticator{APIKeys: map[string][]string{"key": {"admin"}, "other": {"admin"}}, NodeKey: "node"}}
+
+	var id *Identity
+	router := mux.NewRouter()
+	router.Use(h.authenticateRequests)
+	router.HandleFunc("/index/{index}/query", func(w http.ResponseWriter, r *http.Request) {
+		id, _ = IdentityFromContext(r.Context())
+	}).Methods("POST").Name("PostQuery")
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET").Name("GetHealth")
+	router.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET").Name("GetVersion")
+
+	for i, test := range []struct {
+		method, path, key string
+		code              int
+	}{
+		{method: "POST", path: "/index/i/query", key: "key", code: http.StatusOK},
+		{method: "POST", path: "/index/i/query", key: "node", code: http.StatusOK},
+		{method: "POST", path: "/index/i/query", key: "wrong", code: http.StatusUnauthorized},
+		{method: "POST", path: "/index/i/query", code: http.StatusUnauthorized},
+		{method: "GET", path: "/health", code: http.StatusOK},
+		{method: "GET", path: "/version", code: http.StatusOK},
+	} {
+		id = nil
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.key != "" {
+			r.Header.Set(APIKeyHeader, test.key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Fatalf("test %d: expected status %d, got %d", i, test.code, w.Code)
+		} else if test.key == "key" && (id == nil || !reflect.DeepEqual(id.Roles, []string{"admin"}) || id.Subject != apiKeySubject("key")) {
+			t.Fatalf("test %d: unexpected identity: %+v", i, id)
+		} else if test.key == "node" && (id == nil || !id.Node || id.Subject != NodeSubject || !reflect.DeepEqual(id.Roles, []string{AdminRole})) {
+			t.Fatalf("test %d: unexpected node identity: %+v", i, id)
+		} else if test.key == "key" && id.Node {
+			t.Fatalf("test %d: unexpected node identity: %+v", i, id)
+		}
+	}
+
+	// Each API key has its own subject, which does not contain the key.
+	if a, b := apiKeySubject("key"), apiKeySubject("other"); a == b {
+		t.Fatalf("expected different subjects, got %s", a)
+	} else if !strings.HasPrefix(a, "api-key:") || strings.HasSuffix(a, ":key") {
+		t.Fatalf("unexpected subject: %s", a)
+	}
+}
+
+func TestAuthorizer_Allowed(t *testing.T) {
+	a, err := NewAuthorizer(map[string][]Grant{
+		"reader": {{Index: "*", Permission: PermissionRead}},
+		"etl":    {{Index: "events", Field: "clicks", Permission: PermissionWrite}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		roles        []string
+		index, field string
+		perm         Permission
+		exp          bool
+	}{
+		{roles: []string{"reader"}, index: "i", field: "f", perm: PermissionRead, exp: true},
+		{roles: []string{"reader"}, index: "i", perm: PermissionRead, exp: true},
+		{roles: []string{"reader"}, index: "i", field: "f", perm: PermissionWrite},
+		{roles: []string{"etl"}, index: "events", field: "clicks", perm: PermissionRead, exp: true},
+		{roles: []string{"etl"}, index: "events", field: "clicks", perm: PermissionWrite, exp: true},
+		{roles: []string{"etl"}, index: "events", field: "views", perm: PermissionRead},
+		{roles: []string{"etl"}, index: "events", perm: PermissionRead},
+		{roles: []string{"etl"}, index: "events", field: "clicks", perm: PermissionAdmin},
+		{roles: []string{"reader", "etl"}, index: "events", field: "clicks", perm: PermissionWrite, exp: true},
+		{roles: []string{AdminRole}, perm: PermissionAdmin, exp: true},
+		{roles: []string{"unknown"}, index: "i", perm: PermissionRead},
+	} {
+		if got := a.allowed(&Identity{Roles: test.roles}, test.index, test.field, test.perm); got != test.exp {
+			t.Errorf("test %d: expected %v, got %v", i, test.exp, got)
+		}
+	}
+}
+
+func TestAuthorizer_SetRoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pilosa-roles-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "roles.json")
+
+	a, err := NewAuthorizer(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SetRoles(map[string][]Grant{"r": {{Index: "i", Permission: "everything"}}}); errorStatusCode(err) != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %v", err)
+	}
+
+	roles := map[string][]Grant{"r": {{Index: "i", Field: "f", Permission: PermissionWrite}}}
+	if err := a.SetRoles(roles); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saved role bindings take precedence over the ones given.
+	other, err := NewAuthorizer(map[string][]Grant{"x": {{Index: "*", Permission: PermissionRead}}}, path)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other.Roles(), roles) {
+		t.Fatalf("unexpected roles: %+v", other.Roles())
+	}
+}
+
+func TestQueryAccess(t *testing.T) {
+	q, err := pql.ParseString(`Count(Intersect(Row(a=1), Range(b > 10))) Set(1, c=2) TopN(d, n=5) Sum(field=e) Not(Row(a=1))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []access{
+		{index: "i", field: "a", perm: PermissionRead},
+		{index: "i", field: "b", perm: PermissionRead},
+		{index: "i", field: "c", perm: PermissionWrite},
+		{index: "i", field: "d", perm: PermissionRead},
+		{index: "i", field: "e", perm: PermissionRead},
+		{index: "i", perm: PermissionRead},
+		{index: "i", field: "a", perm: PermissionRead},
+	}
+	if got := queryAccess("i", q); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected access: %+v", got)
+	}
+}
+
+func TestHandler_VisibleSchema(t *testing.T) {
+	a, err := NewAuthorizer(map[string][]Grant{
+		"etl":    {{Index: "i", Field: "f", Permission: PermissionWrite}},
+		"reader": {{Index: "j", Permission: PermissionRead}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{authorizer: a}
+	schema := []*pilosa.IndexInfo{
+		{Name: "i", Fields: []*pilosa.FieldInfo{{Name: "f"}, {Name: "g"}}},
+		{Name: "j", Fields: []*pilosa.FieldInfo{{Name: "f"}, {Name: "g"}}},
+	}
+	maxShards := map[string]uint64{"i": 1, "j": 2}
+
+	for _, test := range []struct {
+		roles  []string
+		fields map[string][]string
+		shards map[string]uint64
+	}{
+		{roles: []string{"etl"}, fields: map[string][]string{"i": {"f"}}, shards: map[string]uint64{"i": 1}},
+		{roles: []string{"reader"}, fields: map[string][]string{"j": {"f", "g"}}, shards: map[string]uint64{"j": 2}},
+		{roles: []string{"other"}, fields: map[string][]string{}, shards: map[string]uint64{}},
+		{roles: []string{AdminRole}, fields: map[string][]string{"i": {"f", "g"}, "j": {"f", "g"}}, shards: maxShards},
+	} {
+		r := httptest.NewRequest("GET", "/schema", nil)
+		r = r.WithContext(context.WithValue(r.Context(), identityKey{}, &Identity{Roles: test.roles}))
+
+		fields := make(map[string][]string)
+		for _, ii := range h.visibleSchema(r, schema) {
+			names := []string{}
+			for _, fi := range ii.Fields {
+				names = append(names, fi.Name)
+			}
+			fields[ii.Name] = names
+		}
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("roles %v: expected fields %v, got %v", test.roles, test.fields, fields)
+		}
+		if shards := h.visibleShards(r, maxShards); !reflect.DeepEqual(shards, test.shards) {
+			t.Errorf("roles %v: expected shards %v, got %v", test.roles, test.shards, shards)
+		}
+	}
+
+	// Without an identity, everything is visible.
+	r := httptest.NewRequest("GET", "/schema", nil)
+	if indexes := h.visibleSchema(r, schema); len(indexes) != 2 {
+		t.Errorf("expected every index without an identity, got %d", len(indexes))
+	}
+}
+
+func TestHandler_AuthorizeRequests(t *testing.T) {
+	a, err := NewAuthorizer(map[string][]Grant{
+		"etl":    {{Index: "i", Field: "f", Permission: PermissionWrite}},
+		"reader": {{Index: "i", Permission: PermissionRead}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{
+		authenticator: &Authenticator{APIKeys: map[string][]string{"etl": {"etl"}, "reader": {"reader"}, "admin": {AdminRole}}},
+		authorizer:    a,
+	}
+
+	router := mux.NewRouter()
+	router.Use(h.authenticateRequests)
+	router.Use(h.authorizeRequests)
+	nop := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/index/{index}/query", nop).Methods("POST").Name("PostQuery")
+	router.HandleFunc("/index/{index}", nop).Methods("DELETE").Name("DeleteIndex")
+	router.HandleFunc("/recalculate-caches", nop).Methods("POST").Name("RecalculateCaches")
+	router.HandleFunc("/internal/fragment/nodes", nop).Methods("GET").Name("GetFragmentNodes")
+	router.HandleFunc("/internal/fragment/data", nop).Methods("GET").Name("GetFragmentData")
+	router.HandleFunc("/internal/shards/max", nop).Methods("GET").Name("GetShardsMax")
+	router.HandleFunc("/internal/status", nop).Methods("GET").Name("GetLocalStatus")
+
+	for i, test := range []struct {
+		method, path, body, key string
+		code                    int
+	}{
+		{method: "POST", path: "/index/i/query", body: "Set(1, f=1)", key: "etl", code: http.StatusOK},
+		{method: "POST", path: "/index/i/query", body: "Set(1, g=1)", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/index/i/query", body: "All()", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/index/j/query", body: "Row(f=1)", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/index/i/query", body: "Row(f=1, g=1)", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/index/i/query", body: "Row(g=1, f=1)", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/index/i/query", body: `Row(f=1, from="2019-01-01T00:00", to="2019-02-01T00:00")`, key: "etl", code: http.StatusOK},
+		{method: "GET", path: "/internal/fragment/nodes?index=i&shard=0", key: "reader", code: http.StatusOK},
+		{method: "GET", path: "/internal/fragment/nodes?index=j&shard=0", key: "reader", code: http.StatusForbidden},
+		{method: "GET", path: "/internal/fragment/data?index=i&field=f&view=standard&shard=0", key: "etl", code: http.StatusOK},
+		{method: "GET", path: "/internal/fragment/data?index=i&field=g&view=standard&shard=0", key: "etl", code: http.StatusForbidden},
+		{method: "GET", path: "/internal/shards/max", key: "etl", code: http.StatusOK},
+		{method: "GET", path: "/internal/status", key: "reader", code: http.StatusOK},
+		{method: "DELETE", path: "/index/i", key: "etl", code: http.StatusForbidden},
+		{method: "DELETE", path: "/index/i", key: "admin", code: http.StatusOK},
+		{method: "POST", path: "/recalculate-caches", key: "etl", code: http.StatusForbidden},
+		{method: "POST", path: "/recalculate-caches", key: "admin", code: http.StatusOK},
+	} {
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		r.Header.Set(APIKeyHeader, test.key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("test %d: expected status %d, got %d", i, test.code, w.Code)
+		}
+	}
+}
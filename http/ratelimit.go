@@ -0,0 +1,215 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pilosa/pilosa/v2/roaring"
+	"github.com/pkg/errors"
+)
+
+// RateLimit is the number of queries, and of bits imported, accepted per
+// second for an index. Zero means no limit.
+type RateLimit struct {
+	Query  float64
+	Import float64
+}
+
+// OptHandlerRateLimit limits the requests accepted for each index. Every
+// index has its own budget of def, unless overridden in indexes, so that a
+// busy index cannot use up the budget of the others.
+func OptHandlerRateLimit(def RateLimit, indexes map[string]RateLimit) handlerOption {
+	return func(h *Handler) error {
+		h.rateLimiter = newRateLimiter(def, indexes)
+		return nil
+	}
+}
+
+// rateLimitSweepInterval is how often buckets which have refilled are
+// removed from a rateLimiter.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter keeps a token bucket for each index and kind of request.
+// Buckets are created on first use. A full bucket behaves the same as a new
+// one, so full buckets are removed periodically; the limiter only holds
+// buckets for indexes which have had requests recently.
+type rateLimiter struct {
+	mu        sync.Mutex
+	def       RateLimit
+	indexes   map[string]RateLimit
+	buckets   map[rateLimitKey]*tokenBucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+type rateLimitKey struct {
+	index string
+	imp   bool
+}
+
+func newRateLimiter(def RateLimit, indexes map[string]RateLimit) *rateLimiter {
+	return &rateLimiter{
+		def:     def,
+		indexes: indexes,
+		buckets: make(map[rateLimitKey]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// take takes n tokens for a request against index: one for a query, or one
+// for each bit of an import. If none is available, it returns false and how
+// long to wait for the next one.
+func (l *rateLimiter) take(index string, imp bool, n float64) (bool, time.Duration) {
+	limit, ok := l.indexes[index]
+	if !ok {
+		limit = l.def
+	}
+	rate := limit.Query
+	if imp {
+		rate = limit.Import
+	}
+	if rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	key := rateLimitKey{index: index, imp: imp}
+	b := l.buckets[key]
+	if b == nil {
+		b = newTokenBucket(rate, now)
+		l.buckets[key] = b
+	}
+	return b.take(now, n)
+}
+
+// sweep removes the buckets which are full at now.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// tokenBucket holds up to one second's worth of tokens, and at least one,
+// which refill at rate per second.
+type tokenBucket struct {
+	rate   float64
+	size   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	size := math.Max(rate, 1)
+	return &tokenBucket{rate: rate, size: size, tokens: size, last: now}
+}
+
+// full returns true if the bucket will have refilled by now.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.size
+}
+
+// take takes n tokens if at least one is available. A request larger than
+// the bucket is still accepted when the bucket is full, and leaves it in
+// debt, so that later requests wait until the debt has been paid off.
+func (b *tokenBucket) take(now time.Time, n float64) (bool, time.Duration) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.size, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens -= n
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// limitRequests is middleware which rejects queries beyond the rate limit
+// of their index with 429 Too Many Requests. Imports are limited by
+// limitImport, once the number of bits they set is known. Requests which
+// nodes forward to each other were already counted by the node which
+// received them from a client, and are not limited again, if they are
+// authenticated with the node key.
+func (h *Handler) limitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.rateLimiter == nil || fromNode(r) || mux.CurrentRoute(r).GetName() != "PostQuery" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, wait := h.rateLimiter.take(mux.Vars(r)["index"], false, 1); !ok {
+			rateLimitExceeded(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitImport charges an import of n bits into index against its rate limit.
+// If the limit has been exceeded, it rejects the request with 429 Too Many
+// Requests and returns false.
+func (h *Handler) limitImport(w http.ResponseWriter, r *http.Request, index string, n uint64) bool {
+	if h.rateLimiter == nil || fromNode(r) {
+		return true
+	}
+	if ok, wait := h.rateLimiter.take(index, true, float64(n)); !ok {
+		rateLimitExceeded(w, wait)
+		return false
+	}
+	return true
+}
+
+func rateLimitExceeded(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// importBits returns the number of bits set by an import of the given
+// columns, which are given either by ID or by key.
+func importBits(ids []uint64, keys []string) uint64 {
+	if len(keys) > len(ids) {
+		return uint64(len(keys))
+	}
+	return uint64(len(ids))
+}
+
+// importRoaringBits returns the number of bits set by the views of req.
+func importRoaringBits(req *pilosa.ImportRoaringRequest) (uint64, error) {
+	var n uint64
+	for view, data := range req.Views {
+		bm := roaring.NewBitmap()
+		if err := bm.UnmarshalBinary(data); err != nil {
+			return 0, errors.Wrapf(err, "decoding view %q", view)
+		}
+		n += bm.Count()
+	}
+	return n, nil
+}
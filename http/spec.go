@@ -0,0 +1,408 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pilosa/pilosa/v2"
+	"github.com/pkg/errors"
+)
+
+// openAPISpec is the subset of an OpenAPI 3.0 document which can be derived
+// from the router.
+type openAPISpec struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Explode  *bool         `json:"explode,omitempty"`
+	Schema   openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// ref returns a reference to the schema with the given name in the spec's
+// components.
+func ref(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// Schemas which are used by several components and responses.
+var (
+	specString  = &openAPISchema{Type: "string"}
+	specInteger = &openAPISchema{Type: "integer"}
+	specBoolean = &openAPISchema{Type: "boolean"}
+	specBinary  = &openAPISchema{Type: "string", Format: "binary"}
+	specObject  = &openAPISchema{Type: "object"}
+	specArray   = &openAPISchema{Type: "array", Items: specObject}
+)
+
+// specSchemas are the schemas of the request and response bodies which are
+// described in detail.
+var specSchemas = map[string]*openAPISchema{
+	"Error": {Type: "object", Properties: map[string]*openAPISchema{
+		"error": specString,
+	}},
+	"SuccessResponse": {Type: "object", Properties: map[string]*openAPISchema{
+		"success": specBoolean,
+		"error": {Type: "object", Properties: map[string]*openAPISchema{
+			"message": specString,
+		}},
+	}},
+	"QueryRequest": {Type: "object", Properties: map[string]*openAPISchema{
+		"Query":           specString,
+		"Shards":          {Type: "array", Items: specInteger},
+		"ColumnAttrs":     specBoolean,
+		"Remote":          specBoolean,
+		"ExcludeRowAttrs": specBoolean,
+		"ExcludeColumns":  specBoolean,
+	}},
+	"QueryResponse": {Type: "object", Properties: map[string]*openAPISchema{
+		"results": {Type: "array", Items: &openAPISchema{}},
+		"columnAttrs": {Type: "array", Items: &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{
+			"id":    specInteger,
+			"key":   specString,
+			"attrs": specObject,
+		}}},
+	}},
+	"Schema": {Type: "object", Properties: map[string]*openAPISchema{
+		"indexes": {Type: "array", Items: ref("IndexInfo")},
+	}},
+	"IndexInfo": {Type: "object", Properties: map[string]*openAPISchema{
+		"name": specString,
+		"options": {Type: "object", Properties: map[string]*openAPISchema{
+			"keys":           specBoolean,
+			"trackExistence": specBoolean,
+		}},
+		"fields":     {Type: "array", Items: ref("FieldInfo")},
+		"shardWidth": specInteger,
+	}},
+	"FieldInfo": {Type: "object", Properties: map[string]*openAPISchema{
+		"name": specString,
+		"options": {Type: "object", Properties: map[string]*openAPISchema{
+			"type":           specString,
+			"cacheType":      specString,
+			"cacheSize":      specInteger,
+			"keys":           specBoolean,
+			"base":           specInteger,
+			"bitDepth":       specInteger,
+			"min":            specInteger,
+			"max":            specInteger,
+			"timeQuantum":    specString,
+			"noStandardView": specBoolean,
+		}},
+		"views": {Type: "array", Items: &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{
+			"name": specString,
+		}}},
+	}},
+	"Roles": {Type: "object", AdditionalProperties: &openAPISchema{Type: "array", Items: &openAPISchema{
+		Type: "object", Properties: map[string]*openAPISchema{
+			"index":      specString,
+			"field":      specString,
+			"permission": specString,
+		}}}},
+}
+
+// specParameters are the schemas of the parameters which are not strings.
+var specParameters = map[string]openAPISchema{
+	"shard":           {Type: "integer"},
+	"column":          {Type: "integer"},
+	"offset":          {Type: "integer"},
+	"shards":          {Type: "array", Items: specInteger},
+	"clear":           {Type: "boolean"},
+	"remote":          {Type: "boolean"},
+	"views":           {Type: "boolean"},
+	"columnAttrs":     {Type: "boolean"},
+	"excludeRowAttrs": {Type: "boolean"},
+	"excludeColumns":  {Type: "boolean"},
+	"ignoreKeyCheck":  {Type: "boolean"},
+}
+
+// parameter returns the description of a path or query parameter.
+func parameter(name, in string, required bool) openAPIParameter {
+	p := openAPIParameter{Name: name, In: in, Required: required, Schema: openAPISchema{Type: "string"}}
+	if schema, ok := specParameters[name]; ok {
+		p.Schema = schema
+	}
+	if p.Schema.Type == "array" {
+		// Lists are given as comma-separated values.
+		explode := false
+		p.Explode = &explode
+	}
+	return p
+}
+
+// routeResponse is a response which a route's handler can give.
+type routeResponse struct {
+	status      int
+	contentType string
+	schema      *openAPISchema
+}
+
+func jsonResponse(status int, schema *openAPISchema) routeResponse {
+	return routeResponse{status: status, contentType: "application/json", schema: schema}
+}
+
+func textResponse(status int) routeResponse {
+	return routeResponse{status: status, contentType: "text/plain", schema: specString}
+}
+
+func emptyResponse(status int) routeResponse {
+	return routeResponse{status: status}
+}
+
+// Responses shared by many routes.
+var (
+	notAcceptable = textResponse(http.StatusNotAcceptable)
+	unsupported   = textResponse(http.StatusUnsupportedMediaType)
+	success       = jsonResponse(http.StatusOK, ref("SuccessResponse"))
+)
+
+// failure is an error written with successResponse.
+func failure(status int) routeResponse {
+	return jsonResponse(status, ref("SuccessResponse"))
+}
+
+// routeResponses are the responses of each named route's handler. The
+// responses of the middleware are added by buildSpec.
+var routeResponses = map[string][]routeResponse{
+	"Home":                            {textResponse(http.StatusNotFound)},
+	"GetSpec":                         {jsonResponse(http.StatusOK, specObject), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"GetRoles":                        {jsonResponse(http.StatusOK, ref("Roles")), textResponse(http.StatusNotFound)},
+	"PutRoles":                        {success, failure(http.StatusBadRequest), textResponse(http.StatusNotFound), failure(http.StatusInternalServerError)},
+	"PostClusterResizeAbort":          {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"PostClusterResizeRemoveNode":     {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"PostClusterResizeSetCoordinator": {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"GetExport":                       {{status: http.StatusOK, contentType: "text/csv", schema: specString}, textResponse(http.StatusBadRequest), notAcceptable, textResponse(http.StatusPreconditionFailed), textResponse(http.StatusInternalServerError)},
+	"GetHealth":                       {success},
+	"GetReady":                        {success, failure(http.StatusServiceUnavailable)},
+	"GetIndexes":                      {jsonResponse(http.StatusOK, ref("Schema")), notAcceptable},
+	"GetSchema":                       {jsonResponse(http.StatusOK, ref("Schema")), notAcceptable},
+	"PostSchema":                      {emptyResponse(http.StatusNoContent), textResponse(http.StatusBadRequest)},
+	"GetIndex":                        {jsonResponse(http.StatusOK, ref("IndexInfo")), textResponse(http.StatusNotFound), notAcceptable},
+	"PostIndex":                       {success, failure(http.StatusBadRequest), notAcceptable, failure(http.StatusConflict), failure(http.StatusInternalServerError)},
+	"DeleteIndex":                     {success, failure(http.StatusNotFound), notAcceptable, failure(http.StatusInternalServerError)},
+	"DeleteColumn":                    {success, failure(http.StatusBadRequest), failure(http.StatusNotFound), notAcceptable, failure(http.StatusInternalServerError)},
+	"PostField":                       {success, failure(http.StatusBadRequest), failure(http.StatusNotFound), notAcceptable, failure(http.StatusConflict), failure(http.StatusInternalServerError)},
+	"DeleteField":                     {success, failure(http.StatusNotFound), notAcceptable, failure(http.StatusInternalServerError)},
+	"PostImport":                      {{status: http.StatusOK, contentType: "application/x-protobuf", schema: specBinary}, textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusPreconditionFailed), unsupported, textResponse(http.StatusInternalServerError)},
+	"PostImportRoaring":               {{status: http.StatusOK, contentType: "application/x-protobuf", schema: specBinary}, textResponse(http.StatusBadRequest), notAcceptable, unsupported, textResponse(http.StatusInternalServerError)},
+	"PostQuery":                       {jsonResponse(http.StatusOK, ref("QueryResponse")), emptyResponse(http.StatusFound), jsonResponse(http.StatusBadRequest, ref("Error")), jsonResponse(http.StatusRequestEntityTooLarge, ref("Error"))},
+	"GetInfo":                         {jsonResponse(http.StatusOK, specObject), notAcceptable},
+	"GetStatus":                       {jsonResponse(http.StatusOK, specObject), notAcceptable},
+	"GetVersion":                      {jsonResponse(http.StatusOK, specObject), notAcceptable},
+	"GetLogLevel":                     {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusNotFound)},
+	"PutLogLevel":                     {success, failure(http.StatusBadRequest), textResponse(http.StatusNotFound)},
+	"RecalculateCaches":               {emptyResponse(http.StatusNoContent), textResponse(http.StatusInternalServerError)},
+	"PostClusterMessage":              {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), notAcceptable, unsupported},
+	"GetFragmentBlockData":            {{status: http.StatusOK, contentType: "application/protobuf", schema: specBinary}, textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), textResponse(http.StatusInternalServerError)},
+	"GetFragmentBlocks":               {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"GetFragmentData":                 {{status: http.StatusOK, contentType: "application/octet-stream", schema: specBinary}, textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound)},
+	"PostFragmentData":                {success, failure(http.StatusBadRequest), failure(http.StatusNotFound), failure(http.StatusInternalServerError)},
+	"PostFragmentSync":                {success, failure(http.StatusBadRequest), failure(http.StatusNotFound), failure(http.StatusInternalServerError)},
+	"GetFragmentNodes":                {jsonResponse(http.StatusOK, specArray), textResponse(http.StatusBadRequest), notAcceptable},
+	"GetNodes":                        {jsonResponse(http.StatusOK, specArray), notAcceptable},
+	"PostIndexAttrDiff":               {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"PostFieldAttrDiff":               {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusBadRequest), textResponse(http.StatusNotFound), notAcceptable, textResponse(http.StatusInternalServerError)},
+	"PostTranslateData":               {jsonResponse(http.StatusOK, specObject), textResponse(http.StatusInternalServerError), textResponse(http.StatusNotImplemented)},
+	"PostTranslateKeys":               {{status: http.StatusOK, contentType: "application/x-protobuf", schema: specBinary}, notAcceptable, unsupported, textResponse(http.StatusInternalServerError)},
+	"GetShardsMax":                    {jsonResponse(http.StatusOK, specObject), notAcceptable},
+	"GetLocalStatus":                  {jsonResponse(http.StatusOK, specObject), notAcceptable, textResponse(http.StatusInternalServerError)},
+}
+
+// routeRequestBodies are the request bodies of the routes which are
+// described in detail.
+var routeRequestBodies = map[string]*openAPIRequestBody{
+	"PostQuery": {Required: true, Content: map[string]openAPIMediaType{
+		"text/plain":             {Schema: specString},
+		"application/x-protobuf": {Schema: ref("QueryRequest")},
+	}},
+	"PostSchema": {Required: true, Content: map[string]openAPIMediaType{
+		"application/json": {Schema: ref("Schema")},
+	}},
+	"PutRoles": {Required: true, Content: map[string]openAPIMediaType{
+		"application/json": {Schema: ref("Roles")},
+	}},
+}
+
+// pathVarRegexp matches a path variable in a route template, such as
+// "{index}", along with its optional pattern.
+var pathVarRegexp = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// buildSpec describes every named route of the router. Path parameters are
+// taken from the route template and query parameters from the route's
+// validator. Unnamed routes, such as the debug and metrics endpoints, are
+// not part of the API and are skipped.
+func (h *Handler) buildSpec() (*openAPISpec, error) {
+	spec := &openAPISpec{
+		OpenAPI:    "3.0.0",
+		Info:       openAPIInfo{Title: "Pilosa", Version: pilosa.Version},
+		Paths:      make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{Schemas: specSchemas},
+	}
+
+	// Several routes share a name, but operation IDs must be unique.
+	seen := make(map[string]int)
+
+	err := h.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		name := route.GetName()
+		if name == "" {
+			return nil
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return errors.Wrapf(err, "getting path of route %s", name)
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return errors.Wrapf(err, "getting methods of route %s", name)
+		}
+
+		var params []openAPIParameter
+		for _, m := range pathVarRegexp.FindAllStringSubmatch(tmpl, -1) {
+			params = append(params, parameter(m[1], "path", true))
+		}
+		if v, ok := h.validators[name]; ok {
+			params = append(params, v.parameters()...)
+		}
+
+		path := pathVarRegexp.ReplaceAllString(tmpl, "{$1}")
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		for _, method := range methods {
+			id := name
+			seen[name]++
+			if n := seen[name]; n > 1 {
+				id = fmt.Sprintf("%s%d", name, n)
+			}
+			spec.Paths[path][strings.ToLower(method)] = &openAPIOperation{
+				OperationID: id,
+				Parameters:  params,
+				RequestBody: routeRequestBodies[name],
+				Responses:   h.responses(name),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking routes")
+	}
+	return spec, nil
+}
+
+// responses returns the responses of the named route, including those of
+// the middleware which applies to it.
+func (h *Handler) responses(name string) map[string]openAPIResponse {
+	a := routeResponses[name]
+	if _, ok := h.validators[name]; ok {
+		a = append(a, jsonResponse(http.StatusBadRequest, ref("Error")))
+	}
+	if h.authenticator != nil && requiresCredentials(name) {
+		a = append(a, textResponse(http.StatusUnauthorized))
+		if h.authorizer != nil {
+			a = append(a, textResponse(http.StatusForbidden))
+		}
+	}
+	if h.rateLimiter != nil {
+		switch name {
+		case "PostQuery", "PostImport", "PostImportRoaring":
+			a = append(a, textResponse(http.StatusTooManyRequests))
+		}
+	}
+
+	m := make(map[string]openAPIResponse, len(a))
+	for _, resp := range a {
+		code := strconv.Itoa(resp.status)
+		if _, ok := m[code]; ok {
+			// The handler's own response is described first.
+			continue
+		}
+		r := openAPIResponse{Description: http.StatusText(resp.status)}
+		if resp.schema != nil {
+			r.Content = map[string]openAPIMediaType{resp.contentType: {Schema: resp.schema}}
+		}
+		m[code] = r
+	}
+	if len(m) == 0 {
+		m["default"] = openAPIResponse{Description: "response"}
+	}
+	return m
+}
+
+// parameters returns the query parameters accepted by s, sorted by name.
+func (s *queryValidationSpec) parameters() []openAPIParameter {
+	required := make(map[string]bool, len(s.required))
+	for _, arg := range s.required {
+		required[arg] = true
+	}
+
+	a := make([]openAPIParameter, 0, len(s.args))
+	for arg := range s.args {
+		a = append(a, parameter(arg, "query", required[arg]))
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Name < a[j].Name })
+	return a
+}
@@ -59,6 +59,7 @@ type Index struct {
 
 	logger        logger.Logger
 	snapshotQueue chan *fragment
+	storageConfig *StorageConfig
 
 	// Used for notifying holder when a field is added.
 	holder *Holder
@@ -340,6 +341,37 @@ func (i *Index) existenceField() *Field {
 	return i.existenceFld
 }
 
+// clearColumn clears columnID from every view of every field in the index,
+// including the existence field, and removes its column attributes.
+func (i *Index) clearColumn(columnID uint64) error {
+	shard := columnID / ShardWidth
+	for _, f := range i.Fields() {
+		for _, v := range f.views() {
+			frag := v.Fragment(shard)
+			if frag == nil {
+				continue
+			}
+			if _, err := frag.clearColumn(columnID); err != nil {
+				return errors.Wrapf(err, "clearing field %s view %s", f.Name(), v.name)
+			}
+		}
+	}
+
+	attrs, err := i.ColumnAttrStore().Attrs(columnID)
+	if err != nil {
+		return errors.Wrap(err, "getting column attrs")
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	// A nil value removes the attribute from the store.
+	m := make(map[string]interface{}, len(attrs))
+	for k := range attrs {
+		m[k] = nil
+	}
+	return errors.Wrap(i.ColumnAttrStore().SetAttrs(columnID, m), "removing column attrs")
+}
+
 // recalculateCaches recalculates caches on every field in the index.
 func (i *Index) recalculateCaches() {
 	for _, field := range i.Fields() {
@@ -381,10 +413,15 @@ func (i *Index) CreateFieldIfNotExists(name string, opts ...FieldOption) (*Field
 		return nil, errors.Wrap(err, "validating name")
 	}
 
+	// Find field in cache first.
+	if f := i.Field(name); f != nil {
+		return f, nil
+	}
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	// Find field in cache first.
+	// Check again in case the field was created while waiting for the lock.
 	if f := i.fields[name]; f != nil {
 		return f, nil
 	}
@@ -402,6 +439,10 @@ func (i *Index) CreateFieldIfNotExists(name string, opts ...FieldOption) (*Field
 }
 
 func (i *Index) createFieldIfNotExists(name string, opt FieldOptions) (*Field, error) {
+	if f := i.Field(name); f != nil {
+		return f, nil
+	}
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
@@ -463,6 +504,7 @@ func (i *Index) newField(path, name string) (*Field, error) {
 	f.broadcaster = i.broadcaster
 	f.rowAttrStore = i.newAttrStore(filepath.Join(f.path, ".data"))
 	f.snapshotQueue = i.snapshotQueue
+	f.storageConfig = i.storageConfig
 	f.OpenTranslateStore = i.OpenTranslateStore
 	return f, nil
 }
@@ -206,7 +206,7 @@ func TestIndex_DeleteField(t *testing.T) {
 
 	// Delete again to make sure it errors.
 	err := index.DeleteField("f")
-	if !isNotFoundError(err) {
+	if !isNotFoundError(err) || errors.Cause(err) != pilosa.ErrFieldNotFound {
 		t.Fatalf("expected 'field not found' error, got: %#v", err)
 	}
 }
@@ -227,7 +227,6 @@ func TestIndex_InvalidName(t *testing.T) {
 }
 
 func isNotFoundError(err error) bool {
-	root := errors.Cause(err)
-	_, ok := root.(pilosa.NotFoundError)
+	_, ok := err.(pilosa.NotFoundError)
 	return ok
 }
@@ -15,9 +15,15 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Ensure nopLogger implements interface.
@@ -27,6 +33,11 @@ var _ Logger = &nopLogger{}
 type Logger interface {
 	Printf(format string, v ...interface{})
 	Debugf(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+
+	// With returns a Logger which adds key and value to every message.
+	With(key string, value interface{}) Logger
 }
 
 // NopLogger represents a Logger that doesn't do anything.
@@ -40,6 +51,15 @@ func (n *nopLogger) Printf(format string, v ...interface{}) {}
 // Debugf is a no-op implementation of the Logger Debugf method.
 func (n *nopLogger) Debugf(format string, v ...interface{}) {}
 
+// Warnf is a no-op implementation of the Logger Warnf method.
+func (n *nopLogger) Warnf(format string, v ...interface{}) {}
+
+// Errorf is a no-op implementation of the Logger Errorf method.
+func (n *nopLogger) Errorf(format string, v ...interface{}) {}
+
+// With returns n.
+func (n *nopLogger) With(key string, value interface{}) Logger { return n }
+
 // standardLogger is a basic implementation of Logger based on log.Logger.
 type standardLogger struct {
 	logger *log.Logger
@@ -57,6 +77,18 @@ func (s *standardLogger) Printf(format string, v ...interface{}) {
 
 func (s *standardLogger) Debugf(format string, v ...interface{}) {}
 
+func (s *standardLogger) Warnf(format string, v ...interface{}) {
+	s.logger.Printf(warnPrefix+format, v...)
+}
+
+func (s *standardLogger) Errorf(format string, v ...interface{}) {
+	s.logger.Printf(errorPrefix+format, v...)
+}
+
+func (s *standardLogger) With(key string, value interface{}) Logger {
+	return newFieldLogger(s, key, value)
+}
+
 func (s *standardLogger) Logger() *log.Logger {
 	return s.logger
 }
@@ -80,10 +112,242 @@ func (vb *verboseLogger) Debugf(format string, v ...interface{}) {
 	vb.logger.Printf(format, v...)
 }
 
+func (vb *verboseLogger) Warnf(format string, v ...interface{}) {
+	vb.logger.Printf(warnPrefix+format, v...)
+}
+
+func (vb *verboseLogger) Errorf(format string, v ...interface{}) {
+	vb.logger.Printf(errorPrefix+format, v...)
+}
+
+func (vb *verboseLogger) With(key string, value interface{}) Logger {
+	return newFieldLogger(vb, key, value)
+}
+
 func (vb *verboseLogger) Logger() *log.Logger {
 	return vb.logger
 }
 
+// Prefixes of warning and error messages in plain text logs.
+const (
+	warnPrefix  = "WARN: "
+	errorPrefix = "ERROR: "
+)
+
+// fieldLogger adds fields to the messages of a Logger which only writes
+// plain text, as key=value pairs after the message.
+type fieldLogger struct {
+	logger Logger
+	fields string
+}
+
+func newFieldLogger(l Logger, key string, value interface{}) *fieldLogger {
+	return &fieldLogger{logger: l, fields: formatField(key, value)}
+}
+
+func (l *fieldLogger) Printf(format string, v ...interface{}) {
+	l.logger.Printf("%s%s", fmt.Sprintf(format, v...), l.fields)
+}
+
+func (l *fieldLogger) Debugf(format string, v ...interface{}) {
+	l.logger.Debugf("%s%s", fmt.Sprintf(format, v...), l.fields)
+}
+
+func (l *fieldLogger) Warnf(format string, v ...interface{}) {
+	l.logger.Warnf("%s%s", fmt.Sprintf(format, v...), l.fields)
+}
+
+func (l *fieldLogger) Errorf(format string, v ...interface{}) {
+	l.logger.Errorf("%s%s", fmt.Sprintf(format, v...), l.fields)
+}
+
+func (l *fieldLogger) With(key string, value interface{}) Logger {
+	return &fieldLogger{logger: l.logger, fields: l.fields + formatField(key, value)}
+}
+
+// formatField formats a field for a plain text message. Values containing
+// spaces or quotes are quoted.
+func formatField(key string, value interface{}) string {
+	s := fmt.Sprint(value)
+	if strings.ContainsAny(s, " \t\"=") {
+		s = strconv.Quote(s)
+	}
+	return " " + key + "=" + s
+}
+
+// Level is the lowest severity of the messages a LevelLogger writes.
+type Level int32
+
+// Log levels, from the most verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel returns the level with the given name.
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %q, choose from [debug, info, warn, error]", s)
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
+// LevelLogger is an implementation of Logger whose level can be changed
+// while it is in use. It writes either plain text, like standardLogger, or
+// one JSON object per line, in which the fields added with With are keys.
+type LevelLogger struct {
+	level  *int32 // accessed atomically, shared with loggers made by With
+	json   bool
+	logger *log.Logger
+	fields []logField
+}
+
+type logField struct {
+	key   string
+	value interface{}
+}
+
+// NewLevelLogger returns a LevelLogger which writes messages of level and
+// above to w, as JSON if json is set.
+func NewLevelLogger(w io.Writer, level Level, json bool) *LevelLogger {
+	lvl := int32(level)
+	l := &LevelLogger{level: &lvl, json: json}
+	if json {
+		l.logger = log.New(w, "", 0)
+	} else {
+		l.logger = log.New(w, "", log.LstdFlags)
+	}
+	return l
+}
+
+// Level returns the current level.
+func (l *LevelLogger) Level() Level {
+	return Level(atomic.LoadInt32(l.level))
+}
+
+// SetLevel changes the level of the messages which are written, by l and
+// by every logger derived from the same LevelLogger with With.
+func (l *LevelLogger) SetLevel(level Level) {
+	atomic.StoreInt32(l.level, int32(level))
+}
+
+// Printf writes a message at info level.
+func (l *LevelLogger) Printf(format string, v ...interface{}) {
+	l.logf(LevelInfo, format, v...)
+}
+
+// Debugf writes a message at debug level.
+func (l *LevelLogger) Debugf(format string, v ...interface{}) {
+	l.logf(LevelDebug, format, v...)
+}
+
+// Warnf writes a message at warn level.
+func (l *LevelLogger) Warnf(format string, v ...interface{}) {
+	l.logf(LevelWarn, format, v...)
+}
+
+// Errorf writes a message at error level.
+func (l *LevelLogger) Errorf(format string, v ...interface{}) {
+	l.logf(LevelError, format, v...)
+}
+
+// With returns a LevelLogger which adds key and value to every message. It
+// shares its level and output with l.
+func (l *LevelLogger) With(key string, value interface{}) Logger {
+	other := *l
+	other.fields = append(l.fields[:len(l.fields):len(l.fields)], logField{key: key, value: value})
+	return &other
+}
+
+// Logger returns a log.Logger which writes to the same output at info level.
+// Its messages are dropped while the level is above info.
+func (l *LevelLogger) Logger() *log.Logger {
+	return log.New(levelWriter{l}, "", 0)
+}
+
+func (l *LevelLogger) logf(level Level, format string, v ...interface{}) {
+	if level < l.Level() {
+		return
+	}
+	l.output(level, fmt.Sprintf(format, v...))
+}
+
+func (l *LevelLogger) output(level Level, msg string) {
+	msg = strings.TrimSuffix(msg, "\n")
+	if !l.json {
+		switch level {
+		case LevelWarn:
+			msg = warnPrefix + msg
+		case LevelError:
+			msg = errorPrefix + msg
+		}
+		for _, f := range l.fields {
+			msg += formatField(f.key, f.value)
+		}
+		l.logger.Output(4, msg) // nolint: errcheck
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, time.Now().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, msg)
+	for _, f := range l.fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, f.key)
+		buf.WriteByte(':')
+		writeJSON(&buf, f.value)
+	}
+	buf.WriteByte('}')
+	l.logger.Output(4, buf.String()) // nolint: errcheck
+}
+
+// writeJSON writes v to buf as JSON. Values which cannot be encoded are
+// written as strings.
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+// levelWriter writes each line written to it as a message at info level.
+type levelWriter struct {
+	l *LevelLogger
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	w.l.logf(LevelInfo, "%s", p)
+	return len(p), nil
+}
+
 // CaptureLogger is a logger that stores all the print and debug messages
 // it sees, useful for testing.
 type CaptureLogger struct {
@@ -105,3 +369,19 @@ func (cl *CaptureLogger) Printf(format string, v ...interface{}) {
 func (cl *CaptureLogger) Debugf(format string, v ...interface{}) {
 	cl.Debugs = append(cl.Debugs, fmt.Sprintf(format, v...))
 }
+
+// Warnf formats a message and appends it to Prints.
+func (cl *CaptureLogger) Warnf(format string, v ...interface{}) {
+	cl.Prints = append(cl.Prints, warnPrefix+fmt.Sprintf(format, v...))
+}
+
+// Errorf formats a message and appends it to Prints.
+func (cl *CaptureLogger) Errorf(format string, v ...interface{}) {
+	cl.Prints = append(cl.Prints, errorPrefix+fmt.Sprintf(format, v...))
+}
+
+// With returns a Logger which adds key and value to the messages it
+// appends to cl.
+func (cl *CaptureLogger) With(key string, value interface{}) Logger {
+	return newFieldLogger(cl, key, value)
+}
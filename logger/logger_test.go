@@ -0,0 +1,128 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2/logger"
+)
+
+func TestLevelLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLevelLogger(&buf, logger.LevelInfo, true)
+
+	l.Debugf("hidden")
+	l.Printf("shown %d", 1)
+	l.SetLevel(logger.LevelDebug)
+	l.Debugf("shown %d", 2)
+	l.Logger().Println("shown 3")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	for i, exp := range []struct{ level, msg string }{
+		{"info", "shown 1"},
+		{"debug", "shown 2"},
+		{"info", "shown 3"},
+	} {
+		var m map[string]string
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+			t.Fatalf("decoding %q: %v", lines[i], err)
+		} else if m["level"] != exp.level || m["msg"] != exp.msg || m["time"] == "" {
+			t.Fatalf("unexpected line %d: %q", i, lines[i])
+		}
+	}
+}
+
+func TestLevelLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLevelLogger(&buf, logger.LevelWarn, false)
+
+	l.Debugf("hidden")
+	l.Printf("hidden")
+	l.Warnf("shown %d", 1)
+	l.Errorf("shown %d", 2)
+	l.SetLevel(logger.LevelError)
+	l.Warnf("hidden")
+	l.Logger().Println("hidden")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	} else if !strings.HasSuffix(lines[0], "WARN: shown 1") {
+		t.Fatalf("unexpected line 0: %q", lines[0])
+	} else if !strings.HasSuffix(lines[1], "ERROR: shown 2") {
+		t.Fatalf("unexpected line 1: %q", lines[1])
+	}
+}
+
+func TestLevelLogger_With(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := logger.NewLevelLogger(&buf, logger.LevelInfo, true)
+		fl := l.With("index", "i").With("shard", 3)
+
+		// Loggers made by With follow the level of their parent.
+		fl.Debugf("hidden")
+		l.SetLevel(logger.LevelDebug)
+		fl.Debugf("shown")
+		l.Printf("plain")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("unexpected output:\n%s", buf.String())
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+			t.Fatalf("decoding %q: %v", lines[0], err)
+		} else if m["msg"] != "shown" || m["index"] != "i" || m["shard"] != float64(3) {
+			t.Fatalf("unexpected line 0: %q", lines[0])
+		}
+		m = nil
+		if err := json.Unmarshal([]byte(lines[1]), &m); err != nil {
+			t.Fatalf("decoding %q: %v", lines[1], err)
+		} else if _, ok := m["index"]; ok {
+			t.Fatalf("unexpected field in line 1: %q", lines[1])
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := logger.NewLevelLogger(&buf, logger.LevelInfo, false)
+		l.With("index", "i").With("path", "a b").Errorf("failed")
+
+		if s := strings.TrimSpace(buf.String()); !strings.HasSuffix(s, `ERROR: failed index=i path="a b"`) {
+			t.Fatalf("unexpected output: %q", s)
+		}
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"debug", "info", "warn", "error"} {
+		if level, err := logger.ParseLevel(s); err != nil {
+			t.Fatal(err)
+		} else if level.String() != s {
+			t.Fatalf("unexpected level: %s", level)
+		}
+	}
+	if _, err := logger.ParseLevel("trace"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -21,18 +21,20 @@ import (
 )
 
 var _ pilosa.TranslateStore = (*TranslateStore)(nil)
+var _ pilosa.TranslateKeyScanner = (*TranslateStore)(nil)
 
 type TranslateStore struct {
-	CloseFunc         func() error
-	MaxIDFunc         func() (uint64, error)
-	ReadOnlyFunc      func() bool
-	SetReadOnlyFunc   func(v bool)
-	TranslateKeyFunc  func(key string) (uint64, error)
-	TranslateKeysFunc func(keys []string) ([]uint64, error)
-	TranslateIDFunc   func(id uint64) (string, error)
-	TranslateIDsFunc  func(ids []uint64) ([]string, error)
-	ForceSetFunc      func(id uint64, key string) error
-	EntryReaderFunc   func(ctx context.Context, offset uint64) (pilosa.TranslateEntryReader, error)
+	CloseFunc          func() error
+	MaxIDFunc          func() (uint64, error)
+	ReadOnlyFunc       func() bool
+	SetReadOnlyFunc    func(v bool)
+	TranslateKeyFunc   func(key string) (uint64, error)
+	TranslateKeysFunc  func(keys []string) ([]uint64, error)
+	TranslateIDFunc    func(id uint64) (string, error)
+	TranslateIDsFunc   func(ids []uint64) ([]string, error)
+	KeysWithPrefixFunc func(prefix string) ([]pilosa.TranslateEntry, error)
+	ForceSetFunc       func(id uint64, key string) error
+	EntryReaderFunc    func(ctx context.Context, offset uint64) (pilosa.TranslateEntryReader, error)
 }
 
 func (s *TranslateStore) Close() error {
@@ -67,6 +69,10 @@ func (s *TranslateStore) TranslateIDs(ids []uint64) ([]string, error) {
 	return s.TranslateIDsFunc(ids)
 }
 
+func (s *TranslateStore) KeysWithPrefix(prefix string) ([]pilosa.TranslateEntry, error) {
+	return s.KeysWithPrefixFunc(prefix)
+}
+
 func (s *TranslateStore) ForceSet(id uint64, key string) error {
 	return s.ForceSetFunc(id, key)
 }
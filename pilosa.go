@@ -108,11 +108,22 @@ func newConflictError(err error) ConflictError {
 
 // NotFoundError wraps an error value to signify that a resource was not found
 // such that in an HTTP scenario, http.StatusNotFound would be returned.
-type NotFoundError error
+type NotFoundError struct {
+	error
+
+	// Name is the name of the resource which could not be found.
+	Name string
+}
 
 // newNotFoundError returns err wrapped in a NotFoundError.
 func newNotFoundError(err error, name string) NotFoundError {
-	return NotFoundError(errors.WithMessage(err, name))
+	return NotFoundError{error: errors.WithMessage(err, name), Name: name}
+}
+
+// Cause returns the underlying sentinel error (e.g. ErrIndexNotFound) so that
+// comparisons against errors.Cause continue to work for wrapped values.
+func (e NotFoundError) Cause() error {
+	return errors.Cause(e.error)
 }
 
 // Regular expression to validate index and field names.
@@ -369,6 +369,19 @@ func (c *Call) UintSliceArg(key string) ([]uint64, bool, error) {
 			ret[i] = uint64(v)
 		}
 		return ret, true, nil
+	case []interface{}:
+		ret := make([]uint64, len(tval))
+		for i, v := range tval {
+			switch v := v.(type) {
+			case int64:
+				ret[i] = uint64(v)
+			case uint64:
+				ret[i] = v
+			default:
+				return nil, true, fmt.Errorf("unexpected type %T in UintSliceArg list, val %v", v, v)
+			}
+		}
+		return ret, true, nil
 	default:
 		return nil, true, fmt.Errorf("unexpected type %T in UintSliceArg, val %v", tval, tval)
 	}
@@ -390,6 +403,24 @@ func (c *Call) CallArg(key string) (*Call, bool, error) {
 	}
 }
 
+// Walk traverses c and its descendants in depth-first order, calling fn
+// for each call. Descendants are the call's children followed by any
+// calls passed as arguments, in argument key order. The descendants of a
+// call are skipped if fn returns false for it.
+func (c *Call) Walk(fn func(*Call) bool) {
+	if c == nil || !fn(c) {
+		return
+	}
+	for _, child := range c.Children {
+		child.Walk(fn)
+	}
+	for _, key := range c.keys() {
+		if arg, ok := c.Args[key].(*Call); ok {
+			arg.Walk(fn)
+		}
+	}
+}
+
 // keys returns a list of argument keys in sorted order.
 func (c *Call) keys() []string {
 	a := make([]string, 0, len(c.Args))
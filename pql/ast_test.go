@@ -43,6 +43,35 @@ func TestCall_String(t *testing.T) {
 	})
 }
 
+// Ensure a call tree is walked in depth-first order.
+func TestCall_Walk(t *testing.T) {
+	q, err := pql.ParseString(`GroupBy(Rows(a), Rows(b), filter=Union(Row(c=1), Row(d=2)))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("All", func(t *testing.T) {
+		var names []string
+		q.Calls[0].Walk(func(c *pql.Call) bool {
+			names = append(names, c.Name)
+			return true
+		})
+		if exp := []string{"GroupBy", "Rows", "Rows", "Union", "Row", "Row"}; !reflect.DeepEqual(names, exp) {
+			t.Fatalf("unexpected calls: %v", names)
+		}
+	})
+	t.Run("Skip", func(t *testing.T) {
+		var names []string
+		q.Calls[0].Walk(func(c *pql.Call) bool {
+			names = append(names, c.Name)
+			return c.Name != "Union"
+		})
+		if exp := []string{"GroupBy", "Rows", "Rows", "Union"}; !reflect.DeepEqual(names, exp) {
+			t.Fatalf("unexpected calls: %v", names)
+		}
+	})
+}
+
 // Ensure condition can handle values for BETWEEN operator.
 func TestCondition_Value(t *testing.T) {
 	t.Run("Between Values", func(t *testing.T) {
@@ -942,10 +942,13 @@ func (b *Bitmap) Xor(other *Bitmap) *Bitmap {
 	return output
 }
 
-// Shift shifts the contents of b by 1.
+// Shift shifts the contents of b by n. Values shifted past the maximum
+// value are dropped.
 func (b *Bitmap) Shift(n int) (*Bitmap, error) {
-	if n != 1 {
-		return nil, errors.New("cannot shift by a value other than 1")
+	if n < 0 {
+		return nil, errors.New("cannot shift by negative values")
+	} else if n != 1 {
+		return b.shiftMulti(uint64(n)), nil
 	}
 	output := NewBitmap()
 	iiter, _ := b.Containers.Iterator(0)
@@ -978,6 +981,41 @@ func (b *Bitmap) Shift(n int) (*Bitmap, error) {
 	return output, nil
 }
 
+// shiftMulti shifts the contents of b by n in a single pass. Whole containers
+// move to a new key, and the remaining bit offset splits each container
+// between its new key and the key after it.
+func (b *Bitmap) shiftMulti(n uint64) *Bitmap {
+	output := NewBitmap()
+	keyN, r := n>>16, uint16(n&0xFFFF)
+	if keyN > maxContainerKey {
+		return output
+	}
+
+	put := func(key uint64, c *Container) {
+		if c == nil || c.N() == 0 {
+			return
+		}
+		if existing := output.Containers.Get(key); existing != nil {
+			c = union(existing, c)
+		}
+		output.Containers.Put(key, c)
+	}
+
+	citer, _ := b.Containers.Iterator(0)
+	for citer.Next() {
+		key, c := citer.Value()
+		if key > maxContainerKey-keyN {
+			break
+		}
+		lo, hi := shiftN(c, r)
+		put(key+keyN, lo)
+		if key+keyN < maxContainerKey {
+			put(key+keyN+1, hi)
+		}
+	}
+	return output
+}
+
 // removeEmptyContainers deletes all containers that have a count of zero.
 func (b *Bitmap) removeEmptyContainers() {
 	citer, _ := b.Containers.Iterator(0)
@@ -4646,6 +4684,75 @@ func shiftRun(a *Container) (*Container, bool) {
 	return NewContainerRun(ro), carry
 }
 
+// shiftN shifts the values in c up by r. It returns the values which remain
+// within the container in lo, and the values which overflow into the next
+// container in hi.
+func shiftN(c *Container, r uint16) (lo, hi *Container) {
+	if c.N() == 0 {
+		return nil, nil
+	} else if r == 0 {
+		return c.Clone(), nil
+	}
+	if c.isArray() {
+		return shiftArrayN(c, r)
+	} else if c.isRun() {
+		return shiftRunN(c, r)
+	}
+	return shiftBitmapN(c, r)
+}
+
+// shiftArrayN is an array-specific implementation of shiftN().
+func shiftArrayN(a *Container, r uint16) (lo, hi *Container) {
+	statsHit("shiftN/Array")
+	aa := a.array()
+	i := sort.Search(len(aa), func(i int) bool { return aa[i] > maxContainerVal-r })
+	loa, hia := make([]uint16, i), make([]uint16, len(aa)-i)
+	for j, v := range aa[:i] {
+		loa[j] = v + r
+	}
+	for j, v := range aa[i:] {
+		hia[j] = v + r // wraps into the next container
+	}
+	return NewContainerArray(loa), NewContainerArray(hia)
+}
+
+// shiftRunN is a run-specific implementation of shiftN().
+func shiftRunN(a *Container, r uint16) (lo, hi *Container) {
+	statsHit("shiftN/Run")
+	var lor, hir []interval16
+	for _, v := range a.runs() {
+		switch {
+		case v.last <= maxContainerVal-r:
+			lor = append(lor, interval16{start: v.start + r, last: v.last + r})
+		case v.start > maxContainerVal-r:
+			hir = append(hir, interval16{start: v.start + r, last: v.last + r})
+		default:
+			lor = append(lor, interval16{start: v.start + r, last: maxContainerVal})
+			hir = append(hir, interval16{start: 0, last: v.last + r})
+		}
+	}
+	return NewContainerRun(lor), NewContainerRun(hir)
+}
+
+// shiftBitmapN is a bitmap-specific implementation of shiftN().
+func shiftBitmapN(a *Container, r uint16) (lo, hi *Container) {
+	statsHit("shiftN/Bitmap")
+	words, bitN := int(r/64), r%64
+	out := make([]uint64, bitmapN*2)
+	for i, v := range a.bitmap() {
+		if v == 0 {
+			continue
+		}
+		out[i+words] |= v << bitN
+		if bitN > 0 {
+			out[i+words+1] |= v >> (64 - bitN)
+		}
+	}
+	lo = NewContainerBitmap(-1, out[:bitmapN]).optimize()
+	hi = NewContainerBitmap(-1, out[bitmapN:]).optimize()
+	return lo, hi
+}
+
 // opType represents a type of operation.
 type opType uint8
 
@@ -28,6 +28,7 @@ import (
 	"github.com/pilosa/pilosa/v2"
 	"github.com/pilosa/pilosa/v2/roaring"
 	_ "github.com/pilosa/pilosa/v2/test"
+	"github.com/pkg/errors"
 )
 
 func TestContainerCount(t *testing.T) {
@@ -1117,6 +1118,40 @@ func TestBitmap_Shift(t *testing.T) {
 	}
 }
 
+// Ensure shifting by more than one bit matches shifting each value.
+func TestBitmap_ShiftN(t *testing.T) {
+	var max uint64 = math.MaxUint64
+	values := []uint64{0, 1, 2, 100, 65535, 65536, 65537, 131071, 1 << 20, max - 70000, max - 1, max}
+	// Add a run and a dense range so that every container type is shifted.
+	for v := uint64(300000); v < 310000; v++ {
+		values = append(values, v)
+	}
+	for v := uint64(500000); v < 600000; v += 2 {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	bm := roaring.NewFileBitmap(values...)
+	bm.Optimize()
+
+	for _, n := range []int{0, 1, 2, 63, 64, 65, 1000, 65535, 65536, 65537, 3*65536 + 17} {
+		var exp []uint64
+		for _, v := range values {
+			if v <= max-uint64(n) {
+				exp = append(exp, v+uint64(n))
+			}
+		}
+		if got, err := bm.Shift(n); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Slice(), exp) {
+			t.Fatalf("shift by %d: unexpected bitmap of %d values, expected %d", n, got.Count(), len(exp))
+		}
+	}
+
+	if _, err := bm.Shift(-1); err == nil {
+		t.Fatal("expected error shifting by a negative value")
+	}
+}
+
 func TestBitmap_Quick_Array1(t *testing.T)     { testBitmapQuick(t, 1000, 1000, 2000) }
 func TestBitmap_Quick_Array2(t *testing.T)     { testBitmapQuick(t, 10000, 0, 1000) }
 func TestBitmap_Quick_Bitmap1(t *testing.T)    { testBitmapQuick(t, 10000, 0, 10000) }
@@ -1274,6 +1309,38 @@ func testBitmapMarshalQuick(t *testing.T, n int, min, max uint64, sorted bool) {
 	}
 }
 
+// Ensure a corrupt op is reported with its offset, keeping the ops before it.
+func TestBitmap_UnmarshalBinary_OpLogError(t *testing.T) {
+	bm := roaring.NewBitmap(1, 2, 3)
+	var buf bytes.Buffer
+	if _, err := bm.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bm.OpWriter = &buf
+	if _, err := bm.Add(100); err != nil {
+		t.Fatal(err)
+	}
+	offset := buf.Len()
+	if _, err := bm.Add(200); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the checksum of the last op.
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+
+	bm2 := roaring.NewFileBitmap()
+	err := bm2.UnmarshalBinary(data)
+	if e, ok := errors.Cause(err).(*roaring.OpLogError); !ok {
+		t.Fatalf("expected OpLogError, got %v", err)
+	} else if e.Offset != int64(offset) {
+		t.Fatalf("unexpected offset: %d, expected %d", e.Offset, offset)
+	}
+	if got, exp := bm2.Slice(), []uint64{1, 2, 3, 100}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: %v", got)
+	}
+}
+
 // Ensure iterator can iterate over all the values on the bitmap.
 // TODO duplicate for all container types
 func TestIterator(t *testing.T) {
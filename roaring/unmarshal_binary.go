@@ -22,6 +22,19 @@ import (
 	"github.com/pkg/errors"
 )
 
+// OpLogError is returned when the op log of a Pilosa roaring file is corrupt
+// or truncated. The bitmap still holds the containers of the file and the ops
+// before the bad one.
+type OpLogError struct {
+	// Offset is the position of the bad op in the data.
+	Offset int64
+	Err    error
+}
+
+func (e *OpLogError) Error() string {
+	return fmt.Sprintf("op log at offset %d: %s", e.Offset, e.Err)
+}
+
 // UnmarshalBinary decodes b from a binary-encoded byte slice. data can be in
 // either official roaring format or Pilosa's roaring format.
 func (b *Bitmap) UnmarshalBinary(data []byte) error {
@@ -214,8 +227,7 @@ func (b *Bitmap) unmarshalPilosaRoaring(data []byte) error {
 		// Unmarshal the op and apply it.
 		var opr op
 		if err := opr.UnmarshalBinary(buf); err != nil {
-			// FIXME(benbjohnson): return error with position so file can be trimmed.
-			return err
+			return &OpLogError{Offset: opsOffset, Err: err}
 		}
 		opr.apply(b)
 		// Increase the op count.
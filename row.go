@@ -221,21 +221,16 @@ func (r *Row) Shift(n int64) (*Row, error) {
 		return r, nil
 	}
 
-	work := r
-	var segments []rowSegment
-	for i := int64(0); i < n; i++ {
-		segments = segments[:0]
-		for _, segment := range work.segments {
-			shifted, err := segment.Shift()
-			if err != nil {
-				return nil, errors.Wrap(err, "shifting row segment")
-			}
-			segments = append(segments, *shifted)
+	segments := make([]rowSegment, 0, len(r.segments))
+	for _, segment := range r.segments {
+		shifted, err := segment.Shift(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "shifting row segment")
 		}
-		work = &Row{segments: segments}
+		segments = append(segments, *shifted)
 	}
 
-	return work, nil
+	return &Row{segments: segments}, nil
 }
 
 // SetBit sets the i-th column of the row.
@@ -419,10 +414,10 @@ func (s *rowSegment) Xor(other *rowSegment) *rowSegment {
 	}
 }
 
-// Shift returns s shifted by 1 bit.
-func (s *rowSegment) Shift() (*rowSegment, error) {
+// Shift returns s shifted by n bits.
+func (s *rowSegment) Shift(n int64) (*rowSegment, error) {
 	//TODO deal with overflow
-	data, err := s.data.Shift(1)
+	data, err := s.data.Shift(int(n))
 	if err != nil {
 		return nil, errors.Wrap(err, "shifting roaring data")
 	}
@@ -17,7 +17,6 @@ package pilosa
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -69,6 +68,7 @@ type Server struct { // nolint: maligned
 	metricInterval      time.Duration
 	diagnosticInterval  time.Duration
 	maxWritesPerRequest int
+	queryTimeout        time.Duration
 	isCoordinator       bool
 	syncer              holderSyncer
 
@@ -140,6 +140,24 @@ func OptServerLongQueryTime(dur time.Duration) ServerOption {
 	}
 }
 
+// OptServerQueryTimeout is a functional option on Server
+// used to set the maximum duration of a single query.
+func OptServerQueryTimeout(dur time.Duration) ServerOption {
+	return func(s *Server) error {
+		s.queryTimeout = dur
+		return nil
+	}
+}
+
+// OptServerStorageConfig is a functional option on Server
+// used to set the options for tuning fragment storage.
+func OptServerStorageConfig(c *StorageConfig) ServerOption {
+	return func(s *Server) error {
+		s.holder.storageConfig = c
+		return nil
+	}
+}
+
 // OptServerMaxWritesPerRequest is a functional option on Server
 // used to set the maximum number of writes allowed per request.
 func OptServerMaxWritesPerRequest(n int) ServerOption {
@@ -373,6 +391,7 @@ func NewServer(opts ...ServerOption) (*Server, error) {
 	s.executor.Node = node
 	s.executor.Cluster = s.cluster
 	s.executor.MaxWritesPerRequest = s.maxWritesPerRequest
+	s.executor.QueryTimeout = s.queryTimeout
 	s.cluster.broadcaster = s
 	s.cluster.maxWritesPerRequest = s.maxWritesPerRequest
 	s.holder.broadcaster = s
@@ -397,7 +416,7 @@ func (s *Server) UpAndDown() error {
 	// Log startup
 	err := s.holder.logStartup()
 	if err != nil {
-		log.Println(errors.Wrap(err, "logging startup"))
+		s.logger.Printf("logging startup: %v", err)
 	}
 
 	// Open holder.
@@ -420,7 +439,7 @@ func (s *Server) Open() error {
 	// Log startup
 	err := s.holder.logStartup()
 	if err != nil {
-		log.Println(errors.Wrap(err, "logging startup"))
+		s.logger.Printf("logging startup: %v", err)
 	}
 
 	// Open Cluster management.
@@ -851,6 +870,9 @@ func (s *Server) monitorRuntime() {
 		s.holder.Stats.Gauge("StackInuse", float64(m.StackInuse), 1.0)
 		s.holder.Stats.Gauge("Mallocs", float64(m.Mallocs), 1.0)
 		s.holder.Stats.Gauge("Frees", float64(m.Frees), 1.0)
+
+		// Number of fragments waiting to be snapshotted.
+		s.holder.Stats.Gauge("SnapshotQueue", float64(len(s.holder.snapshotQueue)), 1.0)
 	}
 }
 
@@ -19,12 +19,13 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/pilosa/pilosa/v2"
 	"github.com/pilosa/pilosa/v2/gossip"
+	"github.com/pilosa/pilosa/v2/http"
 	"github.com/pilosa/pilosa/v2/toml"
 	"github.com/pkg/errors"
 	jaeger "github.com/uber/jaeger-client-go"
@@ -63,18 +64,79 @@ type Config struct {
 	// SetRowAttrs & SetColumnAttrs.
 	MaxWritesPerRequest int `toml:"max-writes-per-request"`
 
+	// QueryTimeout limits how long a single query may run before it is
+	// cancelled on every node involved. Zero disables the limit.
+	QueryTimeout toml.Duration `toml:"query-timeout"`
+
 	// LogPath configures where Pilosa will write logs.
 	LogPath string `toml:"log-path"`
 
 	// Verbose toggles verbose logging which can be useful for debugging.
+	// The level can also be changed while the server runs.
 	Verbose bool `toml:"verbose"`
 
+	// LogFormat is the format of the log: "text", or "json" for one JSON
+	// object per line.
+	LogFormat string `toml:"log-format"`
+
 	// HTTP Handler options
 	Handler struct {
 		// CORS Allowed Origins
 		AllowedOrigins []string `toml:"allowed-origins"`
+
+		// AuditLogPath is the file to which mutating requests are
+		// recorded, one JSON object per line. Empty disables the audit log.
+		AuditLogPath string `toml:"audit-log-path"`
+
+		// AuditLogMaxSize is the size in megabytes past which the audit
+		// log is rotated. Zero never rotates it.
+		AuditLogMaxSize int `toml:"audit-log-max-size"`
+
+		// AuditLogMaxBackups is the number of rotated audit logs kept.
+		AuditLogMaxBackups int `toml:"audit-log-max-backups"`
 	} `toml:"handler"`
 
+	// RateLimit limits the number of queries, and of bits imported, per
+	// second accepted for each index. Zero means no limit.
+	RateLimit struct {
+		Query  float64 `toml:"query"`
+		Import float64 `toml:"import"`
+
+		// IndexQuery and IndexImport override the limits for individual
+		// indexes, as "index=rate" pairs.
+		IndexQuery  []string `toml:"index-query"`
+		IndexImport []string `toml:"index-import"`
+	} `toml:"rate-limit"`
+
+	// Auth requires clients to authenticate with an API key or a JWT.
+	Auth struct {
+		Enable bool `toml:"enable"`
+
+		// APIKeys are the accepted API keys, as "key=role" pairs. A key
+		// may be listed more than once to grant it several roles.
+		APIKeys []string `toml:"api-keys"`
+
+		// JWTSecret is the shared secret with which bearer tokens are
+		// signed, using HS256. Bearer tokens are refused if it is empty.
+		JWTSecret string `toml:"jwt-secret"`
+
+		// RolesClaim is the JWT claim holding the token's roles.
+		RolesClaim string `toml:"roles-claim"`
+
+		// Grants bind permissions to roles, as "role=index:permission" or
+		// "role=index/field:permission" entries. The index may be "*".
+		Grants []string `toml:"grants"`
+
+		// RolesPath is the file in which role bindings changed through the
+		// API are kept. Once it exists it takes precedence over Grants.
+		RolesPath string `toml:"roles-path"`
+
+		// NodeKey is the API key which nodes send to each other. It is
+		// granted the admin role, and must be the same on every node. It
+		// is required when auth is enabled.
+		NodeKey string `toml:"node-key"`
+	} `toml:"auth"`
+
 	// MaxMapCount puts an in-process limit on the number of mmaps. After this
 	// is exhausted, Pilosa will fall back to reading the file into memory
 	// normally.
@@ -91,18 +153,14 @@ type Config struct {
 	TLS TLSConfig `toml:"tls"`
 
 	// WorkerPoolSize controls how many goroutines are created for
-	// processing queries. Defaults to runtime.NumCPU(). It is
-	// intentionally not defined as a flag... only exposed here so
-	// that we can limit the size while running tests in CI so we
-	// don't exhaust the goroutine limit.
-	WorkerPoolSize int
+	// processing queries. Zero, the default, means the number of CPUs of
+	// the machine the server starts on.
+	WorkerPoolSize int `toml:"worker-pool-size,omitempty"`
 
 	// ImportWorkerPoolSize controls how many goroutines are created for
-	// processing importRoaring jobs. Defaults to runtime.NumCPU(). It is
-	// intentionally not defined as a flag... only exposed here so
-	// that we can limit the size while running tests in CI so we
-	// don't exhaust the goroutine limit.
-	ImportWorkerPoolSize int
+	// processing importRoaring jobs. Zero, the default, means the number of
+	// CPUs of the machine the server starts on.
+	ImportWorkerPoolSize int `toml:"import-worker-pool-size,omitempty"`
 
 	Cluster struct {
 		// Disabled controls whether clustering functionality is enabled.
@@ -154,6 +212,20 @@ type Config struct {
 		// MutexFraction is passed directly to runtime.SetMutexProfileFraction
 		MutexFraction int `toml:"mutex-fraction"`
 	} `toml:"profile"`
+
+	// Storage tunes how fragments are stored on disk.
+	Storage struct {
+		// Fsync is when the op log of a fragment is synced to disk: "always"
+		// after every write, or "snapshot" only when the fragment is
+		// snapshotted or closed.
+		Fsync string `toml:"fsync"`
+		// MaxOpN is the number of operations in the op log of a fragment
+		// after which it is snapshotted.
+		MaxOpN int `toml:"max-op-n"`
+		// Mmap maps fragment files into memory rather than reading them
+		// onto the heap.
+		Mmap bool `toml:"mmap"`
+	} `toml:"storage"`
 }
 
 // NewConfig returns an instance of Config with default options.
@@ -162,6 +234,7 @@ func NewConfig() *Config {
 		DataDir:             "~/.pilosa",
 		Bind:                ":10101",
 		MaxWritesPerRequest: 5000,
+		LogFormat:           "text",
 
 		// We default these Max File/Map counts very high. This is basically a
 		// backwards compatibility thing where we don't want to cause different
@@ -172,9 +245,6 @@ func NewConfig() *Config {
 		MaxFileCount: 1000000,
 
 		TLS: TLSConfig{},
-
-		WorkerPoolSize:       runtime.NumCPU(),
-		ImportWorkerPoolSize: runtime.NumCPU(),
 	}
 
 	// Cluster config.
@@ -194,6 +264,9 @@ func NewConfig() *Config {
 	c.Gossip.Nodes = 3
 	c.Gossip.ToTheDeadTime = toml.Duration(30 * time.Second)
 
+	// Handler config.
+	c.Handler.AuditLogMaxBackups = 5
+
 	// AntiEntropy config.
 	c.AntiEntropy.Interval = toml.Duration(10 * time.Minute)
 
@@ -209,9 +282,108 @@ func NewConfig() *Config {
 	c.Profile.BlockRate = 10000000 // 1 sample per 10 ms
 	c.Profile.MutexFraction = 100  // 1% sampling
 
+	// Storage config.
+	c.Storage.Fsync = "snapshot"
+	c.Storage.MaxOpN = pilosa.NewStorageConfig().MaxOpN
+	c.Storage.Mmap = true
+
 	return c
 }
 
+// Validate returns an error describing the first invalid option of cfg, such
+// as a negative duration, an unknown metric service or a malformed list entry.
+// Addresses are checked separately when the server starts, since checking
+// them may need to resolve host names.
+func (cfg *Config) Validate() error {
+	for _, d := range []struct {
+		name string
+		d    toml.Duration
+	}{
+		{"query-timeout", cfg.QueryTimeout},
+		{"cluster.long-query-time", cfg.Cluster.LongQueryTime},
+		{"anti-entropy.interval", cfg.AntiEntropy.Interval},
+		{"metric.poll-interval", cfg.Metric.PollInterval},
+		{"gossip.stream-timeout", cfg.Gossip.StreamTimeout},
+		{"gossip.push-pull-interval", cfg.Gossip.PushPullInterval},
+		{"gossip.probe-timeout", cfg.Gossip.ProbeTimeout},
+		{"gossip.probe-interval", cfg.Gossip.ProbeInterval},
+		{"gossip.interval", cfg.Gossip.Interval},
+		{"gossip.to-the-dead-time", cfg.Gossip.ToTheDeadTime},
+	} {
+		if d.d < 0 {
+			return errors.Errorf("%s must not be negative: %s", d.name, d.d)
+		}
+	}
+
+	if cfg.MaxWritesPerRequest < 0 {
+		return errors.Errorf("max-writes-per-request must not be negative: %d", cfg.MaxWritesPerRequest)
+	} else if cfg.Cluster.ReplicaN < 1 {
+		return errors.Errorf("cluster.replicas must be at least 1: %d", cfg.Cluster.ReplicaN)
+	} else if cfg.Translation.MapSize < 0 {
+		return errors.Errorf("translation.map-size must not be negative: %d", cfg.Translation.MapSize)
+	} else if cfg.Tracing.SamplerParam < 0 {
+		return errors.Errorf("tracing.sampler-param must not be negative: %v", cfg.Tracing.SamplerParam)
+	} else if cfg.RateLimit.Query < 0 || cfg.RateLimit.Import < 0 {
+		return errors.New("rate limits must not be negative")
+	} else if cfg.WorkerPoolSize < 0 || cfg.ImportWorkerPoolSize < 0 {
+		return errors.New("worker pool sizes must not be negative")
+	} else if cfg.Handler.AuditLogMaxSize < 0 || cfg.Handler.AuditLogMaxBackups < 0 {
+		return errors.New("handler.audit-log-max-size and handler.audit-log-max-backups must not be negative")
+	} else if cfg.Storage.MaxOpN < 1 {
+		return errors.Errorf("storage.max-op-n must be at least 1: %d", cfg.Storage.MaxOpN)
+	}
+
+	if port, err := strconv.Atoi(cfg.Gossip.Port); err != nil || port < 0 || port > 65535 {
+		return errors.Errorf("invalid gossip.port: %q", cfg.Gossip.Port)
+	}
+
+	switch cfg.Metric.Service {
+	case "expvar", "statsd", "prometheus", "nop", "none":
+	default:
+		return errors.Errorf("invalid metric.service: %q, choose from [expvar, statsd, prometheus, none]", cfg.Metric.Service)
+	}
+
+	switch cfg.LogFormat {
+	case "text", "json":
+	default:
+		return errors.Errorf("invalid log-format: %q, choose from [text, json]", cfg.LogFormat)
+	}
+
+	switch cfg.Storage.Fsync {
+	case "always", "snapshot":
+	default:
+		return errors.Errorf("invalid storage.fsync: %q, choose from [always, snapshot]", cfg.Storage.Fsync)
+	}
+
+	if (cfg.TLS.CertificatePath == "") != (cfg.TLS.CertificateKeyPath == "") {
+		return errors.New("tls.certificate and tls.key must be set together")
+	}
+
+	limits, err := parseIndexRateLimits(http.RateLimit{}, cfg.RateLimit.IndexQuery, cfg.RateLimit.IndexImport)
+	if err != nil {
+		return err
+	}
+	for index, limit := range limits {
+		if limit.Query < 0 || limit.Import < 0 {
+			return errors.Errorf("rate limit for index %s must not be negative", index)
+		}
+	}
+	if cfg.Auth.Enable && cfg.Auth.NodeKey == "" {
+		return errors.New("auth.node-key must be set when auth is enabled")
+	}
+	if _, err := parseAPIKeys(cfg.Auth.APIKeys); err != nil {
+		return err
+	}
+	roles, err := parseGrants(cfg.Auth.Grants)
+	if err != nil {
+		return err
+	}
+	if _, err := http.NewAuthorizer(roles, ""); err != nil {
+		return err
+	}
+	return nil
+}
+
 // validateAddrs controls the address fields in the Config object
 // and fills in any blanks.
 // The addresses fields must be guaranteed by the caller to either be
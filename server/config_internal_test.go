@@ -19,8 +19,11 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/pilosa/pilosa/v2/http"
 )
 
 type addrs struct{ bind, advertise string }
@@ -154,3 +157,81 @@ func TestConfig_validateAddrs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIndexRateLimits(t *testing.T) {
+	def := http.RateLimit{Query: 10, Import: 1}
+	limits, err := parseIndexRateLimits(def, []string{"a=100", "b=0"}, []string{"a=5", "c=2.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]http.RateLimit{
+		"a": {Query: 100, Import: 5},
+		"b": {Query: 0, Import: 1},
+		"c": {Query: 10, Import: 2.5},
+	}
+	if !reflect.DeepEqual(limits, exp) {
+		t.Fatalf("unexpected limits: %+v", limits)
+	}
+
+	for _, pair := range []string{"a", "a=fast"} {
+		if _, err := parseIndexRateLimits(def, []string{pair}, nil); err == nil {
+			t.Fatalf("expected error for %q", pair)
+		}
+	}
+}
+
+func TestParseGrants(t *testing.T) {
+	roles, err := parseGrants([]string{"reader=*:read", "etl=events/clicks:write", "etl=events/views:read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string][]http.Grant{
+		"reader": {{Index: "*", Permission: http.PermissionRead}},
+		"etl": {
+			{Index: "events", Field: "clicks", Permission: http.PermissionWrite},
+			{Index: "events", Field: "views", Permission: http.PermissionRead},
+		},
+	}
+	if !reflect.DeepEqual(roles, exp) {
+		t.Fatalf("unexpected roles: %+v", roles)
+	}
+
+	for _, s := range []string{"reader", "reader:read=*"} {
+		if _, err := parseGrants([]string{s}); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := NewConfig().Validate(); err != nil {
+		t.Fatalf("default config: %s", err)
+	}
+
+	for name, fn := range map[string]func(*Config){
+		"replicas":     func(c *Config) { c.Cluster.ReplicaN = 0 },
+		"duration":     func(c *Config) { c.AntiEntropy.Interval = -1 },
+		"metric":       func(c *Config) { c.Metric.Service = "graphite" },
+		"gossip-port":  func(c *Config) { c.Gossip.Port = "port" },
+		"tls":          func(c *Config) { c.TLS.CertificatePath = "pilosa.crt" },
+		"rate-limit":   func(c *Config) { c.RateLimit.IndexQuery = []string{"i=-1"} },
+		"api-key":      func(c *Config) { c.Auth.APIKeys = []string{"secret"} },
+		"node-key":     func(c *Config) { c.Auth.Enable = true },
+		"audit-log":    func(c *Config) { c.Handler.AuditLogMaxSize = -1 },
+		"grant":        func(c *Config) { c.Auth.Grants = []string{"reader=*:look"} },
+		"max-writes":   func(c *Config) { c.MaxWritesPerRequest = -1 },
+		"sampler-rate": func(c *Config) { c.Tracing.SamplerParam = -0.5 },
+		"worker-pool":  func(c *Config) { c.ImportWorkerPoolSize = -1 },
+		"fsync":        func(c *Config) { c.Storage.Fsync = "never" },
+		"log-format":   func(c *Config) { c.LogFormat = "xml" },
+		"max-op-n":     func(c *Config) { c.Storage.MaxOpN = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewConfig()
+			fn(c)
+			if err := c.Validate(); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package server_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/hex"
 	"encoding/json"
@@ -25,6 +26,7 @@ import (
 	"math"
 	gohttp "net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -36,6 +38,7 @@ import (
 	"github.com/pilosa/pilosa/v2/http"
 	"github.com/pilosa/pilosa/v2/server"
 	"github.com/pilosa/pilosa/v2/test"
+	"github.com/pkg/errors"
 )
 
 func TestHandler_PostSchemaCluster(t *testing.T) {
@@ -77,6 +80,43 @@ func TestHandler_PostSchemaCluster(t *testing.T) {
 	})
 }
 
+// Ensure /ready responds with 503 and the reason when the node cannot serve
+// requests.
+func TestHandler_NotReady(t *testing.T) {
+	cluster := test.MustRunCluster(t, 1)
+	defer cluster.Close()
+	cmd := cluster[0]
+	h := cmd.Handler.(*http.Handler).Handler
+	holder := cmd.Server.Holder()
+
+	ready := func() (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("GET", "/ready", nil))
+		return w.Code, w.Body.String()
+	}
+	if code, body := ready(); code != gohttp.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", code, body)
+	}
+
+	// The data directory cannot be written to.
+	path := holder.Path
+	holder.Path = filepath.Join(path, "missing")
+	if code, body := ready(); code != gohttp.StatusServiceUnavailable {
+		t.Fatalf("unexpected status code: %d", code)
+	} else if !strings.Contains(body, "checking data directory") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+	holder.Path = path
+
+	// A check added by another part of the node fails.
+	cmd.API.AddReadyCheck("test", func() error { return errors.New("not started") })
+	if code, body := ready(); code != gohttp.StatusServiceUnavailable {
+		t.Fatalf("unexpected status code: %d", code)
+	} else if !strings.Contains(body, "checking test: not started") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
 func TestHandler_Endpoints(t *testing.T) {
 	cluster := test.MustRunCluster(t, 1)
 	defer cluster.Close()
@@ -273,6 +313,26 @@ func TestHandler_Endpoints(t *testing.T) {
 		}
 	})
 
+	t.Run("Health", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("GET", "/health", nil))
+		if w.Code != gohttp.StatusOK {
+			t.Fatalf("unexpected status code: %d", w.Code)
+		} else if body := w.Body.String(); body != "{\"success\":true}\n" {
+			t.Fatalf("unexpected body: %q", body)
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("GET", "/ready", nil))
+		if w.Code != gohttp.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+		} else if body := w.Body.String(); body != "{\"success\":true}\n" {
+			t.Fatalf("unexpected body: %q", body)
+		}
+	})
+
 	t.Run("Abort no resize job", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		h.ServeHTTP(w, test.MustNewHTTPRequest("POST", "/cluster/resize/abort", nil))
@@ -389,6 +449,28 @@ func TestHandler_Endpoints(t *testing.T) {
 		}
 	})
 
+	t.Run("Row JSON gzip", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := test.MustNewHTTPRequest("POST", "/index/i0/query", strings.NewReader("Row(f0=30)"))
+		r.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(w, r)
+		if w.Code != gohttp.StatusOK {
+			t.Fatalf("unexpected status code: %d", w.Code)
+		} else if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+			t.Fatalf("unexpected content encoding: %q", enc)
+		}
+		gr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(gr)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(body) != fmt.Sprintf(`{"results":[{"attrs":{},"columns":[%d,%d,%d]}]}`, pilosa.ShardWidth+1, pilosa.ShardWidth+2, 3*pilosa.ShardWidth+4)+"\n" {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
 	f0 := i0.Field("f0")
 	if err := i0.ColumnAttrStore().SetAttrs((1*pilosa.ShardWidth)+1, map[string]interface{}{"x": "y"}); err != nil {
 		t.Fatal(err)
@@ -781,6 +863,28 @@ func TestHandler_Endpoints(t *testing.T) {
 		}
 	})
 
+	t.Run("LogLevel", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("PUT", "/log-level", strings.NewReader(`{"level":"debug"}`)))
+		if w.Code != gohttp.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("GET", "/log-level", nil))
+		if w.Code != gohttp.StatusOK {
+			t.Fatalf("unexpected status code: %d", w.Code)
+		} else if w.Body.String() != `{"level":"debug"}`+"\n" {
+			t.Fatalf("unexpected body: %q", w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, test.MustNewHTTPRequest("PUT", "/log-level", strings.NewReader(`{"level":"trace"}`)))
+		if w.Code != gohttp.StatusBadRequest {
+			t.Fatalf("unexpected status code: %d", w.Code)
+		}
+	})
+
 	t.Run("Fragment Nodes", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		r := test.MustNewHTTPRequest("GET", "/internal/fragment/nodes?index=i&shard=0", nil)
@@ -1118,3 +1222,40 @@ func (r getSchemaResponse) findField(indexName, fieldName string) *pilosa.FieldI
 	}
 	return nil
 }
+
+// BenchmarkHandler_PostQuery_Compression compares serving a large row with
+// and without gzip. The response size of each is logged.
+func BenchmarkHandler_PostQuery_Compression(b *testing.B) {
+	cluster := test.MustRunCluster(b, 1)
+	defer cluster.Close()
+	cmd := cluster[0]
+	h := cmd.Handler.(*http.Handler).Handler
+
+	cmd.MustCreateIndex(b, "i", pilosa.IndexOptions{})
+	cmd.MustCreateField(b, "i", "f")
+	req := pilosa.ImportRequest{Index: "i", Field: "f", Shard: 0}
+	for col := uint64(0); col < 100000; col++ {
+		req.RowIDs = append(req.RowIDs, 0)
+		req.ColumnIDs = append(req.ColumnIDs, col*7)
+	}
+	if err := cmd.API.Import(context.Background(), &req); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, encoding := range []string{"identity", "gzip"} {
+		b.Run(encoding, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				r := test.MustNewHTTPRequest("POST", "/index/i/query", strings.NewReader("Row(f=0)"))
+				r.Header.Set("Accept-Encoding", encoding)
+				h.ServeHTTP(w, r)
+				if w.Code != gohttp.StatusOK {
+					b.Fatalf("unexpected status code: %d", w.Code)
+				}
+				size = w.Body.Len()
+			}
+			b.Logf("response size: %d bytes", size)
+		})
+	}
+}
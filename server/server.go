@@ -31,6 +31,7 @@ import (
 	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -79,6 +80,9 @@ type Command struct {
 	logOutput io.Writer
 	logger    loggerLogger
 
+	// auditLog is the open audit log file, if one is configured.
+	auditLog io.WriteCloser
+
 	Handler      pilosa.Handler
 	API          *pilosa.API
 	ln           net.Listener
@@ -237,6 +241,10 @@ func (m *Command) SetupServer() error {
 	}
 	m.logger.Printf("%s %s, build time %s\n", productName, pilosa.Version, pilosa.BuildTime)
 
+	if err := m.Config.Validate(); err != nil {
+		return errors.Wrap(err, "validating config")
+	}
+
 	// validateAddrs sets the appropriate values for Bind and Advertise
 	// based on the inputs. It is not responsible for applying defaults, although
 	// it does provide a non-zero port (10101) in the case where no port is specified.
@@ -284,6 +292,10 @@ func (m *Command) SetupServer() error {
 	m.listenURI = uri
 
 	c := http.GetHTTPClient(TLSConfig)
+	if m.Config.Auth.Enable {
+		c = http.WithAPIKey(c, m.Config.Auth.NodeKey)
+	}
+	client := http.NewInternalClientFromURI(uri, c)
 
 	// Get advertise address as uri.
 	advertiseURI, err := pilosa.AddressWithDefaults(m.Config.Advertise)
@@ -311,9 +323,15 @@ func (m *Command) SetupServer() error {
 		pilosa.OptServerDataDir(m.Config.DataDir),
 		pilosa.OptServerReplicaN(m.Config.Cluster.ReplicaN),
 		pilosa.OptServerMaxWritesPerRequest(m.Config.MaxWritesPerRequest),
+		pilosa.OptServerQueryTimeout(time.Duration(m.Config.QueryTimeout)),
 		pilosa.OptServerMetricInterval(time.Duration(m.Config.Metric.PollInterval)),
 		pilosa.OptServerDiagnosticsInterval(diagnosticsInterval),
-		pilosa.OptServerExecutorPoolSize(m.Config.WorkerPoolSize),
+		pilosa.OptServerExecutorPoolSize(workerPoolSize(m.Config.WorkerPoolSize)),
+		pilosa.OptServerStorageConfig(&pilosa.StorageConfig{
+			FsyncOps:    m.Config.Storage.Fsync == "always",
+			MaxOpN:      m.Config.Storage.MaxOpN,
+			DisableMmap: !m.Config.Storage.Mmap,
+		}),
 		pilosa.OptServerOpenTranslateStore(boltdb.OpenTranslateStore),
 		pilosa.OptServerOpenTranslateReader(http.GetOpenTranslateReaderFunc(c)),
 		pilosa.OptServerLogger(m.logger),
@@ -322,7 +340,7 @@ func (m *Command) SetupServer() error {
 		pilosa.OptServerGCNotifier(gcnotify.NewActiveGCNotifier()),
 		pilosa.OptServerStatsClient(statsClient),
 		pilosa.OptServerURI(advertiseURI),
-		pilosa.OptServerInternalClient(http.NewInternalClientFromURI(uri, c)),
+		pilosa.OptServerInternalClient(client),
 		pilosa.OptServerClusterDisabled(m.Config.Cluster.Disabled, m.Config.Cluster.Hosts),
 		pilosa.OptServerSerializer(proto.Serializer{}),
 		coordinatorOpt,
@@ -338,22 +356,140 @@ func (m *Command) SetupServer() error {
 
 	m.API, err = pilosa.NewAPI(
 		pilosa.OptAPIServer(m.Server),
-		pilosa.OptAPIImportWorkerPoolSize(m.Config.ImportWorkerPoolSize),
+		pilosa.OptAPIImportWorkerPoolSize(workerPoolSize(m.Config.ImportWorkerPoolSize)),
 	)
 	if err != nil {
 		return errors.Wrap(err, "new api")
 	}
 
+	if m.Config.Handler.AuditLogPath != "" {
+		f, err := openRotatingFile(m.Config.Handler.AuditLogPath, int64(m.Config.Handler.AuditLogMaxSize)<<20, m.Config.Handler.AuditLogMaxBackups)
+		if err != nil {
+			return errors.Wrap(err, "opening audit log")
+		}
+		m.auditLog = f
+	}
+
+	rateLimit := http.RateLimit{Query: m.Config.RateLimit.Query, Import: m.Config.RateLimit.Import}
+	indexRateLimits, err := parseIndexRateLimits(rateLimit, m.Config.RateLimit.IndexQuery, m.Config.RateLimit.IndexImport)
+	if err != nil {
+		return errors.Wrap(err, "parsing rate limits")
+	}
+
+	var authenticator *http.Authenticator
+	var authorizer *http.Authorizer
+	if m.Config.Auth.Enable {
+		apiKeys, err := parseAPIKeys(m.Config.Auth.APIKeys)
+		if err != nil {
+			return errors.Wrap(err, "parsing API keys")
+		}
+		authenticator = &http.Authenticator{
+			APIKeys:    apiKeys,
+			JWTSecret:  []byte(m.Config.Auth.JWTSecret),
+			RolesClaim: m.Config.Auth.RolesClaim,
+			NodeKey:    m.Config.Auth.NodeKey,
+		}
+
+		roles, err := parseGrants(m.Config.Auth.Grants)
+		if err != nil {
+			return errors.Wrap(err, "parsing grants")
+		}
+		authorizer, err = http.NewAuthorizer(roles, m.Config.Auth.RolesPath)
+		if err != nil {
+			return errors.Wrap(err, "new authorizer")
+		}
+	}
+
 	m.Handler, err = http.NewHandler(
 		http.OptHandlerAllowedOrigins(m.Config.Handler.AllowedOrigins),
 		http.OptHandlerAPI(m.API),
 		http.OptHandlerLogger(m.logger),
 		http.OptHandlerListener(m.ln),
 		http.OptHandlerCloseTimeout(m.closeTimeout),
+		http.OptHandlerAuditLog(m.auditLog),
+		http.OptHandlerRateLimit(rateLimit, indexRateLimits),
+		http.OptHandlerAuthenticator(authenticator),
+		http.OptHandlerAuthorizer(authorizer),
+		http.OptHandlerInternalClient(client),
 	)
 	return errors.Wrap(err, "new handler")
 }
 
+// parseIndexRateLimits parses per-index query and import rate limits given as
+// "index=rate" pairs. An index without an override of one kind gets def.
+func parseIndexRateLimits(def http.RateLimit, query, imp []string) (map[string]http.RateLimit, error) {
+	limits := make(map[string]http.RateLimit)
+	for _, pairs := range []struct {
+		a   []string
+		imp bool
+	}{{query, false}, {imp, true}} {
+		for _, pair := range pairs.a {
+			i := strings.Index(pair, "=")
+			if i < 0 {
+				return nil, errors.Errorf("invalid rate limit %q, expected index=rate", pair)
+			}
+			rate, err := strconv.ParseFloat(pair[i+1:], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid rate limit %q", pair)
+			}
+			index := pair[:i]
+			limit, ok := limits[index]
+			if !ok {
+				limit = def
+			}
+			if pairs.imp {
+				limit.Import = rate
+			} else {
+				limit.Query = rate
+			}
+			limits[index] = limit
+		}
+	}
+	return limits, nil
+}
+
+// workerPoolSize returns size, or the number of CPUs if size is zero.
+func workerPoolSize(size int) int {
+	if size == 0 {
+		return runtime.NumCPU()
+	}
+	return size
+}
+
+// parseAPIKeys parses API keys given as "key=role" pairs. A key may be listed
+// more than once to grant it several roles.
+func parseAPIKeys(a []string) (map[string][]string, error) {
+	keys := make(map[string][]string, len(a))
+	for _, pair := range a {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return nil, errors.New("invalid API key, expected key=role")
+		}
+		key, role := pair[:i], pair[i+1:]
+		keys[key] = append(keys[key], role)
+	}
+	return keys, nil
+}
+
+// parseGrants parses role bindings given as "role=index:permission" or
+// "role=index/field:permission" entries.
+func parseGrants(a []string) (map[string][]http.Grant, error) {
+	roles := make(map[string][]http.Grant)
+	for _, s := range a {
+		i, j := strings.Index(s, "="), strings.LastIndex(s, ":")
+		if i < 0 || j < i {
+			return nil, errors.Errorf("invalid grant %q, expected role=index:permission", s)
+		}
+		role, target := s[:i], s[i+1:j]
+		g := http.Grant{Index: target, Permission: http.Permission(s[j+1:])}
+		if k := strings.Index(target, "/"); k >= 0 {
+			g.Index, g.Field = target[:k], target[k+1:]
+		}
+		roles[role] = append(roles[role], g)
+	}
+	return roles, nil
+}
+
 // setupNetworking sets up internode communication based on the configuration.
 func (m *Command) setupNetworking() error {
 	if m.Config.Cluster.Disabled {
@@ -383,6 +519,7 @@ func (m *Command) setupNetworking() error {
 		return errors.Wrap(err, "getting memberset")
 	}
 	m.gossipMemberSet = gossipMemberSet
+	m.API.AddReadyCheck("gossip", gossipMemberSet.Ready)
 
 	return errors.Wrap(gossipMemberSet.Open(), "opening gossip memberset")
 }
@@ -404,6 +541,9 @@ func (m *Command) Close() error {
 	if m.gossipMemberSet != nil {
 		eg.Go(m.gossipMemberSet.Close)
 	}
+	if m.auditLog != nil {
+		eg.Go(m.auditLog.Close)
+	}
 	if closer, ok := m.logOutput.(io.Closer); ok {
 		// If closer is os.Stdout or os.Stderr, don't close it.
 		if closer != os.Stdout && closer != os.Stderr {
@@ -435,6 +575,10 @@ func newStatsClient(name string, host string) (stats.StatsClient, error) {
 func getListener(uri pilosa.URI, tlsconf *tls.Config) (ln net.Listener, err error) {
 	// If bind URI has the https scheme, enable TLS
 	if uri.Scheme == "https" && tlsconf != nil {
+		// Offer HTTP/2 to clients. The config is copied because it is shared
+		// with the internal client, whose transport only speaks HTTP/1.1.
+		tlsconf = tlsconf.Clone()
+		tlsconf.NextProtos = []string{"h2", "http/1.1"}
 		ln, err = tls.Listen("tcp", uri.HostPort(), tlsconf)
 		if err != nil {
 			return nil, errors.Wrap(err, "tls.Listener")
@@ -0,0 +1,118 @@
+// Copyright 2017 Pilosa Corp.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilosa/pilosa/v2"
+	"golang.org/x/net/http2"
+)
+
+// Ensure the TLS listener negotiates HTTP/2 with clients that offer it.
+func TestGetListener_HTTP2(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair("./testdata/certs/localhost.crt", "./testdata/certs/localhost.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsconf := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	uri, err := pilosa.NewURIFromAddress("https://localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := getListener(*uri, tlsconf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	// Ensure the shared config was not modified.
+	if len(tlsconf.NextProtos) != 0 {
+		t.Fatalf("unexpected NextProtos on shared config: %v", tlsconf.NextProtos)
+	}
+
+	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.Proto != "HTTP/2.0" || string(body) != "HTTP/2.0" {
+		t.Fatalf("unexpected protocol: client=%s server=%s", resp.Proto, body)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pilosa-audit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { f.Close() }()
+
+	// Each write which would take the file past 10 bytes starts a new file.
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for p, exp := range map[string]string{
+		path:        "gggg\n",
+		path + ".1": "eeee\nffff\n",
+		path + ".2": "cccc\ndddd\n",
+	} {
+		if buf, err := ioutil.ReadFile(p); err != nil {
+			t.Fatal(err)
+		} else if string(buf) != exp {
+			t.Fatalf("unexpected contents of %s: %q", filepath.Base(p), buf)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only two backups: %v", err)
+	}
+
+	// Reopening appends to the current file.
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	} else if f, err = openRotatingFile(path, 10, 2); err != nil {
+		t.Fatal(err)
+	} else if _, err := f.Write([]byte("hhhh\n")); err != nil {
+		t.Fatal(err)
+	} else if buf, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "gggg\nhhhh\n" {
+		t.Fatalf("unexpected contents after reopening: %q", buf)
+	}
+}
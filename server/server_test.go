@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	gohttp "net/http"
 	"reflect"
 	"sort"
 	"strconv"
@@ -655,6 +656,98 @@ func TestRemoveNodeAfterItDies(t *testing.T) {
 	}
 }
 
+func TestForceRemoveNodeAfterItDies(t *testing.T) {
+	cluster := test.MustRunCluster(t, 2)
+	defer cluster[0].Close() // the second node is closed below
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	cluster.Query(t, "i", "Set(1, f=1) Set(1048577, f=1) Set(2097153, f=1) Set(3145729, f=1)")
+
+	if err := cluster[1].Command.Close(); err != nil {
+		t.Fatalf("closing second node: %v", err)
+	}
+
+	// With a single replica, the cluster is STARTING until the dead node
+	// comes back, and its data can't be moved.
+	if _, err := cluster[0].API.RemoveNode(cluster[1].API.Node().ID); err == nil {
+		t.Fatal("expected error removing dead node without force")
+	}
+
+	if _, err := cluster[0].API.ForceRemoveNode(cluster[1].API.Node().ID); err != nil {
+		t.Fatalf("force removing dead node: %v", err)
+	}
+	if cluster[0].API.State() != pilosa.ClusterStateNormal {
+		t.Fatalf("expected state to be NORMAL, but got %s", cluster[0].API.State())
+	}
+	hosts := cluster[0].API.Hosts(context.Background())
+	if len(hosts) != 1 {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+}
+
+// Ensure that forcibly removing a node syncs its shards to their new owners
+// from the remaining replicas, even with anti-entropy disabled.
+func TestForceRemoveNodeSyncsShards(t *testing.T) {
+	cluster := test.MustNewCluster(t, 3)
+	for _, c := range cluster {
+		c.Config.Cluster.ReplicaN = 2
+		c.Config.AntiEntropy.Interval = 0
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer cluster[0].Close()
+	defer cluster[1].Close() // the third node is closed below
+
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	const shardN = 8
+	for shard := uint64(0); shard < shardN; shard++ {
+		cluster.Query(t, "i", fmt.Sprintf("Set(%d, f=1)", shard*pilosa.ShardWidth))
+	}
+
+	if err := cluster[2].Command.Close(); err != nil {
+		t.Fatalf("closing third node: %v", err)
+	}
+	if _, err := cluster[0].API.ForceRemoveNode(cluster[2].API.Node().ID); err != nil {
+		t.Fatalf("force removing node: %v", err)
+	}
+
+	// Every owner of every shard should end up with its bit.
+	for shard := uint64(0); shard < shardN; shard++ {
+		nodes, err := cluster[0].API.ShardNodes(context.Background(), "i", shard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, node := range nodes {
+			var m *test.Command
+			for _, c := range cluster[:2] {
+				if c.API.Node().ID == node.ID {
+					m = c
+				}
+			}
+			if m == nil {
+				t.Fatalf("shard %d is owned by removed node %s", shard, node.ID)
+			}
+			for i := 0; ; i++ {
+				resp, err := m.API.Query(context.Background(), &pilosa.QueryRequest{
+					Index:  "i",
+					Query:  "Count(Row(f=1))",
+					Shards: []uint64{shard},
+					Remote: true,
+				})
+				if err != nil {
+					t.Fatal(err)
+				} else if resp.Results[0] == uint64(1) {
+					break
+				} else if i > 1000 {
+					t.Fatalf("shard %d was not synced to node %s", shard, node.ID)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+}
+
 func TestRemoveConcurrentIndexCreation(t *testing.T) {
 	cluster := test.MustNewCluster(t, 3)
 	for _, c := range cluster {
@@ -985,3 +1078,81 @@ func TestClusterExhaustingConnectionsImport(t *testing.T) {
 		t.Fatalf("setting lots of shards: %v", err)
 	}
 }
+
+// Ensure nodes of a cluster which requires authentication can reach each
+// other, and that /version can be probed without credentials.
+func TestClusterAuth(t *testing.T) {
+	cluster := test.MustNewCluster(t, 2)
+	for _, c := range cluster {
+		c.Config.Auth.Enable = true
+		c.Config.Auth.NodeKey = "node-secret"
+		c.Config.Auth.APIKeys = []string{"client-secret=admin", "reader-secret=reader"}
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("starting cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	// Writes and queries are forwarded to the node owning each shard.
+	cluster.CreateField(t, "i", pilosa.IndexOptions{}, "f")
+	var query strings.Builder
+	for shard := uint64(0); shard < 8; shard++ {
+		fmt.Fprintf(&query, "Set(%d, f=1)", shard*pilosa.ShardWidth)
+	}
+	cluster.Query(t, "i", query.String())
+	for _, c := range cluster {
+		resp := c.MustQuery(t, &pilosa.QueryRequest{Index: "i", Query: "Count(Row(f=1))"})
+		if resp.Results[0] != uint64(8) {
+			t.Fatalf("unexpected count from %s: %v", c.API.Node().ID, resp.Results[0])
+		}
+	}
+
+	// Role bindings changed on one node are sent to the others, and each
+	// caller only sees the indexes it can read.
+	cluster.CreateField(t, "j", pilosa.IndexOptions{}, "g")
+	req, err := gohttp.NewRequest("PUT", cluster[0].URL()+"/auth/roles", strings.NewReader(`{"reader": [{"index": "i", "permission": "read"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(http.APIKeyHeader, "client-secret")
+	resp, err := gohttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != gohttp.StatusOK {
+		t.Fatalf("PUT /auth/roles: unexpected status %d", resp.StatusCode)
+	}
+	for _, c := range cluster {
+		req, err := gohttp.NewRequest("GET", c.URL()+"/schema", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(http.APIKeyHeader, "reader-secret")
+		resp, err := gohttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema pilosa.Schema
+		err = json.NewDecoder(resp.Body).Decode(&schema)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decoding schema: %v", err)
+		} else if len(schema.Indexes) != 1 || schema.Indexes[0].Name != "i" {
+			t.Fatalf("unexpected schema from %s: %+v", c.API.Node().ID, schema.Indexes)
+		}
+	}
+
+	// Nodes confirm that a node is down by requesting /version, without
+	// credentials. Other requests need them.
+	for path, code := range map[string]int{"/version": gohttp.StatusOK, "/schema": gohttp.StatusUnauthorized} {
+		resp, err := gohttp.Get(cluster[1].URL() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Fatalf("GET %s: expected status %d, got %d", path, code, resp.StatusCode)
+		}
+	}
+}
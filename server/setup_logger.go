@@ -40,10 +40,10 @@ func (m *Command) setupLogger() error {
 		}
 	}
 
+	level := logger.LevelInfo
 	if m.Config.Verbose {
-		m.logger = logger.NewVerboseLogger(m.logOutput)
-	} else {
-		m.logger = logger.NewStandardLogger(m.logOutput)
+		level = logger.LevelDebug
 	}
+	m.logger = logger.NewLevelLogger(m.logOutput, level, m.Config.LogFormat == "json")
 	return nil
 }
@@ -18,12 +18,15 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+
+	"github.com/pilosa/pilosa/v2/logger"
 )
 
 // bufferLogger represents a test Logger that holds log messages
 // in a buffer for review.
 type bufferLogger struct {
-	buf *bytes.Buffer
+	buf    *bytes.Buffer
+	fields string
 }
 
 // NewBufferLogger returns a new instance of BufferLogger.
@@ -34,7 +37,7 @@ func NewBufferLogger() *bufferLogger {
 }
 
 func (b *bufferLogger) Printf(format string, v ...interface{}) {
-	s := fmt.Sprintf(format, v...)
+	s := fmt.Sprintf(format, v...) + b.fields
 	_, err := b.buf.WriteString(s)
 	if err != nil {
 		panic(err)
@@ -43,6 +46,18 @@ func (b *bufferLogger) Printf(format string, v ...interface{}) {
 
 func (b *bufferLogger) Debugf(format string, v ...interface{}) {}
 
+func (b *bufferLogger) Warnf(format string, v ...interface{}) {
+	b.Printf("WARN: "+format, v...)
+}
+
+func (b *bufferLogger) Errorf(format string, v ...interface{}) {
+	b.Printf("ERROR: "+format, v...)
+}
+
+func (b *bufferLogger) With(key string, value interface{}) logger.Logger {
+	return &bufferLogger{buf: b.buf, fields: fmt.Sprintf("%s %s=%v", b.fields, key, value)}
+}
+
 func (b *bufferLogger) ReadAll() ([]byte, error) {
 	return ioutil.ReadAll(b.buf)
 }
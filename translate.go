@@ -17,6 +17,8 @@ package pilosa
 import (
 	"context"
 	"io"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -57,6 +59,13 @@ type TranslateStore interface {
 	EntryReader(ctx context.Context, offset uint64) (TranslateEntryReader, error)
 }
 
+// TranslateKeyScanner is implemented by translate stores which can find the
+// keys beginning with a prefix without reading every key.
+type TranslateKeyScanner interface {
+	// Returns the key/ID pairs whose keys begin with prefix, ordered by key.
+	KeysWithPrefix(prefix string) ([]TranslateEntry, error)
+}
+
 // OpenTranslateStoreFunc represents a function for instantiating and opening a TranslateStore.
 type OpenTranslateStoreFunc func(path, index, field string) (TranslateStore, error)
 
@@ -190,6 +199,7 @@ func (m TranslateOffsetMap) SetFieldOffset(index, name string, offset uint64) {
 
 // Ensure type implements interface.
 var _ TranslateStore = &InMemTranslateStore{}
+var _ TranslateKeyScanner = &InMemTranslateStore{}
 
 // InMemTranslateStore is an in-memory storage engine for mapping keys to int values.
 type InMemTranslateStore struct {
@@ -300,6 +310,22 @@ func (s *InMemTranslateStore) TranslateIDs(ids []uint64) ([]string, error) {
 	return keys, nil
 }
 
+// KeysWithPrefix returns the key/ID pairs whose keys begin with prefix,
+// ordered by key.
+func (s *InMemTranslateStore) KeysWithPrefix(prefix string) ([]TranslateEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []TranslateEntry
+	for i, key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, TranslateEntry{Index: s.index, Field: s.field, ID: uint64(i + 1), Key: key})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
 func (s *InMemTranslateStore) translateID(id uint64) string {
 	if id == 0 || id > uint64(len(s.keys)) {
 		return ""
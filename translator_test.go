@@ -74,6 +74,30 @@ func TestInMemTranslateStore_TranslateID(t *testing.T) {
 	}
 }
 
+func TestInMemTranslateStore_KeysWithPrefix(t *testing.T) {
+	s := pilosa.NewInMemTranslateStore("IDX", "FLD")
+	if _, err := s.TranslateKeys([]string{"foo", "bar", "food", "fo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure only keys with the prefix are returned, ordered by key.
+	if entries, err := s.KeysWithPrefix("foo"); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(entries, []pilosa.TranslateEntry{
+		{Index: "IDX", Field: "FLD", ID: 1, Key: "foo"},
+		{Index: "IDX", Field: "FLD", ID: 3, Key: "food"},
+	}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Ensure an empty prefix returns every key.
+	if entries, err := s.KeysWithPrefix(""); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(entries), 4; got != want {
+		t.Fatalf("len(KeysWithPrefix())=%d, want %d", got, want)
+	}
+}
+
 func TestMultiTranslateEntryReader(t *testing.T) {
 	t.Run("None", func(t *testing.T) {
 		r := pilosa.NewMultiTranslateEntryReader(context.Background(), nil)
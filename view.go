@@ -60,6 +60,7 @@ type view struct {
 	rowAttrStore  AttrStore
 	logger        logger.Logger
 	snapshotQueue chan *fragment
+	storageConfig *StorageConfig
 }
 
 // newView returns a new instance of View.
@@ -99,7 +100,7 @@ func (v *view) open() error {
 			return errors.Wrap(err, "creating fragments directory")
 		}
 
-		v.logger.Debugf("open fragments for index/field/view: %s/%s/%s", v.index, v.field, v.name)
+		v.logger.Debugf("open fragments")
 		if err := v.openFragments(); err != nil {
 			return errors.Wrap(err, "opening fragments")
 		}
@@ -110,7 +111,7 @@ func (v *view) open() error {
 		return err
 	}
 
-	v.logger.Debugf("successfully opened index/field/view: %s/%s/%s", v.index, v.field, v.name)
+	v.logger.Debugf("successfully opened view")
 	return nil
 }
 
@@ -149,12 +150,12 @@ fileLoop:
 			// Parse filename into integer.
 			shard, err := strconv.ParseUint(filepath.Base(fi.Name()), 10, 64)
 			if err != nil {
-				v.logger.Debugf("WARNING: couldn't use non-integer file as shard in index/field/view %s/%s/%s: %s", v.index, v.field, v.name, fi.Name())
+				v.logger.Debugf("WARNING: couldn't use non-integer file as shard: %s", fi.Name())
 				continue
 			}
 
 			workQueue <- struct{}{}
-			v.logger.Debugf("open index/field/view/fragment: %s/%s/%s/%d", v.index, v.field, v.name, shard)
+			v.logger.With("shard", shard).Debugf("open fragment")
 			eg.Go(func() error {
 				defer func() {
 					<-workQueue
@@ -164,7 +165,7 @@ fileLoop:
 					return fmt.Errorf("open fragment: shard=%d, err=%s", frag.shard, err)
 				}
 				frag.RowAttrStore = v.rowAttrStore
-				v.logger.Debugf("add index/field/view/fragment to view.fragments: %s/%s/%s/%d", v.index, v.field, v.name, shard)
+				v.logger.With("shard", shard).Debugf("add fragment to view.fragments")
 				mu.Lock()
 				v.fragments[frag.shard] = frag
 				mu.Unlock()
@@ -261,6 +262,12 @@ func (v *view) recalculateCaches() {
 
 // CreateFragmentIfNotExists returns a fragment in the view by shard.
 func (v *view) CreateFragmentIfNotExists(shard uint64) (*fragment, error) {
+	// Most calls find an existing fragment, so check under a read lock first
+	// to avoid serializing writes to different shards of the same view.
+	if frag := v.Fragment(shard); frag != nil {
+		return frag, nil
+	}
+
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	// Find fragment in cache first.
@@ -287,7 +294,7 @@ func (v *view) CreateFragmentIfNotExists(shard uint64) (*fragment, error) {
 		// Broadcast a message that a new max shard was just created.
 		err := v.broadcaster.SendSync(msg)
 		if err != nil {
-			v.logger.Printf("broadcasting create shard: %v", err)
+			v.logger.Errorf("broadcasting create shard: %v", err)
 		}
 		close(broadcastChan)
 	}()
@@ -307,9 +314,14 @@ func (v *view) newFragment(path string, shard uint64) *fragment {
 	frag := newFragment(path, v.index, v.field, v.name, shard, v.flags())
 	frag.CacheType = v.cacheType
 	frag.CacheSize = v.cacheSize
-	frag.Logger = v.logger
+	frag.Logger = v.logger.With("shard", shard)
 	frag.stats = v.stats
 	frag.snapshotQueue = v.snapshotQueue
+	if v.storageConfig != nil {
+		frag.MaxOpN = v.storageConfig.MaxOpN
+		frag.FsyncOps = v.storageConfig.FsyncOps
+		frag.DisableMmap = v.storageConfig.DisableMmap
+	}
 	if v.fieldType == FieldTypeMutex {
 		frag.mutexVector = newRowsVector(frag)
 	} else if v.fieldType == FieldTypeBool {
@@ -327,7 +339,7 @@ func (v *view) deleteFragment(shard uint64) error {
 		return ErrFragmentNotFound
 	}
 
-	v.logger.Printf("delete fragment: (%s/%s/%s) %d", v.index, v.field, v.name, shard)
+	v.logger.With("shard", shard).Printf("delete fragment")
 
 	// Close data files before deletion.
 	if err := fragment.Close(); err != nil {
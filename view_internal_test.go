@@ -103,6 +103,42 @@ func TestView_CreateFragmentRace(t *testing.T) {
 	}
 }
 
+// Ensure that concurrent creates and lookups across shards always resolve to a
+// single fragment per shard. Run with -race to exercise the locking.
+func TestView_CreateFragmentConcurrentLookup(t *testing.T) {
+	var eg errgroup.Group
+	v := mustOpenView("i", "f", "v")
+	defer v.close()
+
+	const shardN, workerN = 8, 8
+	frags := make([][shardN]*fragment, workerN)
+	for w := 0; w < workerN; w++ {
+		w := w
+		eg.Go(func() error {
+			for shard := uint64(0); shard < shardN; shard++ {
+				frag, err := v.CreateFragmentIfNotExists(shard)
+				if err != nil {
+					return err
+				}
+				frags[w][shard] = frag
+				_ = v.Fragment(shard)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for shard := 0; shard < shardN; shard++ {
+		for w := 1; w < workerN; w++ {
+			if frags[w][shard] != frags[0][shard] {
+				t.Fatalf("shard %d: fragment mismatch between workers", shard)
+			}
+		}
+	}
+}
+
 // delayBroadcaster is a nopBroadcaster with a configurable delay.
 type delayBroadcaster struct {
 	nopBroadcaster